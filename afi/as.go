@@ -19,11 +19,20 @@ var (
 
 	AS_IPV4_UNICAST   = NewAS(AFI_IPV4, SAFI_UNICAST)
 	AS_IPV4_MULTICAST = NewAS(AFI_IPV4, SAFI_MULTICAST)
+	AS_IPV4_MPLS      = NewAS(AFI_IPV4, SAFI_MPLS)
 	AS_IPV4_FLOWSPEC  = NewAS(AFI_IPV4, SAFI_FLOWSPEC)
+	AS_IPV4_VPN       = NewAS(AFI_IPV4, SAFI_MPLS_VPN)
 
 	AS_IPV6_UNICAST   = NewAS(AFI_IPV6, SAFI_UNICAST)
 	AS_IPV6_MULTICAST = NewAS(AFI_IPV6, SAFI_MULTICAST)
+	AS_IPV6_MPLS      = NewAS(AFI_IPV6, SAFI_MPLS)
 	AS_IPV6_FLOWSPEC  = NewAS(AFI_IPV6, SAFI_FLOWSPEC)
+	AS_IPV6_VPN       = NewAS(AFI_IPV6, SAFI_MPLS_VPN)
+
+	AS_L2VPN_EVPN = NewAS(AFI_L2VPN, SAFI_EVPNS)
+
+	AS_LS     = NewAS(AFI_LS, SAFI_LS)
+	AS_LS_VPN = NewAS(AFI_LS, SAFI_LS_VPN)
 )
 
 // NewAS returns AS for given Afi and Safi
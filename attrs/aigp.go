@@ -0,0 +1,123 @@
+package attrs
+
+import (
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/json"
+)
+
+// Aigp represents ATTR_AIGP, the Accumulated IGP Metric attribute (RFC 7311)
+type Aigp struct {
+	CodeFlags
+	Metric uint64    // the AIGP TLV value
+	TLV    []AigpTLV // other TLVs, with opaque values
+}
+
+// AigpTLV represents a single AIGP TLV, with opaque value
+type AigpTLV struct {
+	Type  byte
+	Value []byte
+}
+
+// AIGP TLV types, rfc7311/3
+const (
+	AIGP_TLV_METRIC byte = 1
+)
+
+func NewAigp(at CodeFlags) Attr {
+	return &Aigp{CodeFlags: at}
+}
+
+func (a *Aigp) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) error {
+	for len(buf) > 0 {
+		if len(buf) < 3 {
+			return ErrLength
+		}
+
+		// NB: length includes the type and length fields
+		typ := buf[0]
+		tl := int(msb.Uint16(buf[1:3]))
+		if tl < 3 || len(buf) < tl {
+			return ErrLength
+		}
+		val := buf[3:tl]
+		buf = buf[tl:]
+
+		if typ == AIGP_TLV_METRIC {
+			if len(val) != 8 {
+				return ErrLength
+			}
+			a.Metric = msb.Uint64(val)
+		} else {
+			a.TLV = append(a.TLV, AigpTLV{
+				Type:  typ,
+				Value: append([]byte(nil), val...),
+			})
+		}
+	}
+	return nil
+}
+
+func (a *Aigp) Marshal(dst []byte, cps caps.Caps, dir dir.Dir) []byte {
+	tl := 11
+	for i := range a.TLV {
+		tl += 3 + len(a.TLV[i].Value)
+	}
+	dst = a.CodeFlags.MarshalLen(dst, tl)
+
+	dst = append(dst, AIGP_TLV_METRIC, 0, 11)
+	dst = msb.AppendUint64(dst, a.Metric)
+	for i := range a.TLV {
+		dst = append(dst, a.TLV[i].Type)
+		dst = msb.AppendUint16(dst, uint16(3+len(a.TLV[i].Value)))
+		dst = append(dst, a.TLV[i].Value...)
+	}
+	return dst
+}
+
+func (a *Aigp) ToJSON(dst []byte) []byte {
+	dst = append(dst, `{"metric":`...)
+	dst = json.Uint64(dst, a.Metric)
+	if len(a.TLV) > 0 {
+		dst = append(dst, `,"tlv":[`...)
+		for i := range a.TLV {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = append(dst, `{"type":`...)
+			dst = json.Byte(dst, a.TLV[i].Type)
+			dst = append(dst, `,"value":`...)
+			dst = json.Hex(dst, a.TLV[i].Value)
+			dst = append(dst, '}')
+		}
+		dst = append(dst, ']')
+	}
+	return append(dst, '}')
+}
+
+func (a *Aigp) FromJSON(src []byte) error {
+	a.Metric = 0
+	a.TLV = a.TLV[:0]
+	return json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "metric":
+			a.Metric, err = json.UnUint64(val)
+		case "tlv":
+			err = json.ArrayEach(val, func(key int, val []byte, typ json.Type) error {
+				var tlv AigpTLV
+				err := json.ObjectEach(val, func(key string, val []byte, typ json.Type) (err error) {
+					switch key {
+					case "type":
+						tlv.Type, err = json.UnByte(val)
+					case "value":
+						tlv.Value, err = json.UnHex(val, nil)
+					}
+					return
+				})
+				a.TLV = append(a.TLV, tlv)
+				return err
+			})
+		}
+		return
+	})
+}
@@ -0,0 +1,54 @@
+package attrs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestAigp(t *testing.T) {
+	buf := []byte{
+		0x01, 0x00, 0x0b, 0, 0, 0, 0, 0, 0, 0x01, 0x00, // metric 256
+		0x80, 0x00, 0x05, 0xaa, 0xbb, // vendor TLV
+	}
+	js := `{"metric":256,"tlv":[{"type":128,"value":"0xaabb"}]}`
+
+	var cps caps.Caps
+	a := NewAttr(ATTR_AIGP).(*Aigp)
+	if err := a.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if a.Metric != 256 {
+		t.Errorf("Metric = %d, want 256", a.Metric)
+	}
+	if got := string(a.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON = %s, want %s", got, js)
+	}
+	if got := a.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("Marshal = %x, want %x", got[3:], buf)
+	}
+
+	b := NewAttr(ATTR_AIGP).(*Aigp)
+	if err := b.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	if got := b.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("FromJSON Marshal = %x, want %x", got[3:], buf)
+	}
+
+	// malformed
+	for _, bad := range [][]byte{
+		{0x01, 0x00},                   // short header
+		{0x01, 0x00, 0x02},             // length < 3
+		{0x01, 0x00, 0x0b, 0, 0},       // truncated
+		{0x01, 0x00, 0x07, 0, 0, 0, 0}, // metric not 8 bytes
+	} {
+		c := NewAttr(ATTR_AIGP).(*Aigp)
+		if err := c.Unmarshal(bad, cps, dir.DIR_L); !errors.Is(err, ErrLength) {
+			t.Errorf("Unmarshal(%x) error = %v, want ErrLength", bad, err)
+		}
+	}
+}
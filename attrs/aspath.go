@@ -2,6 +2,7 @@ package attrs
 
 import (
 	"fmt"
+	"math"
 	"slices"
 	"strconv"
 
@@ -18,8 +19,47 @@ type Aspath struct {
 
 // AspathSegment represents an AS_PATH segment
 type AspathSegment struct {
-	IsSet bool     // true iff segment is an AS_SET
-	List  []uint32 // list of AS numbers
+	IsSet    bool     // true iff segment is an AS_SET or AS_CONFED_SET
+	IsConfed bool     // true iff segment is an AS_CONFED_SEQUENCE or AS_CONFED_SET, rfc5065
+	List     []uint32 // list of AS numbers
+}
+
+// AS_PATH segment types, rfc4271/4.3 and rfc5065/3
+const (
+	AS_SET             byte = 1
+	AS_SEQUENCE        byte = 2
+	AS_CONFED_SEQUENCE byte = 3
+	AS_CONFED_SET      byte = 4
+)
+
+// AS_TRANS is the 2-byte placeholder for 4-byte ASNs, rfc6793
+const AS_TRANS = 23456
+
+// Type returns the segment type of seg, eg. AS_SEQUENCE
+func (seg *AspathSegment) Type() byte {
+	switch {
+	case seg.IsConfed && seg.IsSet:
+		return AS_CONFED_SET
+	case seg.IsConfed:
+		return AS_CONFED_SEQUENCE
+	case seg.IsSet:
+		return AS_SET
+	default:
+		return AS_SEQUENCE
+	}
+}
+
+// SetType sets seg.IsSet and seg.IsConfed according to segment type typ.
+// Returns false if typ is not a valid segment type.
+func (seg *AspathSegment) SetType(typ byte) bool {
+	switch typ {
+	case AS_SET, AS_SEQUENCE, AS_CONFED_SEQUENCE, AS_CONFED_SET:
+		seg.IsSet = typ == AS_SET || typ == AS_CONFED_SET
+		seg.IsConfed = typ == AS_CONFED_SEQUENCE || typ == AS_CONFED_SET
+		return true
+	default:
+		return false
+	}
 }
 
 func NewAspath(at CodeFlags) Attr {
@@ -41,13 +81,8 @@ func (a *Aspath) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) error {
 	for len(buf) >= 2 {
 		var seg AspathSegment
 
-		// is AS_SET?
-		switch buf[0] {
-		case 1:
-			seg.IsSet = true // is AS_SET
-		case 2:
-			// is AS_SEQUENCE
-		default:
+		// segment type
+		if !seg.SetType(buf[0]) {
 			return fmt.Errorf("%w: %d", ErrSegType, buf[0])
 		}
 
@@ -58,7 +93,7 @@ func (a *Aspath) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) error {
 		}
 
 		// read ASNs
-		todo := buf[2:]
+		todo := buf[2:tl]
 		for len(todo) >= asnlen {
 			if asnlen == 4 {
 				seg.List = append(seg.List, msb.Uint32(todo))
@@ -100,15 +135,12 @@ func (a *Aspath) Marshal(dst []byte, cps caps.Caps, dir dir.Dir) []byte {
 
 	// attr value
 	for _, seg := range a.Segments {
-		if seg.IsSet {
-			dst = append(dst, 1)
-		} else {
-			dst = append(dst, 2)
-		}
-		dst = append(dst, byte(len(seg.List)))
+		dst = append(dst, seg.Type(), byte(len(seg.List)))
 		for _, hop := range seg.List {
 			if asnlen == 4 {
 				dst = msb.AppendUint32(dst, hop)
+			} else if hop > math.MaxUint16 {
+				dst = msb.AppendUint16(dst, AS_TRANS) // rfc6793/4.2.2
 			} else {
 				dst = msb.AppendUint16(dst, uint16(hop))
 			}
@@ -126,7 +158,12 @@ func (a *Aspath) ToJSON(dst []byte) []byte {
 			dst = append(dst, ',')
 		}
 
-		if seg.IsSet {
+		switch {
+		case seg.IsConfed && seg.IsSet:
+			dst = append(dst, `{"confed_set":[`...)
+		case seg.IsConfed:
+			dst = append(dst, `{"confed_seq":[`...)
+		case seg.IsSet:
 			dst = append(dst, '[')
 		}
 
@@ -137,7 +174,10 @@ func (a *Aspath) ToJSON(dst []byte) []byte {
 			dst = strconv.AppendUint(dst, uint64(asn), 10)
 		}
 
-		if seg.IsSet {
+		switch {
+		case seg.IsConfed:
+			dst = append(dst, `]}`...)
+		case seg.IsSet:
 			dst = append(dst, ']')
 		}
 	}
@@ -183,6 +223,23 @@ func (a *Aspath) FromJSON(src []byte) error {
 			seg.IsSet = true
 			seg_push()
 			return set_err
+		case json.OBJECT: // is an AS_CONFED_*
+			seg_push()
+			return json.ObjectEach(val, func(key string, conf_val []byte, _ json.Type) error {
+				switch key {
+				case "confed_seq":
+				case "confed_set":
+					seg.IsSet = true
+				default:
+					return ErrSegType
+				}
+				seg.IsConfed = true
+				conf_err := json.ArrayEach(conf_val, func(_ int, v []byte, _ json.Type) error {
+					return seg_add(v)
+				})
+				seg_push()
+				return conf_err
+			})
 		default:
 			return seg_add(val)
 		}
@@ -191,6 +248,109 @@ func (a *Aspath) FromJSON(src []byte) error {
 	return err
 }
 
+// Clone returns a deep copy of ap
+func (ap *Aspath) Clone() *Aspath {
+	if ap == nil {
+		return nil
+	}
+	ap2 := &Aspath{CodeFlags: ap.CodeFlags, Segments: slices.Clone(ap.Segments)}
+	for i := range ap2.Segments {
+		ap2.Segments[i].List = slices.Clone(ap2.Segments[i].List)
+	}
+	return ap2
+}
+
+// StripConfed removes all AS_CONFED_SEQUENCE and AS_CONFED_SET segments from ap,
+// presenting the external view of the path at a confederation border, rfc5065/5.
+// If confedID is non-zero, it is prepended to the path as the new first hop.
+func (ap *Aspath) StripConfed(confedID uint32) {
+	ap.Segments = slices.DeleteFunc(ap.Segments, func(seg AspathSegment) bool {
+		return seg.IsConfed
+	})
+
+	if confedID == 0 {
+		return
+	} else if len(ap.Segments) > 0 && !ap.Segments[0].IsSet && len(ap.Segments[0].List) < 255 {
+		ap.Segments[0].List = slices.Insert(ap.Segments[0].List, 0, confedID)
+	} else {
+		ap.Segments = slices.Insert(ap.Segments, 0, AspathSegment{List: []uint32{confedID}})
+	}
+}
+
+// Len returns the AS_PATH length as used in route selection, rfc4271/9.1.2.2:
+// each AS_SET counts as 1, and confederation segments are not counted, rfc5065/5.3.
+func (ap *Aspath) Len() (l int) {
+	if ap == nil {
+		return 0
+	}
+	for _, seg := range ap.Segments {
+		switch {
+		case seg.IsConfed:
+			continue
+		case seg.IsSet:
+			l++
+		default:
+			l += len(seg.List)
+		}
+	}
+	return l
+}
+
+// MergeAS4Path reconstructs the real AS path from aspath received from a 2-byte ASN speaker,
+// with AS_TRANS in place of 4-byte ASNs, and as4path, following rfc6793/4.2.3.
+// Returns a new ATTR_ASPATH, or aspath itself if as4path is nil or must be ignored.
+// Confederation segments in as4path are discarded.
+func MergeAS4Path(aspath, as4path *Aspath) *Aspath {
+	if aspath == nil || as4path == nil {
+		return aspath
+	}
+
+	// drop confed segments from as4path
+	as4 := slices.DeleteFunc(slices.Clone(as4path.Segments), func(seg AspathSegment) bool {
+		return seg.IsConfed
+	})
+	as4len := (&Aspath{Segments: as4}).Len()
+
+	// AS4_PATH longer than AS_PATH? ignore it
+	todo := aspath.Len() - as4len
+	if todo < 0 {
+		return aspath
+	}
+
+	// take the leading todo hops from aspath (plus any confed segments on the way)
+	ap := &Aspath{CodeFlags: aspath.CodeFlags}
+	for _, seg := range aspath.Segments {
+		if seg.IsConfed {
+			ap.Segments = append(ap.Segments, AspathSegment{IsSet: seg.IsSet, IsConfed: true, List: slices.Clone(seg.List)})
+			continue
+		} else if todo == 0 {
+			break
+		}
+
+		if seg.IsSet {
+			ap.Segments = append(ap.Segments, AspathSegment{IsSet: true, List: slices.Clone(seg.List)})
+			todo--
+		} else {
+			n := min(todo, len(seg.List))
+			ap.Segments = append(ap.Segments, AspathSegment{List: slices.Clone(seg.List[:n])})
+			todo -= n
+		}
+	}
+
+	// append what's in AS4_PATH
+	for _, seg := range as4 {
+		seg.List = slices.Clone(seg.List)
+		if n := len(ap.Segments); n > 0 && !seg.IsSet && !ap.Segments[n-1].IsSet && !ap.Segments[n-1].IsConfed &&
+			len(ap.Segments[n-1].List)+len(seg.List) <= 255 {
+			ap.Segments[n-1].List = append(ap.Segments[n-1].List, seg.List...)
+		} else {
+			ap.Segments = append(ap.Segments, seg)
+		}
+	}
+
+	return ap
+}
+
 // HasAsn returns true if ap has given asn anywhere in AS_PATH.
 // If as_set=1, scans AS_SETs only; if -1, ignores AS_SETs completely.
 func (ap *Aspath) HasAsn(asn uint32, as_set int) bool {
@@ -213,11 +373,22 @@ func (ap *Aspath) HasAsn(asn uint32, as_set int) bool {
 	return false
 }
 
+// lastSeg returns the index of the last non-confederation segment in ap, or -1
+func (ap *Aspath) lastSeg() int {
+	for i := len(ap.Segments) - 1; i >= 0; i-- {
+		if !ap.Segments[i].IsConfed {
+			return i
+		}
+	}
+	return -1
+}
+
 // HasOrigin returns true iff ap has given asn at the origin.
 // If as_set=1, requires an AS_SET origin; if -1, requires a non-AS_SET origin.
 // For an AS_SET origin to match the asn must be one of its elements.
+// Confederation segments are skipped.
 func (ap *Aspath) HasOrigin(asn uint32, as_set int) bool {
-	lastseg := len(ap.Segments) - 1
+	lastseg := ap.lastSeg()
 	if lastseg < 0 {
 		return false // no segments?
 	}
@@ -247,13 +418,14 @@ func (ap *Aspath) HasOrigin(asn uint32, as_set int) bool {
 }
 
 // Origin returns the last AS in AS_PATH, or 0 on error.
-// It treats AS_SET origins as errors.
+// It treats AS_SET origins as errors, and skips confederation segments,
+// ie. returns 0 for routes originated within the local confederation.
 func (ap *Aspath) Origin() uint32 {
 	if ap == nil {
 		return 0
 	}
 
-	lastseg := len(ap.Segments) - 1
+	lastseg := ap.lastSeg()
 	if lastseg < 0 {
 		return 0 // no segments?
 	}
@@ -267,3 +439,95 @@ func (ap *Aspath) Origin() uint32 {
 		return seg.List[sl-1]
 	}
 }
+
+// IsPrivateASN returns true iff asn is reserved for private use, rfc6996/5
+func IsPrivateASN(asn uint32) bool {
+	return (asn >= 64512 && asn <= 65534) || (asn >= 4200000000 && asn <= 4294967294)
+}
+
+// HasPrivate returns true iff ap has any private ASN (see IsPrivateASN)
+func (ap *Aspath) HasPrivate() bool {
+	if ap == nil {
+		return false
+	}
+	for _, seg := range ap.Segments {
+		if slices.ContainsFunc(seg.List, IsPrivateASN) {
+			return true
+		}
+	}
+	return false
+}
+
+// DropPrivate removes all private ASNs (see IsPrivateASN) from ap, dropping empty segments.
+// Returns true iff ap was modified.
+func (ap *Aspath) DropPrivate() (modified bool) {
+	if ap == nil {
+		return false
+	}
+
+	for si := range ap.Segments {
+		seg := &ap.Segments[si]
+		if l := len(seg.List); l > 0 {
+			seg.List = slices.DeleteFunc(seg.List, IsPrivateASN)
+			modified = modified || len(seg.List) != l
+		}
+	}
+
+	if modified {
+		ap.Segments = slices.DeleteFunc(ap.Segments, func(seg AspathSegment) bool {
+			return len(seg.List) == 0
+		})
+	}
+	return modified
+}
+
+// Prepend inserts asn n times at the front of ap, rfc4271/5.1.2.
+// The ASNs are added to the first segment if it is an AS_SEQUENCE, otherwise
+// (or if it would overflow) a new AS_SEQUENCE is created at the front.
+func (ap *Aspath) Prepend(asn uint32, n int) {
+	if ap == nil || n <= 0 {
+		return
+	}
+
+	// try the head segment first
+	if len(ap.Segments) > 0 {
+		if seg := &ap.Segments[0]; !seg.IsSet && !seg.IsConfed {
+			k := min(n, 255-len(seg.List))
+			if k > 0 {
+				seg.List = slices.Insert(seg.List, 0, repeatAsn(asn, k)...)
+				n -= k
+			}
+		}
+	}
+
+	// need new segment(s)?
+	for n > 0 {
+		k := min(n, 255)
+		ap.Segments = slices.Insert(ap.Segments, 0, AspathSegment{List: repeatAsn(asn, k)})
+		n -= k
+	}
+}
+
+// repeatAsn returns a new list with asn repeated n times
+func repeatAsn(asn uint32, n int) []uint32 {
+	list := make([]uint32, n)
+	for i := range list {
+		list[i] = asn
+	}
+	return list
+}
+
+// CountAsn returns how many times asn appears in ap, across all segments
+func (ap *Aspath) CountAsn(asn uint32) (count int) {
+	if ap == nil {
+		return 0
+	}
+	for _, seg := range ap.Segments {
+		for _, v := range seg.List {
+			if v == asn {
+				count++
+			}
+		}
+	}
+	return count
+}
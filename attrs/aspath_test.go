@@ -0,0 +1,236 @@
+package attrs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestAspath_Confed(t *testing.T) {
+	// AS4: CONFED_SEQUENCE(65001 65002) CONFED_SET(65003) SEQUENCE(100 200)
+	buf := []byte{
+		0x03, 0x02, 0x00, 0x00, 0xfd, 0xe9, 0x00, 0x00, 0xfd, 0xea,
+		0x04, 0x01, 0x00, 0x00, 0xfd, 0xeb,
+		0x02, 0x02, 0x00, 0x00, 0x00, 0x64, 0x00, 0x00, 0x00, 0xc8,
+	}
+	js := `[{"confed_seq":[65001,65002]},{"confed_set":[65003]},100,200]`
+
+	var cps caps.Caps
+	a := NewAttr(ATTR_AS4PATH).(*Aspath)
+	if err := a.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if got := string(a.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON = '%s', want '%s'", got, js)
+	}
+	if got := a.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("Marshal = '%x', want '%x'", got[3:], buf)
+	}
+
+	b := NewAttr(ATTR_AS4PATH).(*Aspath)
+	if err := b.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	if got := b.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("FromJSON Marshal = '%x', want '%x'", got[3:], buf)
+	}
+
+	// confederation segments not counted
+	if got := a.Len(); got != 2 {
+		t.Errorf("Len = %d, want 2", got)
+	}
+	if got := a.Segments[1].Type(); got != AS_CONFED_SET {
+		t.Errorf("Segments[1].Type = %d, want %d", got, AS_CONFED_SET)
+	}
+	if got := a.Origin(); got != 200 {
+		t.Errorf("Origin = %d, want 200", got)
+	}
+
+	// external view, with confederation id
+	b.StripConfed(65000)
+	if got, want := b.String(), `[65000,100,200]`; got != want {
+		t.Errorf("StripConfed(65000) = '%s', want '%s'", got, want)
+	}
+
+	// external view, without confederation id
+	a.StripConfed(0)
+	if got, want := a.String(), `[100,200]`; got != want {
+		t.Errorf("StripConfed(0) = '%s', want '%s'", got, want)
+	}
+}
+
+func TestAspath_ConfedOnly(t *testing.T) {
+	// a route originated within the confederation
+	a := NewAttr(ATTR_ASPATH).(*Aspath)
+	if err := a.FromJSON([]byte(`[{"confed_seq":[65001,65002]}]`)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	if got := a.Len(); got != 0 {
+		t.Errorf("Len = %d, want 0", got)
+	}
+	if got := a.Origin(); got != 0 {
+		t.Errorf("Origin = %d, want 0", got)
+	}
+	if a.HasOrigin(65002, 0) {
+		t.Errorf("HasOrigin(65002) = true, want false")
+	}
+
+	// invalid segment type
+	b := NewAttr(ATTR_AS4PATH).(*Aspath)
+	err := b.Unmarshal([]byte{0x05, 0x01, 0x00, 0x00, 0xfd, 0xe9}, caps.Caps{}, dir.DIR_L)
+	if !errors.Is(err, ErrSegType) {
+		t.Errorf("Unmarshal type 5 error = %v, want %v", err, ErrSegType)
+	}
+}
+
+func TestAspath_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		code Code
+		buf  []byte
+		want string
+		err  error
+	}{
+		{"as2 two segments", ATTR_ASPATH, []byte{
+			0x02, 0x02, 0x00, 0x64, 0x00, 0xc8,
+			0x01, 0x01, 0x01, 0x2c,
+		}, `[100,200,[300]]`, nil},
+		{"as4 two segments", ATTR_AS4PATH, []byte{
+			0x02, 0x01, 0x00, 0x00, 0x00, 0x64,
+			0x02, 0x02, 0x00, 0x00, 0x00, 0xc8, 0x00, 0x00, 0x01, 0x2c,
+		}, `[100,200,300]`, nil},
+		{"as4 three segments", ATTR_AS4PATH, []byte{
+			0x02, 0x01, 0x00, 0x00, 0x00, 0x64,
+			0x01, 0x01, 0x00, 0x00, 0x00, 0xc8,
+			0x02, 0x01, 0x00, 0x00, 0x01, 0x2c,
+		}, `[100,[200],300]`, nil},
+		{"truncated segment", ATTR_AS4PATH, []byte{
+			0x02, 0x02, 0x00, 0x00, 0x00, 0x64,
+		}, ``, ErrSegLen},
+		{"trailing byte", ATTR_AS4PATH, []byte{
+			0x02, 0x01, 0x00, 0x00, 0x00, 0x64, 0x02,
+		}, ``, ErrLength},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAttr(tt.code).(*Aspath)
+			err := a.Unmarshal(tt.buf, caps.Caps{}, dir.DIR_L)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("Unmarshal error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal error = %v", err)
+			}
+			if got := a.String(); got != tt.want {
+				t.Errorf("Unmarshal = '%s', want '%s'", got, tt.want)
+			}
+			if got := a.Marshal(nil, caps.Caps{}, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("Marshal = '%x', want '%x'", got[3:], tt.buf)
+			}
+		})
+	}
+}
+
+func TestAspath_Prepend(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		asn  uint32
+		n    int
+		want string
+	}{
+		{"empty", `[]`, 65000, 2, `[65000,65000]`},
+		{"sequence", `[100,200]`, 65000, 1, `[65000,100,200]`},
+		{"as_set head", `[[100,200],300]`, 65000, 2, `[65000,65000,[100,200],300]`},
+		{"confed head", `[{"confed_seq":[65001]},100]`, 65000, 1, `[65000,{"confed_seq":[65001]},100]`},
+		{"zero", `[100]`, 65000, 0, `[100]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ap := NewAttr(ATTR_ASPATH).(*Aspath)
+			if err := ap.FromJSON([]byte(tt.in)); err != nil {
+				t.Fatalf("FromJSON error = %v", err)
+			}
+			ap.Prepend(tt.asn, tt.n)
+			if got := ap.String(); got != tt.want {
+				t.Errorf("Prepend(%d, %d) = '%s', want '%s'", tt.asn, tt.n, got, tt.want)
+			}
+			if got := ap.CountAsn(tt.asn); got != tt.n {
+				t.Errorf("CountAsn(%d) = %d, want %d", tt.asn, got, tt.n)
+			}
+		})
+	}
+
+	// overflow the head segment
+	ap := &Aspath{Segments: []AspathSegment{{List: repeatAsn(100, 254)}}}
+	ap.Prepend(65000, 3)
+	if len(ap.Segments) != 2 || len(ap.Segments[0].List) != 2 || len(ap.Segments[1].List) != 255 {
+		t.Errorf("Prepend overflow: got %d segments", len(ap.Segments))
+	}
+	if got := ap.CountAsn(65000); got != 3 {
+		t.Errorf("CountAsn overflow = %d, want 3", got)
+	}
+}
+
+func TestAspath_CountAsn(t *testing.T) {
+	ap := NewAttr(ATTR_ASPATH).(*Aspath)
+	if err := ap.FromJSON([]byte(`[{"confed_seq":[65001,100]},100,200,[100,300],100]`)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	for asn, want := range map[uint32]int{100: 4, 200: 1, 300: 1, 65001: 1, 400: 0} {
+		if got := ap.CountAsn(asn); got != want {
+			t.Errorf("CountAsn(%d) = %d, want %d", asn, got, want)
+		}
+	}
+
+	var nilpath *Aspath
+	if got := nilpath.CountAsn(100); got != 0 {
+		t.Errorf("nil CountAsn = %d, want 0", got)
+	}
+}
+
+func TestMergeAS4Path(t *testing.T) {
+	tests := []struct {
+		name   string
+		aspath string
+		as4    string
+		want   string
+	}{
+		{"classic", `[65001,23456,23456,65004]`, `[4200000002,4200000003,65004]`, `[65001,4200000002,4200000003,65004]`},
+		{"same length", `[23456,65002]`, `[4200000001,65002]`, `[4200000001,65002]`},
+		{"as4 longer", `[23456]`, `[100,4200000001]`, `[23456]`},
+		{"as_set", `[65001,[23456,65003]]`, `[[4200000002,65003]]`, `[65001,[4200000002,65003]]`},
+		{"confed ignored", `[{"confed_seq":[65010]},23456]`, `[{"confed_seq":[65099]},4200000001]`, `[{"confed_seq":[65010]},4200000001]`},
+		{"no as4", `[65001,23456]`, ``, `[65001,23456]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ap := NewAttr(ATTR_ASPATH).(*Aspath)
+			if err := ap.FromJSON([]byte(tt.aspath)); err != nil {
+				t.Fatalf("FromJSON(aspath) error = %v", err)
+			}
+			var as4 *Aspath
+			if tt.as4 != "" {
+				as4 = NewAttr(ATTR_AS4PATH).(*Aspath)
+				if err := as4.FromJSON([]byte(tt.as4)); err != nil {
+					t.Fatalf("FromJSON(as4) error = %v", err)
+				}
+			}
+			before := ap.String()
+			if got := MergeAS4Path(ap, as4).String(); got != tt.want {
+				t.Errorf("MergeAS4Path = '%s', want '%s'", got, tt.want)
+			}
+			if ap.String() != before {
+				t.Errorf("MergeAS4Path modified aspath: '%s'", ap.String())
+			}
+		})
+	}
+}
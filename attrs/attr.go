@@ -93,29 +93,41 @@ type NewFunc func(cf CodeFlags) Attr
 
 // NewFuncs maps attribute codes to their NewFunc
 var NewFuncs = map[Code]NewFunc{
-	ATTR_ORIGIN:          NewOrigin,
-	ATTR_ASPATH:          NewAspath,
-	ATTR_AS4PATH:         NewAspath,
-	ATTR_NEXTHOP:         NewIP4,
-	ATTR_MED:             NewU32,
-	ATTR_LOCALPREF:       NewU32,
-	ATTR_MP_REACH:        NewMP,
-	ATTR_MP_UNREACH:      NewMP,
-	ATTR_COMMUNITY:       NewCommunity,
-	ATTR_EXT_COMMUNITY:   NewExtcom,
-	ATTR_LARGE_COMMUNITY: NewLargeCom,
-	ATTR_AGGREGATOR:      NewAggregator,
-	ATTR_AS4AGGREGATOR:   NewAggregator,
-	ATTR_ORIGINATOR:      NewIP4,
-	ATTR_CLUSTER_LIST:    NewIPList4,
+	ATTR_ORIGIN:             NewOrigin,
+	ATTR_ASPATH:             NewAspath,
+	ATTR_AS4PATH:            NewAspath,
+	ATTR_NEXTHOP:            NewIP4,
+	ATTR_MED:                NewU32,
+	ATTR_LOCALPREF:          NewU32,
+	ATTR_MP_REACH:           NewMP,
+	ATTR_MP_UNREACH:         NewMP,
+	ATTR_COMMUNITY:          NewCommunity,
+	ATTR_EXT_COMMUNITY:      NewExtcom,
+	ATTR_IPV6_EXT_COMMUNITY: NewExtcom6,
+	ATTR_LARGE_COMMUNITY:    NewLargeCom,
+	ATTR_AGGREGATOR:         NewAggregator,
+	ATTR_AS4AGGREGATOR:      NewAggregator,
+	ATTR_ORIGINATOR:         NewIP4,
+	ATTR_CLUSTER_LIST:       NewIPList4,
+	ATTR_BGP_LS:             NewBgpLS,
+	ATTR_OTC:                NewOtc,
+	ATTR_PMSI_TUNNEL:        NewPmsiTunnel,
+	ATTR_TUNNEL:             NewTunnelEncap,
+	ATTR_AIGP:               NewAigp,
+	ATTR_PREFIX_SID:         NewPrefixSid,
 }
 
 // DefaultFlags gives the default flags for attribute codes, in addition to ATTR_OPTIONAL
 var DefaultFlags = map[Code]Flags{
-	ATTR_COMMUNITY:       ATTR_TRANSITIVE,
-	ATTR_EXT_COMMUNITY:   ATTR_TRANSITIVE,
-	ATTR_LARGE_COMMUNITY: ATTR_TRANSITIVE,
-	ATTR_AGGREGATOR:      ATTR_TRANSITIVE,
+	ATTR_COMMUNITY:          ATTR_TRANSITIVE,
+	ATTR_EXT_COMMUNITY:      ATTR_TRANSITIVE,
+	ATTR_IPV6_EXT_COMMUNITY: ATTR_TRANSITIVE,
+	ATTR_LARGE_COMMUNITY:    ATTR_TRANSITIVE,
+	ATTR_AGGREGATOR:         ATTR_TRANSITIVE,
+	ATTR_OTC:                ATTR_TRANSITIVE,
+	ATTR_PMSI_TUNNEL:        ATTR_TRANSITIVE,
+	ATTR_TUNNEL:             ATTR_TRANSITIVE,
+	ATTR_PREFIX_SID:         ATTR_TRANSITIVE,
 }
 
 // NewAttr returns a new Attr instance for given code ac and default flags.
@@ -158,17 +170,16 @@ func (cf CodeFlags) HasFlags(af Flags) bool {
 	return Flags(cf>>8)&af != 0
 }
 
-// MarshalLen appends to dst attribute flags, code, and length
+// MarshalLen appends to dst attribute flags, code, and length.
+// Uses the extended length iff needed or if cf already has ATTR_EXTENDED set.
 // FIXME: switch to always extended and write real length after (defer retfunc?)
 func (cf CodeFlags) MarshalLen(dst []byte, length int) []byte {
 	flags := cf.Flags()
 	if length > 0xff {
 		flags |= ATTR_EXTENDED
-	} else {
-		flags &= ^ATTR_EXTENDED
 	}
 	dst = append(dst, byte(flags), byte(cf.Code()))
-	if length > 0xff {
+	if flags&ATTR_EXTENDED != 0 {
 		dst = msb.AppendUint16(dst, uint16(length))
 	} else {
 		dst = append(dst, byte(length))
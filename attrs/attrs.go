@@ -6,7 +6,10 @@
 package attrs
 
 import (
+	"bytes"
 	"fmt"
+	"maps"
+	"slices"
 	"sort"
 
 	"github.com/bgpfix/bgpfix/binary"
@@ -18,9 +21,11 @@ var msb = binary.Msb
 // Attrs is an ordinary map that represents a set of BGP path attributes.
 // It should not contain nil values.
 //
-// Attrs and its values are not thread-safe.
+// Attrs and its values are not thread-safe, unless shared (see Share).
 type Attrs struct {
-	db map[Code]Attr
+	db     map[Code]Attr
+	shared map[Code]Attr // if non-nil, read-only values shared with other Attrs
+	cow    bool          // if true, db == shared: copy it before writing
 }
 
 // Init initializes Attrs. Can be called multiple times for lazy init.
@@ -38,15 +43,45 @@ func (ats *Attrs) Valid() bool {
 // Reset resets Attrs back to initial state.
 func (ats *Attrs) Reset() {
 	ats.db = nil
+	ats.shared = nil
+	ats.cow = false
 }
 
 // Clear drops all attributes.
 func (ats *Attrs) Clear() {
-	if ats.Valid() {
+	if ats.shared != nil {
+		ats.Reset()
+		ats.Init()
+	} else if ats.Valid() {
 		clear(ats.db)
 	}
 }
 
+// Share returns a copy of ats that shares the attribute values with ats,
+// copying on write. Any later modifications of the returned copy done
+// through its methods (including Use and Own) do not affect ats, but
+// values returned by its Get and Each must be treated as read-only.
+// ats itself must not be modified afterwards.
+func (ats *Attrs) Share() Attrs {
+	if !ats.Valid() {
+		return Attrs{}
+	}
+	return Attrs{db: ats.db, shared: ats.db, cow: true}
+}
+
+// Shared returns true iff ats shares some values with other Attrs, see Share
+func (ats *Attrs) Shared() bool {
+	return ats.shared != nil
+}
+
+// write makes ats.db safe for writing
+func (ats *Attrs) write() {
+	if ats.cow {
+		ats.db = maps.Clone(ats.db)
+		ats.cow = false
+	}
+}
+
 // Len returns the number of attributes
 func (ats *Attrs) Len() int {
 	if ats.Valid() {
@@ -63,6 +98,7 @@ func (ats *Attrs) SetFrom(src Attrs) {
 	}
 
 	ats.Init()
+	ats.write()
 	for ac, at := range ats.db {
 		ats.db[ac] = at
 	}
@@ -85,6 +121,7 @@ func (ats *Attrs) Has(ac Code) bool {
 // Drop drops ats[ac].
 func (ats *Attrs) Drop(ac Code) {
 	if ats.Valid() {
+		ats.write()
 		delete(ats.db, ac)
 	}
 }
@@ -92,27 +129,95 @@ func (ats *Attrs) Drop(ac Code) {
 // Set overwrites ats[ac] with value.
 func (ats *Attrs) Set(ac Code, value Attr) {
 	ats.Init()
+	ats.write()
 	ats.db[ac] = value
 }
 
-// Use returns ats[ac] if its already set and non-nil.
+// Use returns ats[ac] if its already set and non-nil, ready for modification.
 // Otherwise, it adds a new instance for ac with default flags.
 func (ats *Attrs) Use(ac Code) Attr {
 	// already there?
-	if ats.Valid() {
-		if at, ok := ats.db[ac]; ok && at != nil {
-			return at
-		}
-	} else {
-		ats.Init()
+	if at := ats.Own(ac); at != nil {
+		return at
 	}
 
 	// create, store, and return
+	ats.Init()
+	ats.write()
 	at := NewAttr(ac)
 	ats.db[ac] = at
 	return at
 }
 
+// Own returns ats[ac] ready for modification, or nil if not set.
+// If the value is shared (see Share), it is replaced with a private copy first.
+func (ats *Attrs) Own(ac Code) Attr {
+	at := ats.Get(ac)
+	if at == nil || ats.shared == nil || ats.shared[ac] != at {
+		return at
+	}
+
+	at2 := cloneAttr(at)
+	ats.write()
+	ats.db[ac] = at2
+	return at2
+}
+
+// Clone returns a deep copy of ats, see CloneExcept.
+func (ats *Attrs) Clone() Attrs {
+	return ats.CloneExcept()
+}
+
+// CloneExcept returns a deep copy of ats, skipping attribute codes listed in ignore.
+// The returned values share no memory with ats, eg. with message buffers that are re-used.
+func (ats *Attrs) CloneExcept(ignore ...Code) Attrs {
+	var dst Attrs
+	if !ats.Valid() {
+		return dst
+	}
+
+	dst.Init()
+	for ac, at := range ats.db {
+		if at != nil && !slices.Contains(ignore, ac) {
+			dst.db[ac] = cloneAttr(at)
+		}
+	}
+	return dst
+}
+
+// EqualExcept returns true iff ats and other hold the same set of attributes
+// with equal values and flags, skipping attribute codes listed in ignore.
+// The ATTR_EXTENDED flag is not compared, as it only affects the wire encoding.
+func (ats *Attrs) EqualExcept(other *Attrs, ignore ...Code) bool {
+	var buf1, buf2 []byte
+
+	// check ats against other
+	for ac, at := range ats.db {
+		if at == nil || slices.Contains(ignore, ac) {
+			continue
+		}
+
+		at2 := other.Get(ac)
+		if at2 == nil || at.Flags()&^ATTR_EXTENDED != at2.Flags()&^ATTR_EXTENDED {
+			return false
+		}
+
+		buf1, buf2 = at.ToJSON(buf1[:0]), at2.ToJSON(buf2[:0])
+		if !bytes.Equal(buf1, buf2) {
+			return false
+		}
+	}
+
+	// anything in other that's missing in ats?
+	for ac, at := range other.db {
+		if at != nil && !slices.Contains(ignore, ac) && !ats.Has(ac) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Each executes cb for each attribute in ats,
 // in an ascending order of attribute codes.
 func (ats *Attrs) Each(cb func(i int, ac Code, at Attr)) {
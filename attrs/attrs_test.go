@@ -0,0 +1,158 @@
+package attrs
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestAttrs_FromJSON_Flags(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []byte
+	}{
+		{"default", `{"MED":1}`, []byte{0x80, 0x04, 0x04, 0, 0, 0, 1}},
+		{"transitive", `{"MED":{"flags":"OT","value":1}}`, []byte{0xc0, 0x04, 0x04, 0, 0, 0, 1}},
+		{"partial", `{"COMMUNITY":{"flags":"OTP","value":["1:2"]}}`, []byte{0xe0, 0x08, 0x04, 0, 1, 0, 2}},
+		{"extended", `{"ORIGIN":{"flags":"TX","value":"IGP"}}`, []byte{0x50, 0x01, 0x00, 0x01, 0}},
+		{"unused bits", `{"ORIGIN":{"flags":"T0x01","value":"EGP"}}`, []byte{0x41, 0x01, 0x01, 1}},
+	}
+
+	var cps caps.Caps
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ats Attrs
+			if err := ats.FromJSON([]byte(tt.json)); err != nil {
+				t.Fatalf("FromJSON error = %v", err)
+			}
+			var got []byte
+			ats.Each(func(i int, ac Code, at Attr) {
+				got = at.Marshal(got, cps, dir.DIR_L)
+			})
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Marshal = '%x', want '%x'", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttrs_EqualExcept(t *testing.T) {
+	base := `"ORIGIN":{"flags":"T","value":"IGP"},"ASPATH":{"flags":"T","value":[65001,65002]},"NEXTHOP":{"flags":"T","value":"192.0.2.1"}`
+	tests := []struct {
+		name   string
+		a, b   string
+		ignore []Code
+		want   bool
+	}{
+		{"same", `{` + base + `}`, `{` + base + `}`, nil, true},
+		{"med differs", `{` + base + `,"MED":10}`, `{` + base + `,"MED":20}`, nil, false},
+		{"med ignored", `{` + base + `,"MED":10}`, `{` + base + `,"MED":20}`, []Code{ATTR_MED}, true},
+		{"med missing", `{` + base + `,"MED":10}`, `{` + base + `}`, []Code{ATTR_MED}, true},
+		{"aggregate ignored", `{` + base + `,"MED":10,"AGGREGATE":true}`, `{` + base + `,"MED":20}`,
+			[]Code{ATTR_MED, ATTR_AGGREGATE}, true},
+		{"missing", `{` + base + `,"LOCALPREF":100}`, `{` + base + `}`, []Code{ATTR_MED}, false},
+		{"missing reverse", `{` + base + `}`, `{` + base + `,"LOCALPREF":100}`, []Code{ATTR_MED}, false},
+		{"aspath differs", `{` + base + `}`, `{"ORIGIN":"IGP","ASPATH":[65001],"NEXTHOP":"192.0.2.1"}`, []Code{ATTR_MED}, false},
+		{"flags differ", `{"MED":{"flags":"O","value":1}}`, `{"MED":{"flags":"OT","value":1}}`, nil, false},
+		{"extended ignored", `{"MED":{"flags":"O","value":1}}`, `{"MED":{"flags":"OX","value":1}}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a, b Attrs
+			if err := a.FromJSON([]byte(tt.a)); err != nil {
+				t.Fatalf("a.FromJSON error = %v", err)
+			}
+			if err := b.FromJSON([]byte(tt.b)); err != nil {
+				t.Fatalf("b.FromJSON error = %v", err)
+			}
+			if got := a.EqualExcept(&b, tt.ignore...); got != tt.want {
+				t.Errorf("EqualExcept = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttrs_Share(t *testing.T) {
+	var orig Attrs
+	if err := orig.FromJSON([]byte(`{"ASPATH":[1,2,3],"MED":10,"COMMUNITY":["1:2"]}`)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	want := string(orig.ToJSON(nil))
+
+	ats := orig.Share()
+	if !ats.Shared() || orig.Shared() {
+		t.Fatalf("Shared() = %v / %v, want true / false", ats.Shared(), orig.Shared())
+	}
+	if ats.Get(ATTR_ASPATH) != orig.Get(ATTR_ASPATH) {
+		t.Errorf("Get(ASPATH) does not share the value")
+	}
+
+	// modify the copy in all possible ways
+	ap := ats.Own(ATTR_ASPATH).(*Aspath)
+	if ap == orig.Get(ATTR_ASPATH) {
+		t.Fatalf("Own(ASPATH) returned the shared value")
+	}
+	ap.Segments[0].List[0] = 100
+	ats.Use(ATTR_MED).(*U32).Val = 20
+	ats.Use(ATTR_LOCALPREF).(*U32).Val = 200
+	ats.Drop(ATTR_COMMUNITY)
+
+	if got := string(orig.ToJSON(nil)); got != want {
+		t.Errorf("original modified: %s, want %s", got, want)
+	}
+	if got, want := string(ats.ToJSON(nil)), `{"ASPATH":{"flags":"T","value":[100,2,3]},"MED":{"flags":"O","value":20},"LOCALPREF":{"flags":"T","value":200}}`; got != want {
+		t.Errorf("copy = %s, want %s", got, want)
+	}
+
+	// owned values are not copied again
+	if ats.Own(ATTR_ASPATH) != ap {
+		t.Errorf("Own(ASPATH) copied an owned value")
+	}
+
+	ats.Clear()
+	if ats.Len() != 0 || orig.Len() != 3 {
+		t.Errorf("Clear: Len = %d / %d, want 0 / 3", ats.Len(), orig.Len())
+	}
+}
+
+func TestAttrs_Clone(t *testing.T) {
+	js := `{"ORIGIN":"IGP","ASPATH":[65001,[65002,65003]],"NEXTHOP":"192.0.2.1","MED":10,"LOCALPREF":100,` +
+		`"COMMUNITY":["1:2"],"LARGE_COMMUNITY":["65001:1:2"],"EXT_COMMUNITY":[{"type":"TARGET","value":"65000:100"}],` +
+		`"AGGREGATOR":{"asn":65001,"addr":"192.0.2.9"},"ORIGINATOR":"192.0.2.2","CLUSTER_LIST":["192.0.2.3"],"OTC":65001,` +
+		`"AIGP":{"metric":100},"MP_REACH":{"af":"IPV6/UNICAST","nexthop":"2001:db8::1","prefixes":["2001:db8:1::/48"]},` +
+		`"MP_UNREACH":{"af":"L2VPN/VPLS","data":"0x001122334455"}}`
+
+	var orig Attrs
+	if err := orig.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	want := string(orig.ToJSON(nil))
+
+	ats := orig.Clone()
+	if got := string(ats.ToJSON(nil)); got != want {
+		t.Fatalf("Clone = %s, want %s", got, want)
+	}
+	ats.Each(func(i int, ac Code, at Attr) {
+		if _, ok := at.(*Raw); ok {
+			t.Errorf("Clone %s = *Raw, want %T", ac, orig.Get(ac))
+		}
+	})
+
+	// modify the original in place, the clone must not change
+	orig.Get(ATTR_ASPATH).(*Aspath).Segments[1].List[0] = 1
+	orig.Get(ATTR_COMMUNITY).(*Community).Value[0] = 1
+	orig.Get(ATTR_LARGE_COMMUNITY).(*LargeCom).Value2[0] = 1
+	orig.Get(ATTR_CLUSTER_LIST).(*IPList).Addr[0] = orig.Get(ATTR_NEXTHOP).(*IP).Addr
+	orig.Get(ATTR_EXT_COMMUNITY).(*Extcom).Value[0].(*ExtcomASN).Value = 1
+	orig.Get(ATTR_AIGP).(*Aigp).Metric = 1
+	orig.Get(ATTR_MP_REACH).(*MP).Prefixes().Prefixes[0].Prefix = netip.MustParsePrefix("2001:db8:2::/48")
+	orig.Get(ATTR_MP_UNREACH).(*MP).Data[0] = 1
+	if got := string(ats.ToJSON(nil)); got != want {
+		t.Errorf("Clone after modification = %s, want %s", got, want)
+	}
+}
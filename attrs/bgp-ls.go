@@ -0,0 +1,138 @@
+package attrs
+
+import (
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/json"
+)
+
+// BgpLS represents ATTR_BGP_LS at TLV granularity (RFC 9552)
+type BgpLS struct {
+	CodeFlags
+	TLV []BgpLSTLV
+}
+
+// BgpLSTLV represents a single BGP-LS TLV, with opaque value
+type BgpLSTLV struct {
+	Type  uint16
+	Value []byte
+}
+
+func NewBgpLS(at CodeFlags) Attr {
+	return &BgpLS{CodeFlags: at}
+}
+
+func (a *BgpLS) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) (err error) {
+	a.TLV, err = readBgpLSTLV(a.TLV[:0], buf)
+	return err
+}
+
+// readBgpLSTLV appends copies of all BGP-LS TLVs in buf to dst
+func readBgpLSTLV(dst []BgpLSTLV, buf []byte) ([]BgpLSTLV, error) {
+	for len(buf) > 0 {
+		if len(buf) < 4 {
+			return dst, ErrLength
+		}
+		typ := msb.Uint16(buf[0:2])
+		tl := int(msb.Uint16(buf[2:4]))
+		buf = buf[4:]
+		if len(buf) < tl {
+			return dst, ErrLength
+		}
+
+		dst = append(dst, BgpLSTLV{
+			Type:  typ,
+			Value: append([]byte(nil), buf[:tl]...),
+		})
+		buf = buf[tl:]
+	}
+	return dst, nil
+}
+
+// appendBgpLSTLV appends the wire representation of BGP-LS TLVs in src to dst
+func appendBgpLSTLV(dst []byte, src []BgpLSTLV) []byte {
+	for i := range src {
+		dst = msb.AppendUint16(dst, src[i].Type)
+		dst = msb.AppendUint16(dst, uint16(len(src[i].Value)))
+		dst = append(dst, src[i].Value...)
+	}
+	return dst
+}
+
+// bgpLSTLVLen returns the wire length of BGP-LS TLVs in src
+func bgpLSTLVLen(src []BgpLSTLV) (tl int) {
+	for i := range src {
+		tl += 4 + len(src[i].Value)
+	}
+	return tl
+}
+
+// Add appends a copy of TLV typ with given value
+func (a *BgpLS) Add(typ uint16, value []byte) {
+	a.TLV = append(a.TLV, BgpLSTLV{
+		Type:  typ,
+		Value: append([]byte(nil), value...),
+	})
+}
+
+// Find returns the value of the first TLV of type typ, or nil if not found
+func (a *BgpLS) Find(typ uint16) []byte {
+	for i := range a.TLV {
+		if a.TLV[i].Type == typ {
+			return a.TLV[i].Value
+		}
+	}
+	return nil
+}
+
+func (a *BgpLS) Marshal(dst []byte, cps caps.Caps, dir dir.Dir) []byte {
+	dst = a.CodeFlags.MarshalLen(dst, bgpLSTLVLen(a.TLV))
+	return appendBgpLSTLV(dst, a.TLV)
+}
+
+func (a *BgpLS) ToJSON(dst []byte) []byte {
+	return bgpLSTLVToJSON(dst, a.TLV)
+}
+
+// bgpLSTLVToJSON appends JSON representation of BGP-LS TLVs in src to dst
+func bgpLSTLVToJSON(dst []byte, src []BgpLSTLV) []byte {
+	dst = append(dst, '[')
+	for i := range src {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `{"type":`...)
+		dst = json.Uint16(dst, src[i].Type)
+		dst = append(dst, `,"value":`...)
+		dst = json.Hex(dst, src[i].Value)
+		dst = append(dst, '}')
+	}
+	return append(dst, ']')
+}
+
+func (a *BgpLS) FromJSON(src []byte) (err error) {
+	a.TLV, err = bgpLSTLVFromJSON(a.TLV[:0], src)
+	return err
+}
+
+// bgpLSTLVFromJSON appends BGP-LS TLVs from their JSON representation in src to dst
+func bgpLSTLVFromJSON(dst []BgpLSTLV, src []byte) ([]BgpLSTLV, error) {
+	err := json.ArrayEach(src, func(key int, val []byte, typ json.Type) error {
+		var tlv BgpLSTLV
+		err := json.ObjectEach(val, func(key string, val []byte, typ json.Type) (err error) {
+			switch key {
+			case "type":
+				tlv.Type, err = json.UnUint16(val)
+			case "value":
+				tlv.Value, err = json.UnHex(val, nil)
+			}
+			return
+		})
+		if err != nil {
+			return err
+		}
+		dst = append(dst, tlv)
+		return nil
+	})
+	return dst, err
+}
@@ -0,0 +1,51 @@
+package attrs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestBgpLS(t *testing.T) {
+	// node name (1026) and router-id (1028)
+	buf := []byte{
+		0x04, 0x02, 0x00, 0x02, 'r', '1',
+		0x04, 0x04, 0x00, 0x04, 0x0a, 0x00, 0x00, 0x01,
+	}
+	js := `[{"type":1026,"value":"0x7231"},{"type":1028,"value":"0x0a000001"}]`
+
+	var cps caps.Caps
+	a := NewAttr(ATTR_BGP_LS).(*BgpLS)
+	if err := a.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if got := string(a.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON = '%s', want '%s'", got, js)
+	}
+	if err := a.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal again error = %v", err)
+	}
+	if got := string(a.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON after reuse = '%s', want '%s'", got, js)
+	}
+	if v := a.Find(1028); !bytes.Equal(v, buf[10:]) {
+		t.Errorf("Find = '%x', want '%x'", v, buf[10:])
+	}
+
+	b := NewAttr(ATTR_BGP_LS).(*BgpLS)
+	if err := b.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	if err := b.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON again error = %v", err)
+	}
+	if got := b.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("Marshal = '%x', want '%x'", got[3:], buf)
+	}
+
+	if err := b.Unmarshal(buf[:8], cps, dir.DIR_L); err != ErrLength {
+		t.Errorf("Unmarshal truncated error = %v, want %v", err, ErrLength)
+	}
+}
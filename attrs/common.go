@@ -179,6 +179,8 @@ func (a *Aggregator) Marshal(dst []byte, cps caps.Caps, dir dir.Dir) []byte {
 	dst = a.CodeFlags.MarshalLen(dst, asnlen+4)
 	if asnlen == 4 {
 		dst = msb.AppendUint32(dst, a.ASN)
+	} else if a.ASN > 0xffff {
+		dst = msb.AppendUint16(dst, AS_TRANS) // rfc6793/4.2.2
 	} else {
 		dst = msb.AppendUint16(dst, uint16(a.ASN))
 	}
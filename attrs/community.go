@@ -2,6 +2,7 @@ package attrs
 
 import (
 	"bytes"
+	"slices"
 	"strconv"
 
 	"github.com/bgpfix/bgpfix/caps"
@@ -16,6 +17,49 @@ type Community struct {
 	Value []uint16
 }
 
+// Well-known communities, rfc1997, rfc3765, rfc7611, rfc7999, rfc8326, rfc9494
+const (
+	COMMUNITY_GRACEFUL_SHUTDOWN   uint32 = 0xFFFF0000
+	COMMUNITY_ACCEPT_OWN          uint32 = 0xFFFF0001
+	COMMUNITY_LLGR_STALE          uint32 = 0xFFFF0006
+	COMMUNITY_NO_LLGR             uint32 = 0xFFFF0007
+	COMMUNITY_BLACKHOLE           uint32 = 0xFFFF029A
+	COMMUNITY_NO_EXPORT           uint32 = 0xFFFFFF01
+	COMMUNITY_NO_ADVERTISE        uint32 = 0xFFFFFF02
+	COMMUNITY_NO_EXPORT_SUBCONFED uint32 = 0xFFFFFF03
+	COMMUNITY_NO_PEER             uint32 = 0xFFFFFF04
+)
+
+// CommunityName maps well-known communities to their names
+var CommunityName = map[uint32]string{
+	COMMUNITY_GRACEFUL_SHUTDOWN:   "GRACEFUL_SHUTDOWN",
+	COMMUNITY_ACCEPT_OWN:          "ACCEPT_OWN",
+	COMMUNITY_LLGR_STALE:          "LLGR_STALE",
+	COMMUNITY_NO_LLGR:             "NO_LLGR",
+	COMMUNITY_BLACKHOLE:           "BLACKHOLE",
+	COMMUNITY_NO_EXPORT:           "NO_EXPORT",
+	COMMUNITY_NO_ADVERTISE:        "NO_ADVERTISE",
+	COMMUNITY_NO_EXPORT_SUBCONFED: "NO_EXPORT_SUBCONFED",
+	COMMUNITY_NO_PEER:             "NO_PEER",
+}
+
+// CommunityValue maps well-known community names to their values
+var CommunityValue = map[string]uint32{
+	"GRACEFUL_SHUTDOWN":   COMMUNITY_GRACEFUL_SHUTDOWN,
+	"ACCEPT_OWN":          COMMUNITY_ACCEPT_OWN,
+	"LLGR_STALE":          COMMUNITY_LLGR_STALE,
+	"NO_LLGR":             COMMUNITY_NO_LLGR,
+	"BLACKHOLE":           COMMUNITY_BLACKHOLE,
+	"NO_EXPORT":           COMMUNITY_NO_EXPORT,
+	"NO_ADVERTISE":        COMMUNITY_NO_ADVERTISE,
+	"NO_EXPORT_SUBCONFED": COMMUNITY_NO_EXPORT_SUBCONFED,
+	"NO_PEER":             COMMUNITY_NO_PEER,
+}
+
+// CommunityNames enables rendering well-known communities by name in Community.ToJSON.
+// Community.FromJSON always accepts both forms.
+var CommunityNames = false
+
 func NewCommunity(at CodeFlags) Attr {
 	return &Community{CodeFlags: at}
 }
@@ -32,11 +76,73 @@ func (a *Community) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) error {
 	return nil
 }
 
+// Add appends community asn:value
 func (a *Community) Add(asn uint16, value uint16) {
 	a.ASN = append(a.ASN, asn)
 	a.Value = append(a.Value, value)
 }
 
+// Has returns true iff a has community asn:value
+func (a *Community) Has(asn uint16, value uint16) bool {
+	return a.Find(asn, value) >= 0
+}
+
+// Find returns index of community asn:value, or -1
+func (a *Community) Find(asn uint16, value uint16) int {
+	if a == nil {
+		return -1
+	}
+	for i := range a.ASN {
+		if a.ASN[i] == asn && a.Value[i] == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// Drop drops all occurrences of community asn:value.
+// Returns true iff a was modified.
+func (a *Community) Drop(asn uint16, value uint16) (modified bool) {
+	if a == nil {
+		return false
+	}
+	j := 0
+	for i := range a.ASN {
+		if a.ASN[i] == asn && a.Value[i] == value {
+			continue
+		}
+		a.ASN[j], a.Value[j] = a.ASN[i], a.Value[i]
+		j++
+	}
+	if j != len(a.ASN) {
+		a.ASN, a.Value = a.ASN[:j], a.Value[:j]
+		return true
+	}
+	return false
+}
+
+// HasWellKnown returns true iff a has the well-known community of given name,
+// eg. NO_EXPORT or BLACKHOLE (see CommunityValue).
+func (a *Community) HasWellKnown(name string) bool {
+	v, ok := CommunityValue[name]
+	return ok && a.Has(uint16(v>>16), uint16(v))
+}
+
+// Sort sorts communities in a in ascending order, dropping duplicates
+func (a *Community) Sort() {
+	all := make([]uint32, len(a.ASN))
+	for i := range a.ASN {
+		all[i] = uint32(a.ASN[i])<<16 | uint32(a.Value[i])
+	}
+	slices.Sort(all)
+	all = slices.Compact(all)
+
+	a.ASN, a.Value = a.ASN[:0], a.Value[:0]
+	for _, v := range all {
+		a.Add(uint16(v>>16), uint16(v))
+	}
+}
+
 func (a *Community) Marshal(dst []byte, cps caps.Caps, dir dir.Dir) []byte {
 	tl := 4 * len(a.ASN)
 	dst = a.CodeFlags.MarshalLen(dst, tl)
@@ -55,6 +161,13 @@ func (a *Community) ToJSON(dst []byte) []byte {
 		} else {
 			dst = append(dst, `"`...)
 		}
+		if CommunityNames {
+			if name, ok := CommunityName[uint32(a.ASN[i])<<16|uint32(a.Value[i])]; ok {
+				dst = append(dst, name...)
+				dst = append(dst, '"')
+				continue
+			}
+		}
 		dst = strconv.AppendUint(dst, uint64(a.ASN[i]), 10)
 		dst = append(dst, ':')
 		dst = strconv.AppendUint(dst, uint64(a.Value[i]), 10)
@@ -66,6 +179,11 @@ func (a *Community) ToJSON(dst []byte) []byte {
 func (a *Community) FromJSON(src []byte) error {
 	sep := []byte(":")
 	return json.ArrayEach(src, func(key int, val []byte, typ json.Type) error {
+		if v, ok := CommunityValue[json.S(val)]; ok {
+			a.Add(uint16(v>>16), uint16(v))
+			return nil
+		}
+
 		d := bytes.Split(val, sep)
 		if len(d) != 2 {
 			return ErrValue
@@ -0,0 +1,84 @@
+package attrs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestCommunity(t *testing.T) {
+	var cps caps.Caps
+	a := NewAttr(ATTR_COMMUNITY).(*Community)
+	if err := a.FromJSON([]byte(`["666:666","100:1","666:666"]`)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+
+	// add blackhole
+	if a.HasWellKnown("BLACKHOLE") {
+		t.Errorf("HasWellKnown(BLACKHOLE) = true before Add")
+	}
+	a.Add(0xffff, 0x029a)
+	if !a.HasWellKnown("BLACKHOLE") {
+		t.Errorf("HasWellKnown(BLACKHOLE) = false after Add")
+	}
+	if a.HasWellKnown("NO_EXPORT") || a.HasWellKnown("INVALID") {
+		t.Errorf("HasWellKnown returned true for a missing community")
+	}
+
+	// drop 666:666
+	if !a.Has(666, 666) {
+		t.Errorf("Has(666, 666) = false")
+	}
+	if !a.Drop(666, 666) {
+		t.Errorf("Drop(666, 666) = false")
+	}
+	if a.Has(666, 666) || a.Drop(666, 666) {
+		t.Errorf("666:666 still present after Drop")
+	}
+	if got, want := a.Find(100, 1), 0; got != want {
+		t.Errorf("Find(100, 1) = %d, want %d", got, want)
+	}
+
+	buf := []byte{0x00, 0x64, 0x00, 0x01, 0xff, 0xff, 0x02, 0x9a}
+	if got := a.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("Marshal = %x, want %x", got[3:], buf)
+	}
+
+	// JSON with and without names
+	if got, want := string(a.ToJSON(nil)), `["100:1","65535:666"]`; got != want {
+		t.Errorf("ToJSON = %s, want %s", got, want)
+	}
+	CommunityNames = true
+	defer func() { CommunityNames = false }()
+	js := `["100:1","BLACKHOLE"]`
+	if got := string(a.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON names = %s, want %s", got, js)
+	}
+
+	b := NewAttr(ATTR_COMMUNITY).(*Community)
+	if err := b.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON names error = %v", err)
+	}
+	if got := b.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("FromJSON names Marshal = %x, want %x", got[3:], buf)
+	}
+
+	// a mix of named and numeric communities, round-trip
+	js = `["GRACEFUL_SHUTDOWN","65000:1","NO_EXPORT","65535:1234","NO_LLGR"]`
+	c := NewAttr(ATTR_COMMUNITY).(*Community)
+	if err := c.FromJSON([]byte(`["65535:0","65000:1","NO_EXPORT","65535:1234","0xffff:0x0007"]`)); err != nil {
+		t.Fatalf("FromJSON mix error = %v", err)
+	}
+	if got := string(c.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON mix = %s, want %s", got, js)
+	}
+	d := NewAttr(ATTR_COMMUNITY).(*Community)
+	if err := d.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON mix names error = %v", err)
+	}
+	if got, want := d.Marshal(nil, cps, dir.DIR_L), c.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got, want) {
+		t.Errorf("FromJSON mix Marshal = %x, want %x", got, want)
+	}
+}
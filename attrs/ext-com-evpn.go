@@ -0,0 +1,162 @@
+package attrs
+
+import (
+	"net"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/json"
+)
+
+// ExtcomMacMobility represents the EVPN MAC Mobility extended community, rfc7432/7.7
+type ExtcomMacMobility struct {
+	Sticky bool   // if set, the MAC address is static
+	Seq    uint32 // sequence number
+}
+
+func NewExtcomMacMobility(et ExtcomType) ExtcomValue {
+	return &ExtcomMacMobility{}
+}
+
+func (e *ExtcomMacMobility) Unmarshal(raw uint64) error {
+	e.Sticky = (raw>>40)&0x01 != 0
+	e.Seq = uint32(raw)
+	return nil
+}
+
+func (e *ExtcomMacMobility) Marshal(cps caps.Caps) uint64 {
+	var raw uint64
+	if e.Sticky {
+		raw |= 0x01 << 40
+	}
+	raw |= uint64(e.Seq)
+	return raw
+}
+
+func (e *ExtcomMacMobility) ToJSON(dst []byte) []byte {
+	dst = append(dst, `{"sticky":`...)
+	dst = json.Bool(dst, e.Sticky)
+	dst = append(dst, `,"seq":`...)
+	dst = json.Uint32(dst, e.Seq)
+	return append(dst, '}')
+}
+
+func (e *ExtcomMacMobility) FromJSON(src []byte) (err error) {
+	e.Sticky = json.GetBool(src, "sticky")
+	e.Seq = 0
+	if v := json.Get(src, "seq"); v != nil {
+		e.Seq, err = json.UnUint32(v)
+	}
+	return err
+}
+
+// ExtcomEsiLabel represents the EVPN ESI Label extended community, rfc7432/7.5
+type ExtcomEsiLabel struct {
+	SingleActive bool   // if set, the ES is in single-active redundancy mode
+	Label        uint32 // MPLS label (20 bits)
+}
+
+func NewExtcomEsiLabel(et ExtcomType) ExtcomValue {
+	return &ExtcomEsiLabel{}
+}
+
+func (e *ExtcomEsiLabel) Unmarshal(raw uint64) error {
+	e.SingleActive = (raw>>40)&0x01 != 0
+	e.Label = uint32(raw&0xffffff) >> 4
+	return nil
+}
+
+func (e *ExtcomEsiLabel) Marshal(cps caps.Caps) uint64 {
+	var raw uint64
+	if e.SingleActive {
+		raw |= 0x01 << 40
+	}
+	raw |= uint64(e.Label&0xfffff) << 4
+	return raw
+}
+
+func (e *ExtcomEsiLabel) ToJSON(dst []byte) []byte {
+	dst = append(dst, `{"single-active":`...)
+	dst = json.Bool(dst, e.SingleActive)
+	dst = append(dst, `,"label":`...)
+	dst = json.Uint32(dst, e.Label)
+	return append(dst, '}')
+}
+
+func (e *ExtcomEsiLabel) FromJSON(src []byte) (err error) {
+	e.SingleActive = json.GetBool(src, "single-active")
+	e.Label = 0
+	if v := json.Get(src, "label"); v != nil {
+		e.Label, err = json.UnUint32(v)
+	}
+	if err == nil && e.Label > 0xfffff {
+		return ErrValue
+	}
+	return err
+}
+
+// ExtcomMAC represents extended communities carrying a MAC address, eg.
+// the EVPN ES-Import Route Target (rfc7432/7.6) or Router's MAC (rfc9135/8.1)
+type ExtcomMAC struct {
+	MAC net.HardwareAddr
+}
+
+func NewExtcomMAC(et ExtcomType) ExtcomValue {
+	return &ExtcomMAC{}
+}
+
+func (e *ExtcomMAC) Unmarshal(raw uint64) error {
+	e.MAC = net.HardwareAddr{
+		byte(raw >> 40), byte(raw >> 32), byte(raw >> 24),
+		byte(raw >> 16), byte(raw >> 8), byte(raw)}
+	return nil
+}
+
+func (e *ExtcomMAC) Marshal(cps caps.Caps) uint64 {
+	var raw uint64
+	if len(e.MAC) == 6 {
+		for _, b := range e.MAC {
+			raw = raw<<8 | uint64(b)
+		}
+	}
+	return raw
+}
+
+func (e *ExtcomMAC) ToJSON(dst []byte) []byte {
+	dst = append(dst, '"')
+	dst = append(dst, e.MAC.String()...)
+	return append(dst, '"')
+}
+
+func (e *ExtcomMAC) FromJSON(src []byte) error {
+	mac, err := net.ParseMAC(json.SQ(src))
+	if err != nil {
+		return err
+	} else if len(mac) != 6 {
+		return ErrValue
+	}
+	e.MAC = mac
+	return nil
+}
+
+// ExtcomDefaultGateway represents the Default Gateway extended community, rfc7432/7.8
+type ExtcomDefaultGateway struct{}
+
+func NewExtcomDefaultGateway(et ExtcomType) ExtcomValue {
+	return &ExtcomDefaultGateway{}
+}
+
+func (e *ExtcomDefaultGateway) Unmarshal(raw uint64) error {
+	return nil
+}
+
+func (e *ExtcomDefaultGateway) Marshal(cps caps.Caps) uint64 {
+	return 0
+}
+
+func (e *ExtcomDefaultGateway) ToJSON(dst []byte) []byte {
+	return append(dst, json.True...)
+}
+
+func (e *ExtcomDefaultGateway) FromJSON(src []byte) error {
+	return nil
+}
@@ -0,0 +1,44 @@
+package attrs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestExtcom_EVPN(t *testing.T) {
+	buf := []byte{
+		0x06, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2a, // MAC Mobility: sticky, seq 42
+		0x06, 0x01, 0x01, 0x00, 0x00, 0x00, 0x3e, 0x80, // ESI Label: single-active, label 1000
+		0x06, 0x02, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, // ES-Import RT
+		0x06, 0x03, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, // Router's MAC
+		0x03, 0x0d, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Default Gateway
+	}
+	js := `[{"type":"EVPN_MAC_MOBILITY","value":{"sticky":true,"seq":42}},` +
+		`{"type":"EVPN_ESI_LABEL","value":{"single-active":true,"label":1000}},` +
+		`{"type":"EVPN_ES_IMPORT","value":"00:11:22:33:44:55"},` +
+		`{"type":"EVPN_ROUTER_MAC","value":"aa:bb:cc:dd:ee:ff"},` +
+		`{"type":"DEFAULT_GATEWAY","value":true}]`
+
+	var cps caps.Caps
+	a := NewAttr(ATTR_EXT_COMMUNITY).(*Extcom)
+	if err := a.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if got := string(a.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON = %s\nwant %s", got, js)
+	}
+	if mm, ok := a.Value[0].(*ExtcomMacMobility); !ok || mm.Seq != 42 || !mm.Sticky {
+		t.Errorf("MAC Mobility = %#v", a.Value[0])
+	}
+
+	b := NewAttr(ATTR_EXT_COMMUNITY).(*Extcom)
+	if err := b.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	if got := b.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("Marshal = %x\nwant %x", got[3:], buf)
+	}
+}
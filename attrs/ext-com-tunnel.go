@@ -0,0 +1,68 @@
+package attrs
+
+import (
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/json"
+)
+
+// ExtcomColor represents the Color extended community, rfc9012/4.3
+type ExtcomColor struct {
+	Flags uint16 // flags, eg. the Color-Only bits (rfc9256/8.8.1)
+	Color uint32 // color value
+}
+
+func NewExtcomColor(et ExtcomType) ExtcomValue {
+	return &ExtcomColor{}
+}
+
+func (e *ExtcomColor) Unmarshal(raw uint64) error {
+	e.Flags = uint16(raw >> 32)
+	e.Color = uint32(raw)
+	return nil
+}
+
+func (e *ExtcomColor) Marshal(cps caps.Caps) uint64 {
+	return uint64(e.Flags)<<32 | uint64(e.Color)
+}
+
+func (e *ExtcomColor) ToJSON(dst []byte) []byte {
+	if e.Flags != 0 {
+		dst = append(dst, `{"flags":`...)
+		dst = json.Uint16(dst, e.Flags)
+		dst = append(dst, `,"color":`...)
+	} else {
+		dst = append(dst, `{"color":`...)
+	}
+	dst = json.Uint32(dst, e.Color)
+	return append(dst, '}')
+}
+
+func (e *ExtcomColor) FromJSON(src []byte) error {
+	e.Flags, e.Color = 0, 0
+	return json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "flags":
+			e.Flags, err = json.UnUint16(val)
+		case "color":
+			e.Color, err = json.UnUint32(val)
+		}
+		return
+	})
+}
+
+// Color returns the first Color extended community value in a, or 0 if not found
+func (a *Extcom) Color() uint32 {
+	if i := a.Find(EXTCOM_COLOR); i >= 0 {
+		if ec, ok := a.Value[i].(*ExtcomColor); ok {
+			return ec.Color
+		}
+	}
+	return 0
+}
+
+// SetColor sets the Color extended community in a to color, with no flags,
+// replacing all existing Color values.
+func (a *Extcom) SetColor(color uint32) {
+	a.Drop(EXTCOM_COLOR)
+	a.Add(EXTCOM_COLOR, &ExtcomColor{Color: color})
+}
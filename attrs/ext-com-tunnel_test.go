@@ -0,0 +1,48 @@
+package attrs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestExtcom_Color(t *testing.T) {
+	buf := []byte{
+		0x03, 0x0b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x64, // Color 100
+		0x03, 0x0b, 0x40, 0x00, 0x00, 0x01, 0x00, 0x00, // Color 65536, CO bits 01
+	}
+	js := `[{"type":"COLOR","value":{"color":100}},` +
+		`{"type":"COLOR","value":{"flags":16384,"color":65536}}]`
+
+	var cps caps.Caps
+	a := NewAttr(ATTR_EXT_COMMUNITY).(*Extcom)
+	if err := a.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if got := string(a.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON = %s\nwant %s", got, js)
+	}
+	if got := a.Color(); got != 100 {
+		t.Errorf("Color() = %d, want 100", got)
+	}
+
+	b := NewAttr(ATTR_EXT_COMMUNITY).(*Extcom)
+	if err := b.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	if got := b.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("Marshal = %x\nwant %x", got[3:], buf)
+	}
+
+	// SetColor replaces all existing values
+	b.SetColor(42)
+	want := []byte{0x03, 0x0b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a}
+	if got := b.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], want) {
+		t.Errorf("SetColor Marshal = %x\nwant %x", got[3:], want)
+	}
+	if got := b.Color(); got != 42 {
+		t.Errorf("Color() after SetColor = %d, want 42", got)
+	}
+}
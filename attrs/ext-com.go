@@ -1,7 +1,9 @@
 package attrs
 
 import (
+	"cmp"
 	"fmt"
+	"slices"
 	"strconv"
 
 	"github.com/bgpfix/bgpfix/caps"
@@ -69,6 +71,16 @@ const (
 	EXTCOM_FLOW_REDIRECT_AS4 ExtcomType = 0x8208
 	EXTCOM_FLOW_REDIRECT_NH  ExtcomType = 0x0800 // draft-simpson-idr-flowspec-redirect-02.txt
 	EXTCOM_FLOW_DSCP         ExtcomType = 0x8009
+
+	// evpn, rfc7432/7
+	EXTCOM_EVPN_MAC_MOBILITY ExtcomType = 0x0600
+	EXTCOM_EVPN_ESI_LABEL    ExtcomType = 0x0601
+	EXTCOM_EVPN_ES_IMPORT    ExtcomType = 0x0602
+	EXTCOM_EVPN_ROUTER_MAC   ExtcomType = 0x0603 // rfc9135/8.1
+	EXTCOM_DEFAULT_GATEWAY   ExtcomType = 0x030d
+
+	// tunnel encapsulation, rfc9012/4.3
+	EXTCOM_COLOR ExtcomType = 0x030b
 )
 
 //go:generate go run github.com/dmarkham/enumer -type ExtcomType -trimprefix EXTCOM_
@@ -88,6 +100,16 @@ var ExtcomNewFuncs = map[ExtcomType]ExtcomNewFunc{
 	EXTCOM_FLOW_REDIRECT_NH:  NewExtcomFlowRedirectNH,
 	EXTCOM_FLOW_DSCP:         NewExtcomFlowDSCP,
 
+	// evpn
+	EXTCOM_EVPN_MAC_MOBILITY: NewExtcomMacMobility,
+	EXTCOM_EVPN_ESI_LABEL:    NewExtcomEsiLabel,
+	EXTCOM_EVPN_ES_IMPORT:    NewExtcomMAC,
+	EXTCOM_EVPN_ROUTER_MAC:   NewExtcomMAC,
+	EXTCOM_DEFAULT_GATEWAY:   NewExtcomDefaultGateway,
+
+	// tunnel encapsulation
+	EXTCOM_COLOR: NewExtcomColor,
+
 	// generic type NewExtcomrs
 	EXTCOM_AS2: NewExtcomASN,
 	EXTCOM_AS4: NewExtcomASN,
@@ -276,6 +298,35 @@ func (a *Extcom) Drop(et ExtcomType) {
 	}
 }
 
+// Sort sorts extended communities in a by their wire representation,
+// dropping duplicates and values dropped earlier.
+func (a *Extcom) Sort(cps caps.Caps) {
+	type ec struct {
+		u64 uint64
+		et  ExtcomType
+		val ExtcomValue
+	}
+
+	all := make([]ec, 0, len(a.Type))
+	for i, et := range a.Type {
+		if val := a.Value[i]; val != nil {
+			u64 := val.Marshal(cps)&0x0000ffffffffffff | uint64(et)<<48
+			all = append(all, ec{u64, et, val})
+		}
+	}
+	slices.SortStableFunc(all, func(x, y ec) int {
+		return cmp.Compare(x.u64, y.u64)
+	})
+	all = slices.CompactFunc(all, func(x, y ec) bool {
+		return x.u64 == y.u64
+	})
+
+	a.Type, a.Value = a.Type[:0], a.Value[:0]
+	for _, v := range all {
+		a.Add(v.et, v.val)
+	}
+}
+
 // Find returns index of community type et, or -1
 func (a *Extcom) Find(et ExtcomType) int {
 	for i, et2 := range a.Type {
@@ -0,0 +1,312 @@
+package attrs
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/json"
+)
+
+// Extcom6 represents ATTR_IPV6_EXT_COMMUNITY (rfc5701)
+type Extcom6 struct {
+	CodeFlags
+
+	Type  []ExtcomType   // top 2 bytes
+	Value []Extcom6Value // bottom 18 bytes
+}
+
+// IPv6 Address Specific Extended Community Value
+type Extcom6Value interface {
+	// Unmarshal parses wire representation from src (18 bytes)
+	Unmarshal(src []byte) error
+
+	// Marshal appends wire representation of the value to dst (18 bytes)
+	Marshal(dst []byte, cps caps.Caps) []byte
+
+	// ToJSON appends JSON representation of the value to dst
+	ToJSON(dst []byte) []byte
+
+	// FromJSON reads value from JSON representation in src
+	FromJSON(src []byte) error
+}
+
+const (
+	// types
+	EXTCOM6_IP6 ExtcomType = 0x0000
+
+	// subtypes
+	EXTCOM6_TARGET ExtcomType = EXTCOM6_IP6 | EXTCOM_TARGET
+	EXTCOM6_ORIGIN ExtcomType = EXTCOM6_IP6 | EXTCOM_ORIGIN
+)
+
+// Extcom6TypeName maps IPv6 extended community types to their names
+var Extcom6TypeName = map[ExtcomType]string{
+	EXTCOM6_TARGET: "TARGET",
+	EXTCOM6_ORIGIN: "ORIGIN",
+}
+
+// Extcom6NewFunc returns a new Extcom6Value for given type
+type Extcom6NewFunc func(ExtcomType) Extcom6Value
+
+// Extcom6NewFuncs maps IPv6 extended community types to their new func
+var Extcom6NewFuncs = map[ExtcomType]Extcom6NewFunc{
+	EXTCOM6_IP6: NewExtcom6Addr,
+}
+
+func NewExtcom6(at CodeFlags) Attr {
+	return &Extcom6{CodeFlags: at}
+}
+
+// NewExtcom6Value returns a new Extcom6Value for given ExtcomType
+func NewExtcom6Value(et ExtcomType) Extcom6Value {
+	if newfunc, ok := Extcom6NewFuncs[et.Value()]; ok {
+		return newfunc(et.Value())
+	} else if newfunc, ok := Extcom6NewFuncs[et.Type()]; ok {
+		return newfunc(et.Type())
+	} else {
+		return NewExtcom6Raw(et)
+	}
+}
+
+func (a *Extcom6) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) error {
+	for len(buf) > 0 {
+		if len(buf) < 20 {
+			return ErrLength
+		}
+
+		et := ExtcomType(msb.Uint16(buf))
+		ev := NewExtcom6Value(et)
+		if err := ev.Unmarshal(buf[2:20]); err != nil {
+			return err
+		}
+		a.Add(et, ev)
+		buf = buf[20:]
+	}
+
+	return nil
+}
+
+func (a *Extcom6) Marshal(dst []byte, cps caps.Caps, dir dir.Dir) []byte {
+	tl := 0
+	for _, val := range a.Value {
+		if val != nil {
+			tl += 20
+		}
+	}
+	dst = a.CodeFlags.MarshalLen(dst, tl)
+	for i := range a.Type {
+		et, val := a.Type[i], a.Value[i]
+		if val == nil {
+			continue
+		}
+		dst = msb.AppendUint16(dst, uint16(et))
+		dst = val.Marshal(dst, cps)
+	}
+	return dst
+}
+
+func (a *Extcom6) ToJSON(dst []byte) []byte {
+	dst = append(dst, '[')
+	first := true
+	for i := range a.Type {
+		et, val := a.Type[i], a.Value[i]
+		if val == nil {
+			continue
+		} else if !first {
+			dst = append(dst, ',')
+		} else {
+			first = false
+		}
+
+		dst = append(dst, `{"type":"`...)
+		if name, ok := Extcom6TypeName[et.Value()]; ok {
+			dst = append(dst, name...)
+		} else {
+			dst = append(dst, `0x`...)
+			dst = strconv.AppendUint(dst, uint64(et.Value()), 16)
+		}
+
+		dst = append(dst, `","value":`...)
+		dst = val.ToJSON(dst)
+
+		if !et.IsTransitive() {
+			dst = append(dst, `,"nontransitive":true`...)
+		}
+
+		dst = append(dst, '}')
+	}
+	return append(dst, ']')
+}
+
+func (a *Extcom6) FromJSON(src []byte) error {
+	return json.ArrayEach(src, func(key int, val []byte, typ json.Type) error {
+		// get community type
+		var et ExtcomType
+		v := json.Get(val, "type")
+		if v == nil {
+			return ErrExtcomType
+		}
+		if err := extcom6Type(&et, json.SQ(v)); err != nil {
+			return fmt.Errorf("%w: %w", ErrExtcomType, err)
+		}
+
+		// transitive? (best-effort)
+		if json.GetBool(val, "nontransitive") {
+			et |= EXTCOM_TRANSITIVE
+		}
+
+		// get community value
+		v = json.Get(val, "value")
+		if v == nil {
+			return ErrExtcomValue
+		}
+
+		// parse and store
+		ev := NewExtcom6Value(et)
+		if err := ev.FromJSON(v); err != nil {
+			return fmt.Errorf("%w: %w", ErrExtcomValue, err)
+		}
+		a.Add(et, ev)
+		return nil
+	})
+}
+
+// extcom6Type parses IPv6 extended community type name or number in src into et
+func extcom6Type(et *ExtcomType, src string) error {
+	for et2, name := range Extcom6TypeName {
+		if name == src {
+			*et = et2
+			return nil
+		}
+	}
+
+	v, err := strconv.ParseUint(src, 0, 16)
+	if err != nil {
+		return err
+	}
+	*et = ExtcomType(v)
+	return nil
+}
+
+// Add appends IPv6 extended community type et with value val
+func (a *Extcom6) Add(et ExtcomType, val Extcom6Value) {
+	a.Type = append(a.Type, et)
+	a.Value = append(a.Value, val)
+}
+
+// Drop drops IPv6 extended community type et
+func (a *Extcom6) Drop(et ExtcomType) {
+	for i, et2 := range a.Type {
+		if et2 == et {
+			a.Value[i] = nil
+		}
+	}
+}
+
+// Find returns index of IPv6 extended community type et, or -1
+func (a *Extcom6) Find(et ExtcomType) int {
+	for i, et2 := range a.Type {
+		if et2 == et && a.Value[i] != nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// The basic (raw) IPv6 Extended Community value
+type Extcom6Raw struct {
+	Raw [18]byte
+}
+
+func NewExtcom6Raw(et ExtcomType) Extcom6Value {
+	return &Extcom6Raw{}
+}
+
+func (e *Extcom6Raw) Unmarshal(src []byte) error {
+	if len(src) != len(e.Raw) {
+		return ErrLength
+	}
+	copy(e.Raw[:], src)
+	return nil
+}
+
+func (e *Extcom6Raw) Marshal(dst []byte, cps caps.Caps) []byte {
+	return append(dst, e.Raw[:]...)
+}
+
+func (e *Extcom6Raw) ToJSON(dst []byte) []byte {
+	return json.Hex(dst, e.Raw[:])
+}
+
+func (e *Extcom6Raw) FromJSON(src []byte) error {
+	buf, err := json.UnHex(src, nil)
+	if err == nil && len(buf) != len(e.Raw) {
+		err = ErrLength
+	}
+	copy(e.Raw[:], buf)
+	return err
+}
+
+// IPv6 address specific value, eg. IPv6 Route Target (rfc5701/3)
+type Extcom6Addr struct {
+	Addr  netip.Addr // Global Administrator
+	Value uint16     // Local Administrator
+}
+
+func NewExtcom6Addr(et ExtcomType) Extcom6Value {
+	return &Extcom6Addr{}
+}
+
+func (e *Extcom6Addr) Unmarshal(src []byte) error {
+	if len(src) != 18 {
+		return ErrLength
+	}
+	e.Addr = netip.AddrFrom16([16]byte(src[0:16]))
+	e.Value = msb.Uint16(src[16:18])
+	return nil
+}
+
+func (e *Extcom6Addr) Marshal(dst []byte, cps caps.Caps) []byte {
+	if e.Addr.Is6() {
+		dst = append(dst, e.Addr.AsSlice()...)
+	} else {
+		dst = append(dst, make([]byte, 16)...)
+	}
+	return msb.AppendUint16(dst, e.Value)
+}
+
+func (e *Extcom6Addr) ToJSON(dst []byte) []byte {
+	dst = append(dst, '"')
+	dst = e.Addr.AppendTo(dst)
+	dst = append(dst, ':')
+	dst = strconv.AppendUint(dst, uint64(e.Value), 10)
+	return append(dst, '"')
+}
+
+func (e *Extcom6Addr) FromJSON(src []byte) error {
+	ss := json.SQ(src)
+	i := strings.LastIndexByte(ss, ':')
+	if i < 0 {
+		return ErrValue
+	}
+
+	a, err := netip.ParseAddr(ss[:i])
+	if err != nil {
+		return err
+	} else if !a.Is6() {
+		return ErrAF
+	}
+	e.Addr = a
+
+	v, err := strconv.ParseUint(ss[i+1:], 10, 16)
+	if err != nil {
+		return err
+	}
+	e.Value = uint16(v)
+
+	return nil
+}
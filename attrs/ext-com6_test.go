@@ -0,0 +1,74 @@
+package attrs
+
+import (
+	"bytes"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestExtcom6(t *testing.T) {
+	buf := []byte{
+		0x00, 0x02, // IPv6 Route Target
+		0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, // 2001:db8::1
+		0x00, 0x64, // 100
+		0x40, 0x03, // IPv6 Route Origin, non-transitive
+		0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // 2001:db8::
+		0x00, 0x01, // 1
+		0x00, 0x42, // unknown subtype
+		0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x02, // 2001:db8::2
+		0x00, 0x05, // 5
+	}
+	js := `[{"type":"TARGET","value":"2001:db8::1:100"},{"type":"ORIGIN","value":"2001:db8:::1","nontransitive":true},{"type":"0x42","value":"2001:db8::2:5"}]`
+
+	var cps caps.Caps
+	a := NewAttr(ATTR_IPV6_EXT_COMMUNITY).(*Extcom6)
+	if err := a.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+
+	i := a.Find(EXTCOM6_TARGET)
+	if i < 0 {
+		t.Fatalf("Find(EXTCOM6_TARGET) = -1")
+	}
+	rt, ok := a.Value[i].(*Extcom6Addr)
+	if !ok {
+		t.Fatalf("Value = %T, want *Extcom6Addr", a.Value[i])
+	}
+	if rt.Addr != netip.MustParseAddr("2001:db8::1") || rt.Value != 100 {
+		t.Errorf("route target = %s:%d, want 2001:db8::1:100", rt.Addr, rt.Value)
+	}
+
+	if got := string(a.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON = %s, want %s", got, js)
+	}
+	if got := a.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("Marshal = %x, want %x", got[3:], buf)
+	}
+
+	b := NewAttr(ATTR_IPV6_EXT_COMMUNITY).(*Extcom6)
+	if err := b.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	if got := b.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("FromJSON Marshal = %x, want %x", got[3:], buf)
+	}
+
+	// dropped values are not marshaled
+	b.Drop(EXTCOM6_TARGET)
+	if got := b.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf[20:]) {
+		t.Errorf("Drop Marshal = %x, want %x", got[3:], buf[20:])
+	}
+
+	// malformed
+	c := NewAttr(ATTR_IPV6_EXT_COMMUNITY).(*Extcom6)
+	if err := c.Unmarshal(buf[:19], cps, dir.DIR_L); !errors.Is(err, ErrLength) {
+		t.Errorf("Unmarshal(short) error = %v, want ErrLength", err)
+	}
+	if err := c.FromJSON([]byte(`[{"type":"TARGET","value":"192.0.2.1:100"}]`)); !errors.Is(err, ErrExtcomValue) {
+		t.Errorf("FromJSON(IPv4) error = %v, want ErrExtcomValue", err)
+	}
+}
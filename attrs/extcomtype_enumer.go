@@ -7,8 +7,8 @@ import (
 	"strings"
 )
 
-const _ExtcomTypeName = "AS2TARGETORIGINSUBTYPEIP4IP4_TARGETIP4_ORIGINAS4AS4_TARGETAS4_ORIGINFLOW_REDIRECT_NHTRANSITIVEFLOW_RATE_BYTESFLOW_ACTIONFLOW_REDIRECT_AS2FLOW_DSCPFLOW_RATE_PACKETSFLOW_REDIRECT_IP4FLOW_REDIRECT_AS4TYPE"
-const _ExtcomTypeLowerName = "as2targetoriginsubtypeip4ip4_targetip4_originas4as4_targetas4_originflow_redirect_nhtransitiveflow_rate_bytesflow_actionflow_redirect_as2flow_dscpflow_rate_packetsflow_redirect_ip4flow_redirect_as4type"
+const _ExtcomTypeName = "AS2TARGETORIGINSUBTYPEIP4IP4_TARGETIP4_ORIGINAS4AS4_TARGETAS4_ORIGINCOLORDEFAULT_GATEWAYEVPN_MAC_MOBILITYEVPN_ESI_LABELEVPN_ES_IMPORTEVPN_ROUTER_MACFLOW_REDIRECT_NHTRANSITIVEFLOW_RATE_BYTESFLOW_ACTIONFLOW_REDIRECT_AS2FLOW_DSCPFLOW_RATE_PACKETSFLOW_REDIRECT_IP4FLOW_REDIRECT_AS4TYPE"
+const _ExtcomTypeLowerName = "as2targetoriginsubtypeip4ip4_targetip4_originas4as4_targetas4_origincolordefault_gatewayevpn_mac_mobilityevpn_esi_labelevpn_es_importevpn_router_macflow_redirect_nhtransitiveflow_rate_bytesflow_actionflow_redirect_as2flow_dscpflow_rate_packetsflow_redirect_ip4flow_redirect_as4type"
 
 var _ExtcomTypeMap = map[ExtcomType]string{
 	0:     _ExtcomTypeName[0:3],
@@ -21,16 +21,22 @@ var _ExtcomTypeMap = map[ExtcomType]string{
 	512:   _ExtcomTypeName[45:48],
 	514:   _ExtcomTypeName[48:58],
 	515:   _ExtcomTypeName[58:68],
-	2048:  _ExtcomTypeName[68:84],
-	16384: _ExtcomTypeName[84:94],
-	32774: _ExtcomTypeName[94:109],
-	32775: _ExtcomTypeName[109:120],
-	32776: _ExtcomTypeName[120:137],
-	32777: _ExtcomTypeName[137:146],
-	32780: _ExtcomTypeName[146:163],
-	33032: _ExtcomTypeName[163:180],
-	33288: _ExtcomTypeName[180:197],
-	48896: _ExtcomTypeName[197:201],
+	779:   _ExtcomTypeName[68:73],
+	781:   _ExtcomTypeName[73:88],
+	1536:  _ExtcomTypeName[88:105],
+	1537:  _ExtcomTypeName[105:119],
+	1538:  _ExtcomTypeName[119:133],
+	1539:  _ExtcomTypeName[133:148],
+	2048:  _ExtcomTypeName[148:164],
+	16384: _ExtcomTypeName[164:174],
+	32774: _ExtcomTypeName[174:189],
+	32775: _ExtcomTypeName[189:200],
+	32776: _ExtcomTypeName[200:217],
+	32777: _ExtcomTypeName[217:226],
+	32780: _ExtcomTypeName[226:243],
+	33032: _ExtcomTypeName[243:260],
+	33288: _ExtcomTypeName[260:277],
+	48896: _ExtcomTypeName[277:281],
 }
 
 func (i ExtcomType) String() string {
@@ -54,6 +60,12 @@ func _ExtcomTypeNoOp() {
 	_ = x[EXTCOM_AS4-(512)]
 	_ = x[EXTCOM_AS4_TARGET-(514)]
 	_ = x[EXTCOM_AS4_ORIGIN-(515)]
+	_ = x[EXTCOM_COLOR-(779)]
+	_ = x[EXTCOM_DEFAULT_GATEWAY-(781)]
+	_ = x[EXTCOM_EVPN_MAC_MOBILITY-(1536)]
+	_ = x[EXTCOM_EVPN_ESI_LABEL-(1537)]
+	_ = x[EXTCOM_EVPN_ES_IMPORT-(1538)]
+	_ = x[EXTCOM_EVPN_ROUTER_MAC-(1539)]
 	_ = x[EXTCOM_FLOW_REDIRECT_NH-(2048)]
 	_ = x[EXTCOM_TRANSITIVE-(16384)]
 	_ = x[EXTCOM_FLOW_RATE_BYTES-(32774)]
@@ -66,7 +78,7 @@ func _ExtcomTypeNoOp() {
 	_ = x[EXTCOM_TYPE-(48896)]
 }
 
-var _ExtcomTypeValues = []ExtcomType{EXTCOM_AS2, EXTCOM_TARGET, EXTCOM_ORIGIN, EXTCOM_SUBTYPE, EXTCOM_IP4, EXTCOM_IP4_TARGET, EXTCOM_IP4_ORIGIN, EXTCOM_AS4, EXTCOM_AS4_TARGET, EXTCOM_AS4_ORIGIN, EXTCOM_FLOW_REDIRECT_NH, EXTCOM_TRANSITIVE, EXTCOM_FLOW_RATE_BYTES, EXTCOM_FLOW_ACTION, EXTCOM_FLOW_REDIRECT_AS2, EXTCOM_FLOW_DSCP, EXTCOM_FLOW_RATE_PACKETS, EXTCOM_FLOW_REDIRECT_IP4, EXTCOM_FLOW_REDIRECT_AS4, EXTCOM_TYPE}
+var _ExtcomTypeValues = []ExtcomType{EXTCOM_AS2, EXTCOM_TARGET, EXTCOM_ORIGIN, EXTCOM_SUBTYPE, EXTCOM_IP4, EXTCOM_IP4_TARGET, EXTCOM_IP4_ORIGIN, EXTCOM_AS4, EXTCOM_AS4_TARGET, EXTCOM_AS4_ORIGIN, EXTCOM_COLOR, EXTCOM_DEFAULT_GATEWAY, EXTCOM_EVPN_MAC_MOBILITY, EXTCOM_EVPN_ESI_LABEL, EXTCOM_EVPN_ES_IMPORT, EXTCOM_EVPN_ROUTER_MAC, EXTCOM_FLOW_REDIRECT_NH, EXTCOM_TRANSITIVE, EXTCOM_FLOW_RATE_BYTES, EXTCOM_FLOW_ACTION, EXTCOM_FLOW_REDIRECT_AS2, EXTCOM_FLOW_DSCP, EXTCOM_FLOW_RATE_PACKETS, EXTCOM_FLOW_REDIRECT_IP4, EXTCOM_FLOW_REDIRECT_AS4, EXTCOM_TYPE}
 
 var _ExtcomTypeNameToValueMap = map[string]ExtcomType{
 	_ExtcomTypeName[0:3]:          EXTCOM_AS2,
@@ -89,26 +101,38 @@ var _ExtcomTypeNameToValueMap = map[string]ExtcomType{
 	_ExtcomTypeLowerName[48:58]:   EXTCOM_AS4_TARGET,
 	_ExtcomTypeName[58:68]:        EXTCOM_AS4_ORIGIN,
 	_ExtcomTypeLowerName[58:68]:   EXTCOM_AS4_ORIGIN,
-	_ExtcomTypeName[68:84]:        EXTCOM_FLOW_REDIRECT_NH,
-	_ExtcomTypeLowerName[68:84]:   EXTCOM_FLOW_REDIRECT_NH,
-	_ExtcomTypeName[84:94]:        EXTCOM_TRANSITIVE,
-	_ExtcomTypeLowerName[84:94]:   EXTCOM_TRANSITIVE,
-	_ExtcomTypeName[94:109]:       EXTCOM_FLOW_RATE_BYTES,
-	_ExtcomTypeLowerName[94:109]:  EXTCOM_FLOW_RATE_BYTES,
-	_ExtcomTypeName[109:120]:      EXTCOM_FLOW_ACTION,
-	_ExtcomTypeLowerName[109:120]: EXTCOM_FLOW_ACTION,
-	_ExtcomTypeName[120:137]:      EXTCOM_FLOW_REDIRECT_AS2,
-	_ExtcomTypeLowerName[120:137]: EXTCOM_FLOW_REDIRECT_AS2,
-	_ExtcomTypeName[137:146]:      EXTCOM_FLOW_DSCP,
-	_ExtcomTypeLowerName[137:146]: EXTCOM_FLOW_DSCP,
-	_ExtcomTypeName[146:163]:      EXTCOM_FLOW_RATE_PACKETS,
-	_ExtcomTypeLowerName[146:163]: EXTCOM_FLOW_RATE_PACKETS,
-	_ExtcomTypeName[163:180]:      EXTCOM_FLOW_REDIRECT_IP4,
-	_ExtcomTypeLowerName[163:180]: EXTCOM_FLOW_REDIRECT_IP4,
-	_ExtcomTypeName[180:197]:      EXTCOM_FLOW_REDIRECT_AS4,
-	_ExtcomTypeLowerName[180:197]: EXTCOM_FLOW_REDIRECT_AS4,
-	_ExtcomTypeName[197:201]:      EXTCOM_TYPE,
-	_ExtcomTypeLowerName[197:201]: EXTCOM_TYPE,
+	_ExtcomTypeName[68:73]:        EXTCOM_COLOR,
+	_ExtcomTypeLowerName[68:73]:   EXTCOM_COLOR,
+	_ExtcomTypeName[73:88]:        EXTCOM_DEFAULT_GATEWAY,
+	_ExtcomTypeLowerName[73:88]:   EXTCOM_DEFAULT_GATEWAY,
+	_ExtcomTypeName[88:105]:       EXTCOM_EVPN_MAC_MOBILITY,
+	_ExtcomTypeLowerName[88:105]:  EXTCOM_EVPN_MAC_MOBILITY,
+	_ExtcomTypeName[105:119]:      EXTCOM_EVPN_ESI_LABEL,
+	_ExtcomTypeLowerName[105:119]: EXTCOM_EVPN_ESI_LABEL,
+	_ExtcomTypeName[119:133]:      EXTCOM_EVPN_ES_IMPORT,
+	_ExtcomTypeLowerName[119:133]: EXTCOM_EVPN_ES_IMPORT,
+	_ExtcomTypeName[133:148]:      EXTCOM_EVPN_ROUTER_MAC,
+	_ExtcomTypeLowerName[133:148]: EXTCOM_EVPN_ROUTER_MAC,
+	_ExtcomTypeName[148:164]:      EXTCOM_FLOW_REDIRECT_NH,
+	_ExtcomTypeLowerName[148:164]: EXTCOM_FLOW_REDIRECT_NH,
+	_ExtcomTypeName[164:174]:      EXTCOM_TRANSITIVE,
+	_ExtcomTypeLowerName[164:174]: EXTCOM_TRANSITIVE,
+	_ExtcomTypeName[174:189]:      EXTCOM_FLOW_RATE_BYTES,
+	_ExtcomTypeLowerName[174:189]: EXTCOM_FLOW_RATE_BYTES,
+	_ExtcomTypeName[189:200]:      EXTCOM_FLOW_ACTION,
+	_ExtcomTypeLowerName[189:200]: EXTCOM_FLOW_ACTION,
+	_ExtcomTypeName[200:217]:      EXTCOM_FLOW_REDIRECT_AS2,
+	_ExtcomTypeLowerName[200:217]: EXTCOM_FLOW_REDIRECT_AS2,
+	_ExtcomTypeName[217:226]:      EXTCOM_FLOW_DSCP,
+	_ExtcomTypeLowerName[217:226]: EXTCOM_FLOW_DSCP,
+	_ExtcomTypeName[226:243]:      EXTCOM_FLOW_RATE_PACKETS,
+	_ExtcomTypeLowerName[226:243]: EXTCOM_FLOW_RATE_PACKETS,
+	_ExtcomTypeName[243:260]:      EXTCOM_FLOW_REDIRECT_IP4,
+	_ExtcomTypeLowerName[243:260]: EXTCOM_FLOW_REDIRECT_IP4,
+	_ExtcomTypeName[260:277]:      EXTCOM_FLOW_REDIRECT_AS4,
+	_ExtcomTypeLowerName[260:277]: EXTCOM_FLOW_REDIRECT_AS4,
+	_ExtcomTypeName[277:281]:      EXTCOM_TYPE,
+	_ExtcomTypeLowerName[277:281]: EXTCOM_TYPE,
 }
 
 var _ExtcomTypeNames = []string{
@@ -122,16 +146,22 @@ var _ExtcomTypeNames = []string{
 	_ExtcomTypeName[45:48],
 	_ExtcomTypeName[48:58],
 	_ExtcomTypeName[58:68],
-	_ExtcomTypeName[68:84],
-	_ExtcomTypeName[84:94],
-	_ExtcomTypeName[94:109],
-	_ExtcomTypeName[109:120],
-	_ExtcomTypeName[120:137],
-	_ExtcomTypeName[137:146],
-	_ExtcomTypeName[146:163],
-	_ExtcomTypeName[163:180],
-	_ExtcomTypeName[180:197],
-	_ExtcomTypeName[197:201],
+	_ExtcomTypeName[68:73],
+	_ExtcomTypeName[73:88],
+	_ExtcomTypeName[88:105],
+	_ExtcomTypeName[105:119],
+	_ExtcomTypeName[119:133],
+	_ExtcomTypeName[133:148],
+	_ExtcomTypeName[148:164],
+	_ExtcomTypeName[164:174],
+	_ExtcomTypeName[174:189],
+	_ExtcomTypeName[189:200],
+	_ExtcomTypeName[200:217],
+	_ExtcomTypeName[217:226],
+	_ExtcomTypeName[226:243],
+	_ExtcomTypeName[243:260],
+	_ExtcomTypeName[260:277],
+	_ExtcomTypeName[277:281],
 }
 
 // ExtcomTypeString retrieves an enum value from the enum constants string name.
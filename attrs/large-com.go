@@ -2,6 +2,8 @@ package attrs
 
 import (
 	"bytes"
+	"cmp"
+	"slices"
 	"strconv"
 
 	"github.com/bgpfix/bgpfix/caps"
@@ -37,12 +39,74 @@ func (a *LargeCom) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) error {
 	return nil
 }
 
+// Add appends large community asn:value1:value2 (see Sort for dropping duplicates)
 func (a *LargeCom) Add(asn, value1, value2 uint32) {
 	a.ASN = append(a.ASN, asn)
 	a.Value1 = append(a.Value1, value1)
 	a.Value2 = append(a.Value2, value2)
 }
 
+// Has returns true iff a has large community asn:value1:value2
+func (a *LargeCom) Has(asn, value1, value2 uint32) bool {
+	return a.Find(asn, value1, value2) >= 0
+}
+
+// Find returns index of large community asn:value1:value2, or -1
+func (a *LargeCom) Find(asn, value1, value2 uint32) int {
+	if a == nil {
+		return -1
+	}
+	for i := range a.ASN {
+		if a.ASN[i] == asn && a.Value1[i] == value1 && a.Value2[i] == value2 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Drop drops all occurrences of large community asn:value1:value2.
+// Returns true iff a was modified.
+func (a *LargeCom) Drop(asn, value1, value2 uint32) (modified bool) {
+	if a == nil {
+		return false
+	}
+	j := 0
+	for i := range a.ASN {
+		if a.ASN[i] == asn && a.Value1[i] == value1 && a.Value2[i] == value2 {
+			continue
+		}
+		a.ASN[j], a.Value1[j], a.Value2[j] = a.ASN[i], a.Value1[i], a.Value2[i]
+		j++
+	}
+	if j != len(a.ASN) {
+		a.ASN, a.Value1, a.Value2 = a.ASN[:j], a.Value1[:j], a.Value2[:j]
+		return true
+	}
+	return false
+}
+
+// Sort sorts communities in a in ascending order, dropping duplicates
+func (a *LargeCom) Sort() {
+	all := make([][3]uint32, len(a.ASN))
+	for i := range a.ASN {
+		all[i] = [3]uint32{a.ASN[i], a.Value1[i], a.Value2[i]}
+	}
+	slices.SortFunc(all, func(x, y [3]uint32) int {
+		for i := range x {
+			if c := cmp.Compare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	all = slices.Compact(all)
+
+	a.ASN, a.Value1, a.Value2 = a.ASN[:0], a.Value1[:0], a.Value2[:0]
+	for _, v := range all {
+		a.Add(v[0], v[1], v[2])
+	}
+}
+
 func (a *LargeCom) Marshal(dst []byte, cps caps.Caps, dir dir.Dir) []byte {
 	tl := 12 * len(a.ASN)
 	dst = a.CodeFlags.MarshalLen(dst, tl)
@@ -0,0 +1,57 @@
+package attrs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestLargeCom(t *testing.T) {
+	var cps caps.Caps
+	buf := []byte{
+		0x00, 0x00, 0xfd, 0xe8, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, // 65000:1:2
+		0x00, 0x00, 0xfd, 0xe8, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x04, // 65000:3:4
+		0xfa, 0x56, 0xea, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // 4200000001:0:1
+	}
+	js := `["65000:1:2","65000:3:4","4200000001:0:1"]`
+
+	a := NewAttr(ATTR_LARGE_COMMUNITY).(*LargeCom)
+	if err := a.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if got := string(a.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON = %s, want %s", got, js)
+	}
+
+	// add
+	if a.Has(65000, 5, 6) {
+		t.Errorf("Has(65000:5:6) = true before Add")
+	}
+	a.Add(65000, 5, 6)
+	if !a.Has(65000, 5, 6) {
+		t.Errorf("Has(65000:5:6) = false after Add")
+	}
+
+	// duplicates are dropped by Sort
+	a.Add(65000, 3, 4)
+	a.Sort()
+	if got, want := string(a.ToJSON(nil)), `["65000:1:2","65000:3:4","65000:5:6","4200000001:0:1"]`; got != want {
+		t.Errorf("Sort = %s, want %s", got, want)
+	}
+
+	// drop
+	if !a.Drop(65000, 5, 6) {
+		t.Errorf("Drop(65000:5:6) = false")
+	}
+	if a.Has(65000, 5, 6) || a.Drop(65000, 5, 6) {
+		t.Errorf("65000:5:6 still present after Drop")
+	}
+	if got := a.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("Marshal = %x, want %x", got[3:], buf)
+	}
+	if got, want := a.Find(4200000001, 0, 1), 2; got != want {
+		t.Errorf("Find(4200000001:0:1) = %d, want %d", got, want)
+	}
+}
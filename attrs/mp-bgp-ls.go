@@ -0,0 +1,226 @@
+package attrs
+
+import (
+	"net/netip"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/json"
+	"github.com/bgpfix/bgpfix/nlri"
+)
+
+// NewMPBgpLS returns, for a parent mp attribute, a new MPValue implementing BGP-LS
+func NewMPBgpLS(mp *MP) MPValue {
+	return &MPBgpLS{MP: mp}
+}
+
+// MPBgpLS represents ATTR_MP attributes for BGP Link-State NLRI (RFC 9552),
+// at TLV granularity: the descriptors are kept as opaque TLVs.
+type MPBgpLS struct {
+	*MP
+
+	NextHop netip.Addr  // best-effort
+	NLRI    []BgpLSNLRI // see rfc9552/5.2
+}
+
+// BgpLSNLRI represents a single BGP-LS NLRI.
+// NLRI types other than BGPLS_NODE, BGPLS_LINK, and BGPLS_PREFIX* are kept in Raw.
+type BgpLSNLRI struct {
+	Type uint16 // NLRI type
+
+	RD       nlri.RD    // Route Distinguisher, only for SAFI_LS_VPN
+	Protocol byte       // Protocol-ID, eg. 2 for IS-IS Level 2
+	ID       uint64     // Identifier of the routing universe
+	TLV      []BgpLSTLV // descriptors, eg. Local Node Descriptors (256)
+
+	Raw []byte // NLRI value, for unsupported NLRI types
+}
+
+// BGP-LS NLRI types, rfc9552/5.2
+const (
+	BGPLS_NODE    uint16 = 1 // Node NLRI
+	BGPLS_LINK    uint16 = 2 // Link NLRI
+	BGPLS_PREFIX4 uint16 = 3 // IPv4 Topology Prefix NLRI
+	BGPLS_PREFIX6 uint16 = 4 // IPv6 Topology Prefix NLRI
+)
+
+// vpn returns true iff the NLRI in a carry a Route Distinguisher
+func (a *MPBgpLS) vpn() bool {
+	return a.Safi() == afi.SAFI_LS_VPN
+}
+
+func (a *MPBgpLS) Unmarshal(cps caps.Caps, _ dir.Dir) error {
+	// best-effort NH parser
+	if len(a.NH) > 0 {
+		a.NextHop, _, _ = ParseNH(a.NH)
+	}
+
+	a.NLRI = a.NLRI[:0]
+	data := a.Data
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return ErrLength
+		}
+		n := BgpLSNLRI{Type: msb.Uint16(data[0:2])}
+		l := int(msb.Uint16(data[2:4]))
+		data = data[4:]
+		if len(data) < l {
+			return ErrLength
+		}
+
+		if err := n.Unmarshal(data[:l], a.vpn()); err != nil {
+			return err
+		}
+		a.NLRI = append(a.NLRI, n)
+		data = data[l:]
+	}
+
+	return nil
+}
+
+// Unmarshal parses the value of BGP-LS NLRI n in buf, according to n.Type
+func (n *BgpLSNLRI) Unmarshal(buf []byte, vpn bool) (err error) {
+	switch n.Type {
+	case BGPLS_NODE, BGPLS_LINK, BGPLS_PREFIX4, BGPLS_PREFIX6:
+		if vpn {
+			if n.RD, err = nlri.ReadRD(buf); err != nil {
+				return err
+			}
+			buf = buf[8:]
+		}
+		if len(buf) < 1+8 {
+			return ErrLength
+		}
+		n.Protocol = buf[0]
+		n.ID = msb.Uint64(buf[1:9])
+		n.TLV, err = readBgpLSTLV(n.TLV[:0], buf[9:])
+		return err
+
+	default:
+		n.Raw = append(n.Raw[:0], buf...)
+		return nil
+	}
+}
+
+func (a *MPBgpLS) Marshal(cps caps.Caps, _ dir.Dir) {
+	// best-effort, in new memory (NH and Data might reference the message buffer)
+	nh := a.NH[:0:0]
+	if a.NextHop.IsValid() {
+		nh = append(nh, a.NextHop.AsSlice()...)
+	}
+	a.NH = nh
+
+	var data []byte
+	for i := range a.NLRI {
+		n := &a.NLRI[i]
+		data = msb.AppendUint16(data, n.Type)
+		data = append(data, 0, 0) // length (tbd [1])
+		off := len(data)
+		data = n.Marshal(data, a.vpn())
+		msb.PutUint16(data[off-2:], uint16(len(data)-off)) // [1]
+	}
+	a.Data = data
+}
+
+// Marshal appends the wire representation of the value of BGP-LS NLRI n to dst
+func (n *BgpLSNLRI) Marshal(dst []byte, vpn bool) []byte {
+	switch n.Type {
+	case BGPLS_NODE, BGPLS_LINK, BGPLS_PREFIX4, BGPLS_PREFIX6:
+		if vpn {
+			dst = n.RD.Marshal(dst)
+		}
+		dst = append(dst, n.Protocol)
+		dst = msb.AppendUint64(dst, n.ID)
+		return appendBgpLSTLV(dst, n.TLV)
+
+	default:
+		return append(dst, n.Raw...)
+	}
+}
+
+func (a *MPBgpLS) ToJSON(dst []byte) []byte {
+	if a.Code() == ATTR_MP_REACH && a.NextHop.IsValid() {
+		dst = append(dst, `"nexthop":"`...)
+		dst = a.NextHop.AppendTo(dst)
+		dst = append(dst, `",`...)
+	}
+
+	dst = append(dst, `"nlri":[`...)
+	for i := range a.NLRI {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = a.NLRI[i].ToJSON(dst, a.vpn())
+	}
+	return append(dst, ']')
+}
+
+// ToJSON appends JSON representation of BGP-LS NLRI n to dst
+func (n *BgpLSNLRI) ToJSON(dst []byte, vpn bool) []byte {
+	dst = append(dst, `{"type":`...)
+	dst = json.Uint16(dst, n.Type)
+
+	switch n.Type {
+	case BGPLS_NODE, BGPLS_LINK, BGPLS_PREFIX4, BGPLS_PREFIX6:
+		if vpn {
+			dst = append(dst, `,"rd":"`...)
+			dst = n.RD.AppendTo(dst)
+			dst = append(dst, '"')
+		}
+		dst = append(dst, `,"proto":`...)
+		dst = json.Byte(dst, n.Protocol)
+		dst = append(dst, `,"id":`...)
+		dst = json.Uint64(dst, n.ID)
+		dst = append(dst, `,"tlv":`...)
+		dst = bgpLSTLVToJSON(dst, n.TLV)
+	default:
+		dst = append(dst, `,"raw":`...)
+		dst = json.Hex(dst, n.Raw)
+	}
+
+	return append(dst, '}')
+}
+
+func (a *MPBgpLS) FromJSON(src []byte) error {
+	a.NLRI = a.NLRI[:0]
+	return json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "nexthop":
+			if a.Code() == ATTR_MP_REACH {
+				a.NextHop, err = netip.ParseAddr(json.S(val))
+			}
+		case "nlri":
+			return json.ArrayEach(val, func(key int, val []byte, typ json.Type) error {
+				var n BgpLSNLRI
+				if err := n.FromJSON(val); err != nil {
+					return err
+				}
+				a.NLRI = append(a.NLRI, n)
+				return nil
+			})
+		}
+		return err
+	})
+}
+
+// FromJSON reads BGP-LS NLRI n from its JSON representation in src
+func (n *BgpLSNLRI) FromJSON(src []byte) error {
+	return json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "type":
+			n.Type, err = json.UnUint16(val)
+		case "rd":
+			n.RD, err = nlri.ParseRD(json.S(val))
+		case "proto":
+			n.Protocol, err = json.UnByte(val)
+		case "id":
+			n.ID, err = json.UnUint64(val)
+		case "tlv":
+			n.TLV, err = bgpLSTLVFromJSON(n.TLV[:0], val)
+		case "raw":
+			n.Raw, err = json.UnHex(val, nil)
+		}
+		return err
+	})
+}
@@ -0,0 +1,98 @@
+package attrs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestMPBgpLS(t *testing.T) {
+	tests := []struct {
+		name string
+		code Code
+		buf  []byte
+		js   string
+	}{
+		{
+			"reach",
+			ATTR_MP_REACH,
+			[]byte{
+				0x40, 0x04, 0x47, // AFI/SAFI
+				0x04, 0xc0, 0x00, 0x02, 0x01, // next-hop
+				0x00, // reserved
+
+				// Node NLRI
+				0x00, 0x01, 0x00, 0x15,
+				0x02,                                           // IS-IS Level 2
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Identifier
+				0x01, 0x00, 0x00, 0x08, 0x02, 0x00, 0x00, 0x04, 0x00, 0x00, 0xfd, 0xe8, // Local Node Descriptors: AS 65000
+
+				// unsupported NLRI type
+				0x00, 0x06, 0x00, 0x02, 0xaa, 0xbb,
+			},
+			`{"af":"LS/LS","nexthop":"192.0.2.1","nlri":[` +
+				`{"type":1,"proto":2,"id":0,"tlv":[{"type":256,"value":"0x020000040000fde8"}]},` +
+				`{"type":6,"raw":"0xaabb"}]}`,
+		},
+		{
+			"vpn unreach",
+			ATTR_MP_UNREACH,
+			[]byte{
+				0x40, 0x04, 0x48, // AFI/SAFI
+
+				// IPv6 Prefix NLRI
+				0x00, 0x04, 0x00, 0x1a,
+				0x00, 0x00, 0xfd, 0xe8, 0x00, 0x00, 0x00, 0x01, // RD 65000:1
+				0x03,                                           // OSPFv2
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x07, // Identifier
+				0x01, 0x09, 0x00, 0x05, 0x20, 0x20, 0x01, 0x0d, 0xb8, // IP Reachability: 2001:db8::/32
+			},
+			`{"af":"LS/LS_VPN","nlri":[` +
+				`{"type":4,"rd":"65000:1","proto":3,"id":7,"tlv":[{"type":265,"value":"0x2020010db8"}]}]}`,
+		},
+	}
+
+	var cps caps.Caps
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp := NewAttr(tt.code).(*MP)
+			if err := mp.Unmarshal(tt.buf, cps, dir.DIR_L); err != nil {
+				t.Fatalf("Unmarshal error = %v", err)
+			}
+			if mp.BgpLS() == nil {
+				t.Fatalf("Value = %T, want *MPBgpLS", mp.Value)
+			}
+			if got := string(mp.ToJSON(nil)); got != tt.js {
+				t.Errorf("ToJSON = '%s', want '%s'", got, tt.js)
+			}
+			if err := mp.BgpLS().Unmarshal(cps, dir.DIR_L); err != nil {
+				t.Fatalf("Unmarshal again error = %v", err)
+			}
+			if got := string(mp.ToJSON(nil)); got != tt.js {
+				t.Errorf("ToJSON after reuse = '%s', want '%s'", got, tt.js)
+			}
+			if got := mp.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("Marshal = '%x', want '%x'", got[3:], tt.buf)
+			}
+
+			mp2 := NewAttr(tt.code).(*MP)
+			if err := mp2.FromJSON([]byte(tt.js)); err != nil {
+				t.Fatalf("FromJSON error = %v", err)
+			}
+			if err := mp2.BgpLS().FromJSON([]byte(tt.js)); err != nil {
+				t.Fatalf("FromJSON again error = %v", err)
+			}
+			if got := mp2.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("FromJSON Marshal = '%x', want '%x'", got[3:], tt.buf)
+			}
+		})
+	}
+
+	// truncated descriptor TLV
+	mp := NewAttr(ATTR_MP_UNREACH).(*MP)
+	if err := mp.Unmarshal([]byte{0x40, 0x04, 0x47, 0x00, 0x01, 0x00, 0x0b, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, 0x00}, cps, dir.DIR_L); err != ErrLength {
+		t.Errorf("Unmarshal truncated error = %v, want %v", err, ErrLength)
+	}
+}
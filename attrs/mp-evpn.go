@@ -0,0 +1,321 @@
+package attrs
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/json"
+	"github.com/bgpfix/bgpfix/nlri"
+)
+
+// NewMPEvpn returns, for a parent mp attribute, a new MPValue implementing BGP EVPN
+func NewMPEvpn(mp *MP) MPValue {
+	return &MPEvpn{MP: mp}
+}
+
+// MPEvpn represents ATTR_MP attributes for RFC7432 BGP MPLS-Based Ethernet VPN
+type MPEvpn struct {
+	*MP
+
+	NextHop netip.Addr  // best-effort
+	Routes  []EvpnRoute // see RFC7432/7
+}
+
+// EvpnRoute represents a single EVPN NLRI.
+// Route types other than EVPN_MAC_IP and EVPN_MULTICAST are kept in Raw.
+type EvpnRoute struct {
+	Type byte // route type
+
+	RD     nlri.RD          // Route Distinguisher
+	ESI    [10]byte         // Ethernet Segment Identifier, only for EVPN_MAC_IP
+	Tag    uint32           // Ethernet Tag ID
+	MAC    net.HardwareAddr // MAC address, only for EVPN_MAC_IP
+	IP     netip.Addr       // IP address (optional for EVPN_MAC_IP), or Originating Router's IP
+	Labels []uint32         // MPLS label(s), only for EVPN_MAC_IP
+
+	Raw []byte // route value, for unsupported route types
+}
+
+// EVPN route types, rfc7432/7
+const (
+	EVPN_AD        byte = 1 // Ethernet Auto-Discovery
+	EVPN_MAC_IP    byte = 2 // MAC/IP Advertisement
+	EVPN_MULTICAST byte = 3 // Inclusive Multicast Ethernet Tag
+	EVPN_ES        byte = 4 // Ethernet Segment
+)
+
+func (a *MPEvpn) Unmarshal(cps caps.Caps, _ dir.Dir) error {
+	// best-effort NH parser
+	if len(a.NH) > 0 {
+		a.NextHop, _, _ = ParseNH(a.NH)
+	}
+
+	data := a.Data
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return ErrLength
+		}
+		r := EvpnRoute{Type: data[0]}
+		l := int(data[1])
+		data = data[2:]
+		if len(data) < l {
+			return ErrLength
+		}
+
+		if err := r.Unmarshal(data[:l]); err != nil {
+			return err
+		}
+		a.Routes = append(a.Routes, r)
+		data = data[l:]
+	}
+
+	return nil
+}
+
+// Unmarshal parses the value of EVPN route r in buf, according to r.Type
+func (r *EvpnRoute) Unmarshal(buf []byte) (err error) {
+	switch r.Type {
+	case EVPN_MAC_IP: // rfc7432/7.2
+		if len(buf) < 8+10+4+1+6+1 {
+			return ErrLength
+		}
+		r.RD, _ = nlri.ReadRD(buf)
+		copy(r.ESI[:], buf[8:18])
+		r.Tag = msb.Uint32(buf[18:22])
+		if buf[22] != 48 {
+			return ErrValue
+		}
+		r.MAC = net.HardwareAddr(append([]byte(nil), buf[23:29]...))
+		buf = buf[29:]
+
+		if r.IP, buf, err = readEvpnIP(buf, true); err != nil {
+			return err
+		}
+
+		// Label1 [+ Label2]
+		if len(buf) != 3 && len(buf) != 6 {
+			return ErrLength
+		}
+		for ; len(buf) > 0; buf = buf[3:] {
+			label, _, _ := nlri.ReadLabel(buf)
+			r.Labels = append(r.Labels, label)
+		}
+
+	case EVPN_MULTICAST: // rfc7432/7.3
+		if len(buf) < 8+4+1 {
+			return ErrLength
+		}
+		r.RD, _ = nlri.ReadRD(buf)
+		r.Tag = msb.Uint32(buf[8:12])
+		if r.IP, buf, err = readEvpnIP(buf[12:], false); err != nil {
+			return err
+		} else if len(buf) != 0 {
+			return ErrLength
+		}
+
+	default:
+		r.Raw = append(r.Raw[:0], buf...)
+	}
+	return nil
+}
+
+// readEvpnIP reads an IP address preceded by its length in bits from buf,
+// returning the rest of buf. If optional, the address can be empty.
+func readEvpnIP(buf []byte, optional bool) (ip netip.Addr, rest []byte, err error) {
+	if len(buf) < 1 {
+		return ip, buf, ErrLength
+	}
+	switch l := int(buf[0]); {
+	case l == 0 && optional:
+		return ip, buf[1:], nil
+	case l == 32 && len(buf) >= 1+4:
+		return netip.AddrFrom4([4]byte(buf[1:5])), buf[5:], nil
+	case l == 128 && len(buf) >= 1+16:
+		return netip.AddrFrom16([16]byte(buf[1:17])), buf[17:], nil
+	default:
+		return ip, buf, ErrLength
+	}
+}
+
+// appendEvpnIP appends IP address ip preceded by its length in bits to dst
+func appendEvpnIP(dst []byte, ip netip.Addr) []byte {
+	dst = append(dst, byte(ip.BitLen()))
+	return append(dst, ip.AsSlice()...)
+}
+
+func (a *MPEvpn) Marshal(cps caps.Caps, _ dir.Dir) {
+	// best-effort
+	nh := a.NH[:0]
+	if a.NextHop.IsValid() {
+		nh = append(nh, a.NextHop.AsSlice()...)
+	}
+	a.NH = nh
+
+	data := a.Data[:0]
+	for i := range a.Routes {
+		r := &a.Routes[i]
+		data = append(data, r.Type, 0) // length (tbd [1])
+		off := len(data)
+		data = r.Marshal(data)
+		data[off-1] = byte(len(data) - off) // [1]
+	}
+	a.Data = data
+}
+
+// Marshal appends the wire representation of the value of EVPN route r to dst
+func (r *EvpnRoute) Marshal(dst []byte) []byte {
+	switch r.Type {
+	case EVPN_MAC_IP:
+		dst = r.RD.Marshal(dst)
+		dst = append(dst, r.ESI[:]...)
+		dst = msb.AppendUint32(dst, r.Tag)
+		dst = append(dst, 48)
+		if len(r.MAC) == 6 {
+			dst = append(dst, r.MAC...)
+		} else {
+			dst = append(dst, 0, 0, 0, 0, 0, 0)
+		}
+		dst = appendEvpnIP(dst, r.IP)
+		switch len(r.Labels) {
+		case 0:
+			dst = nlri.AppendLabel(dst, 0, true)
+		case 1:
+			dst = nlri.AppendLabel(dst, r.Labels[0], true)
+		default:
+			dst = nlri.AppendLabel(dst, r.Labels[0], false)
+			dst = nlri.AppendLabel(dst, r.Labels[1], true)
+		}
+		return dst
+
+	case EVPN_MULTICAST:
+		dst = r.RD.Marshal(dst)
+		dst = msb.AppendUint32(dst, r.Tag)
+		return appendEvpnIP(dst, r.IP)
+
+	default:
+		return append(dst, r.Raw...)
+	}
+}
+
+func (a *MPEvpn) ToJSON(dst []byte) []byte {
+	if a.Code() == ATTR_MP_REACH && a.NextHop.IsValid() {
+		dst = append(dst, `"nexthop":"`...)
+		dst = a.NextHop.AppendTo(dst)
+		dst = append(dst, `",`...)
+	}
+
+	dst = append(dst, `"routes":[`...)
+	for i := range a.Routes {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = a.Routes[i].ToJSON(dst)
+	}
+	return append(dst, ']')
+}
+
+// ToJSON appends JSON representation of EVPN route r to dst
+func (r *EvpnRoute) ToJSON(dst []byte) []byte {
+	dst = append(dst, `{"type":`...)
+	dst = json.Byte(dst, r.Type)
+
+	switch r.Type {
+	case EVPN_MAC_IP, EVPN_MULTICAST:
+		dst = append(dst, `,"rd":"`...)
+		dst = r.RD.AppendTo(dst)
+		dst = append(dst, '"')
+		if r.Type == EVPN_MAC_IP {
+			dst = append(dst, `,"esi":`...)
+			dst = json.Hex(dst, r.ESI[:])
+		}
+		dst = append(dst, `,"tag":`...)
+		dst = json.Uint32(dst, r.Tag)
+		if r.Type == EVPN_MAC_IP {
+			dst = append(dst, `,"mac":"`...)
+			dst = append(dst, r.MAC.String()...)
+			dst = append(dst, '"')
+		}
+		if r.IP.IsValid() {
+			dst = append(dst, `,"ip":`...)
+			dst = json.Addr(dst, r.IP)
+		}
+		if r.Type == EVPN_MAC_IP {
+			dst = append(dst, `,"labels":[`...)
+			for i, l := range r.Labels {
+				if i > 0 {
+					dst = append(dst, ',')
+				}
+				dst = json.Uint32(dst, l)
+			}
+			dst = append(dst, ']')
+		}
+	default:
+		dst = append(dst, `,"raw":`...)
+		dst = json.Hex(dst, r.Raw)
+	}
+
+	return append(dst, '}')
+}
+
+func (a *MPEvpn) FromJSON(src []byte) error {
+	return json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "nexthop":
+			if a.Code() == ATTR_MP_REACH {
+				a.NextHop, err = netip.ParseAddr(json.S(val))
+			}
+		case "routes":
+			return json.ArrayEach(val, func(key int, val []byte, typ json.Type) error {
+				var r EvpnRoute
+				if err := r.FromJSON(val); err != nil {
+					return err
+				}
+				a.Routes = append(a.Routes, r)
+				return nil
+			})
+		}
+		return err
+	})
+}
+
+// FromJSON reads EVPN route r from its JSON representation in src
+func (r *EvpnRoute) FromJSON(src []byte) error {
+	return json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "type":
+			r.Type, err = json.UnByte(val)
+		case "rd":
+			r.RD, err = nlri.ParseRD(json.S(val))
+		case "esi":
+			var esi []byte
+			esi, err = json.UnHex(val, nil)
+			if err == nil && len(esi) != len(r.ESI) {
+				err = ErrLength
+			}
+			copy(r.ESI[:], esi)
+		case "tag":
+			r.Tag, err = json.UnUint32(val)
+		case "mac":
+			r.MAC, err = net.ParseMAC(json.S(val))
+			if err == nil && len(r.MAC) != 6 {
+				err = ErrValue
+			}
+		case "ip":
+			r.IP, err = json.UnAddr(val)
+		case "labels":
+			err = json.ArrayEach(val, func(key int, val []byte, typ json.Type) error {
+				l, err := json.UnUint32(val)
+				if err == nil && l > nlri.LABEL_MAX {
+					err = ErrValue
+				}
+				r.Labels = append(r.Labels, l)
+				return err
+			})
+		case "raw":
+			r.Raw, err = json.UnHex(val, nil)
+		}
+		return err
+	})
+}
@@ -0,0 +1,68 @@
+package attrs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestMPEvpn(t *testing.T) {
+	buf := []byte{
+		0x00, 0x19, 0x46, // AFI/SAFI
+		0x04, 0xc0, 0x00, 0x02, 0x01, // next-hop
+		0x00, // reserved
+
+		// MAC/IP advertisement
+		0x02, 0x25,
+		0x00, 0x00, 0xfd, 0xe8, 0x00, 0x00, 0x00, 0x01, // RD
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, // ESI
+		0x00, 0x00, 0x00, 0x00, // Ethernet Tag
+		0x30, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, // MAC
+		0x20, 0x0a, 0x00, 0x00, 0x01, // IP
+		0x00, 0x06, 0x41, // label
+
+		// Inclusive Multicast Ethernet Tag
+		0x03, 0x11,
+		0x00, 0x00, 0xfd, 0xe8, 0x00, 0x00, 0x00, 0x01, // RD
+		0x00, 0x00, 0x00, 0x64, // Ethernet Tag
+		0x20, 0xc0, 0x00, 0x02, 0x01, // IP
+
+		// IP Prefix route (rfc9136), not supported
+		0x05, 0x03, 0xaa, 0xbb, 0xcc,
+	}
+	js := `{"af":"L2VPN/EVPNS","nexthop":"192.0.2.1","routes":[` +
+		`{"type":2,"rd":"65000:1","esi":"0x00010203040506070809","tag":0,"mac":"00:11:22:33:44:55","ip":"10.0.0.1","labels":[100]},` +
+		`{"type":3,"rd":"65000:1","tag":100,"ip":"192.0.2.1"},` +
+		`{"type":5,"raw":"0xaabbcc"}]}`
+
+	var cps caps.Caps
+	mp := NewAttr(ATTR_MP_REACH).(*MP)
+	if err := mp.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if mp.Evpn() == nil {
+		t.Fatalf("Value = %T, want *MPEvpn", mp.Value)
+	}
+	if got := string(mp.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON = '%s', want '%s'", got, js)
+	}
+	if got := mp.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("Marshal = '%x', want '%x'", got[3:], buf)
+	}
+
+	mp2 := NewAttr(ATTR_MP_REACH).(*MP)
+	if err := mp2.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	if got := mp2.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("FromJSON Marshal = '%x', want '%x'", got[3:], buf)
+	}
+
+	// truncated MAC/IP advertisement
+	mp3 := NewAttr(ATTR_MP_UNREACH).(*MP)
+	if err := mp3.Unmarshal([]byte{0x00, 0x19, 0x46, 0x02, 0x02, 0x00, 0x00}, cps, dir.DIR_L); err == nil {
+		t.Errorf("Unmarshal truncated: no error")
+	}
+}
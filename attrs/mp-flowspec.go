@@ -525,6 +525,38 @@ func (f *FlowPrefix6) FromJSON(src []byte) (err error) {
 	return ErrValue
 }
 
+// FlowTCPFlags maps TCP flag names to their bitmask values, rfc8955/4.2.2.9
+var FlowTCPFlags = map[string]uint64{
+	"fin": 0x01,
+	"syn": 0x02,
+	"rst": 0x04,
+	"psh": 0x08,
+	"ack": 0x10,
+	"urg": 0x20,
+	"ece": 0x40,
+	"cwr": 0x80,
+}
+
+// FlowFragFlags maps fragment flag names to their bitmask values, rfc8955/4.2.2.12
+var FlowFragFlags = map[string]uint64{
+	"dont-fragment":  0x01,
+	"is-fragment":    0x02,
+	"first-fragment": 0x04,
+	"last-fragment":  0x08,
+}
+
+// flowFlags returns the flag names for given flowtype, or nil
+func flowFlags(ft FlowType) map[string]uint64 {
+	switch ft {
+	case FLOW_TCP_FLAGS:
+		return FlowTCPFlags
+	case FLOW_FRAG:
+		return FlowFragFlags
+	default:
+		return nil
+	}
+}
+
 // FlowGeneric represents a generic flowtype with a list of (operator, value) pairs
 type FlowGeneric struct {
 	Type FlowType
@@ -675,9 +707,13 @@ func (f *FlowGeneric) ToJSON(dst []byte) []byte {
 			dst = strconv.AppendUint(dst, uint64(op.Len()), 10)
 
 			dst = append(dst, `,"val":`...)
-			dst = append(dst, `"0x`...)
-			dst = strconv.AppendUint(dst, val, 16)
-			dst = append(dst, `"`...)
+			if names := flowFlags(f.Type); names != nil {
+				dst = f.flagsToJSON(dst, val, names)
+			} else {
+				dst = append(dst, `"0x`...)
+				dst = strconv.AppendUint(dst, val, 16)
+				dst = append(dst, `"`...)
+			}
 		}
 
 		dst = append(dst, `}`...)
@@ -686,6 +722,58 @@ func (f *FlowGeneric) ToJSON(dst []byte) []byte {
 	return dst
 }
 
+// flagsToJSON appends val as a JSON array of flag names to dst,
+// with unknown bits (if any) as the last element in hex
+func (f *FlowGeneric) flagsToJSON(dst []byte, val uint64, names map[string]uint64) []byte {
+	// sort by bit value
+	bits := make([]string, 0, len(names))
+	for name, bit := range names {
+		if val&bit != 0 {
+			bits = append(bits, name)
+			val &= ^bit
+		}
+	}
+	sort.Slice(bits, func(i, j int) bool {
+		return names[bits[i]] < names[bits[j]]
+	})
+
+	dst = append(dst, '[')
+	for i, name := range bits {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, '"')
+		dst = append(dst, name...)
+		dst = append(dst, '"')
+	}
+	if val != 0 {
+		if len(bits) > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `"0x`...)
+		dst = strconv.AppendUint(dst, val, 16)
+		dst = append(dst, '"')
+	}
+	return append(dst, ']')
+}
+
+// flagsFromJSON parses a JSON array of flag names (or numbers) in src
+func (f *FlowGeneric) flagsFromJSON(src []byte) (val uint64, err error) {
+	names := flowFlags(f.Type)
+	err = json.ArrayEach(src, func(key int, src []byte, typ json.Type) error {
+		s := json.S(src)
+		if bit, ok := names[strings.ToLower(s)]; ok {
+			val |= bit
+		} else if v, err := strconv.ParseUint(s, 0, 64); err == nil {
+			val |= v
+		} else {
+			return ErrValue
+		}
+		return nil
+	})
+	return
+}
+
 func (f *FlowGeneric) FromJSON(src []byte) (err error) {
 	f.Op = f.Op[:0]
 	f.Val = f.Val[:0]
@@ -735,6 +823,15 @@ func (f *FlowGeneric) FromJSON(src []byte) (err error) {
 				}
 
 			case "val":
+				if typ == json.ARRAY {
+					flags, err := f.flagsFromJSON(val)
+					if err != nil {
+						return err
+					}
+					fval = flags
+					break
+				}
+
 				val, err := strconv.ParseUint(json.SQ(val), 0, 64)
 				if err != nil {
 					return err
@@ -44,3 +44,45 @@ func TestFlowPrefix6(t *testing.T) {
 		})
 	}
 }
+
+func TestFlowGeneric_Flags(t *testing.T) {
+	tests := []struct {
+		ft   FlowType
+		buf  []byte
+		json string
+	}{
+		{FLOW_TCP_FLAGS, []byte{0x81, 0x12}, `[{"op":"ALL","len":1,"val":["syn","ack"]}]`},
+		{FLOW_TCP_FLAGS, []byte{0x12, 0x01, 0x04, 0x80, 0x01}, `[{"op":"NONE","len":2,"val":["rst","0x100"]},{"op":"ANY","len":1,"val":["fin"]}]`},
+		{FLOW_FRAG, []byte{0x80, 0x03}, `[{"op":"ANY","len":1,"val":["dont-fragment","is-fragment"]}]`},
+		{FLOW_FRAG, []byte{0x80, 0x00}, `[{"op":"ANY","len":1,"val":[]}]`},
+	}
+	var cps caps.Caps
+	for ti, tt := range tests {
+		t.Run(fmt.Sprintf("tests[%d]", ti), func(t *testing.T) {
+			f := NewFlowGeneric(tt.ft)
+			if _, err := f.Unmarshal(tt.buf, cps); err != nil {
+				t.Fatalf("Unmarshal error = %v", err)
+			}
+			if json := string(f.ToJSON(nil)); json != tt.json {
+				t.Errorf("ToJSON = '%s', want '%s'", json, tt.json)
+			}
+
+			f2 := NewFlowGeneric(tt.ft)
+			if err := f2.FromJSON([]byte(tt.json)); err != nil {
+				t.Fatalf("FromJSON error = %v", err)
+			}
+			if buf := f2.Marshal(nil, cps); !bytes.Equal(buf, tt.buf) {
+				t.Errorf("Marshal = '%x', want '%x'", buf, tt.buf)
+			}
+		})
+	}
+
+	// hex is still accepted
+	f := NewFlowGeneric(FLOW_TCP_FLAGS)
+	if err := f.FromJSON([]byte(`[{"op":"ALL","len":1,"val":"0x12"}]`)); err != nil {
+		t.Fatalf("FromJSON hex error = %v", err)
+	}
+	if buf := f.Marshal(nil, cps); !bytes.Equal(buf, []byte{0x81, 0x12}) {
+		t.Errorf("Marshal hex = '%x'", buf)
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/bgpfix/bgpfix/nlri"
 )
 
-// MPPrefixes represents ATTR_MP for the generic RFC4760 IP prefix encoding
+// MPPrefixes represents ATTR_MP for the generic RFC4760 IP prefix encoding.
+// For MPLS VPNs (RFC4364), the prefixes carry their RD and labels,
+// while the next-hop is stored without the (zero) RD.
 type MPPrefixes struct {
 	*MP
 
@@ -30,8 +32,11 @@ func (a *MPPrefixes) Unmarshal(cps caps.Caps, dir dir.Dir) error {
 	)
 
 	// NH defined?
-	if len(a.NH) > 0 {
-		addr, ll, ok := ParseNH(a.NH)
+	if nh := a.NH; len(nh) > 0 {
+		if nlri.HasRD(a.AS) {
+			nh = stripRD(nh)
+		}
+		addr, ll, ok := ParseNH(nh)
 		if !ok {
 			return ErrLength
 		}
@@ -60,17 +65,36 @@ func (a *MPPrefixes) Unmarshal(cps caps.Caps, dir dir.Dir) error {
 
 func (a *MPPrefixes) Marshal(cps caps.Caps, dir dir.Dir) {
 	// next-hop
-	nh := a.NH[:0]
+	var rd []byte
+	if nlri.HasRD(a.AS) {
+		rd = make([]byte, 8) // zero RD, rfc4364/4.3.2
+	}
+	// NB: use new memory, NH and Data might still reference the message buffer
+	nh := a.NH[:0:0]
 	if a.NextHop.IsValid() {
+		nh = append(nh, rd...)
 		nh = append(nh, a.NextHop.AsSlice()...)
 		if a.LinkLocal.IsValid() {
+			nh = append(nh, rd...)
 			nh = append(nh, a.LinkLocal.AsSlice()...)
 		}
 	}
 	a.NH = nh
 
 	// prefixes
-	a.Data = nlri.Marshal(a.Data[:0], a.Prefixes, a.AS, cps, dir)
+	a.Data = nlri.Marshal(a.Data[:0:0], a.Prefixes, a.AS, cps, dir)
+}
+
+// stripRD returns VPN next-hop in buf without the Route Distinguisher(s), rfc4364/4.3.2
+func stripRD(buf []byte) []byte {
+	switch len(buf) {
+	case 8 + 4, 8 + 16:
+		return buf[8:]
+	case 8 + 16 + 8 + 16:
+		return append(buf[8:24:24], buf[32:48]...)
+	default:
+		return buf
+	}
 }
 
 func (a *MPPrefixes) ToJSON(dst []byte) []byte {
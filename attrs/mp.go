@@ -1,6 +1,9 @@
 package attrs
 
 import (
+	"bytes"
+	"slices"
+
 	"github.com/bgpfix/bgpfix/afi"
 	"github.com/bgpfix/bgpfix/caps"
 	"github.com/bgpfix/bgpfix/dir"
@@ -41,12 +44,37 @@ type MPValue interface {
 // MPNewFunc returns new ATTR_MP_* value for afi/safi in mp
 type MPNewFunc func(mp *MP) MPValue
 
-// MPNewFuncs maps ATTR_MP_* afi/safi pairs to their NewFunc
-var MPNewFuncs = map[afi.AS]MPNewFunc{
-	afi.AS_IPV4_UNICAST:  NewMPPrefixes,
-	afi.AS_IPV4_FLOWSPEC: NewMPFlowspec,
-	afi.AS_IPV6_UNICAST:  NewMPPrefixes,
-	afi.AS_IPV6_FLOWSPEC: NewMPFlowspec,
+// MPNewFuncs maps ATTR_MP_* afi/safi pairs to their NewFunc.
+// Use RegisterMPValue to modify.
+var MPNewFuncs = map[afi.AS]MPNewFunc{}
+
+func init() {
+	RegisterMPValue(afi.AS_IPV4_UNICAST, NewMPPrefixes)
+	RegisterMPValue(afi.AS_IPV4_MULTICAST, NewMPPrefixes)
+	RegisterMPValue(afi.AS_IPV4_MPLS, NewMPPrefixes)
+	RegisterMPValue(afi.AS_IPV4_FLOWSPEC, NewMPFlowspec)
+	RegisterMPValue(afi.AS_IPV4_VPN, NewMPPrefixes)
+	RegisterMPValue(afi.AS_IPV6_UNICAST, NewMPPrefixes)
+	RegisterMPValue(afi.AS_IPV6_MULTICAST, NewMPPrefixes)
+	RegisterMPValue(afi.AS_IPV6_MPLS, NewMPPrefixes)
+	RegisterMPValue(afi.AS_IPV6_FLOWSPEC, NewMPFlowspec)
+	RegisterMPValue(afi.AS_IPV6_VPN, NewMPPrefixes)
+	RegisterMPValue(afi.AS_L2VPN_EVPN, NewMPEvpn)
+	RegisterMPValue(afi.AS_LS, NewMPBgpLS)
+	RegisterMPValue(afi.AS_LS_VPN, NewMPBgpLS)
+}
+
+// RegisterMPValue registers newfunc as the ATTR_MP_* value decoder for afi/safi pair as,
+// overriding the existing one (if any). If newfunc is nil, as falls back to MPRaw.
+//
+// RegisterMPValue is not thread-safe: call it before parsing or creating any messages,
+// eg. in an init() function, and never concurrently with message processing.
+func RegisterMPValue(as afi.AS, newfunc MPNewFunc) {
+	if newfunc == nil {
+		delete(MPNewFuncs, as)
+	} else {
+		MPNewFuncs[as] = newfunc
+	}
 }
 
 func NewMP(at CodeFlags) Attr {
@@ -54,19 +82,22 @@ func NewMP(at CodeFlags) Attr {
 }
 
 // NewMPValue returns a new MPValue for parent mp,
-// or nil if its AFI/SAFI pair is not supported.
+// or MPRaw if its AFI/SAFI pair is not supported.
 func NewMPValue(mp *MP) MPValue {
 	if newfunc, ok := MPNewFuncs[mp.AS]; ok {
 		return newfunc(mp)
 	} else {
-		return nil
+		return NewMPRaw(mp)
 	}
 }
 
-func (mp *MP) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) error {
+// parseHeader parses the ATTR_MP_* header in buf into mp.AS and mp.NH, returning the NLRI.
+// For ATTR_MP_REACH, the header includes the next-hop and the reserved octet, which is
+// present for all AFI/SAFI pairs, including eg. flowspec with no next-hop (rfc4760/3, rfc8955/4).
+func (mp *MP) parseHeader(buf []byte) ([]byte, error) {
 	// afi + safi
 	if len(buf) < 3 {
-		return ErrLength
+		return nil, ErrLength
 	}
 	mp.AS = afi.NewASBytes(buf[0:3])
 	buf = buf[3:]
@@ -74,19 +105,47 @@ func (mp *MP) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) error {
 	// nexthop?
 	if mp.Code() == ATTR_MP_REACH {
 		if len(buf) < 2 {
-			return ErrLength
+			return nil, ErrLength
 		}
 
 		nhl := int(buf[0])
 		buf = buf[1:]
 		if len(buf) < nhl+1 {
-			return ErrLength
+			return nil, ErrLength
 		}
 		mp.NH = buf[:nhl]
 
 		buf = buf[nhl+1:] // skip the reserved byte
 	}
 
+	return buf, nil
+}
+
+// headerLen returns the length of the ATTR_MP_* header written by appendHeader
+func (mp *MP) headerLen() int {
+	if mp.Code() == ATTR_MP_REACH {
+		return 2 + 1 + 1 + len(mp.NH) + 1 // afi + safi + next-hop len + data + reserved
+	}
+	return 2 + 1 // afi + safi
+}
+
+// appendHeader appends the ATTR_MP_* header to dst, see parseHeader
+func (mp *MP) appendHeader(dst []byte) []byte {
+	dst = mp.AS.Marshal3(dst)
+	if mp.Code() == ATTR_MP_REACH {
+		dst = append(dst, byte(len(mp.NH)))
+		dst = append(dst, mp.NH...)
+		dst = append(dst, 0) // reserved byte
+	}
+	return dst
+}
+
+func (mp *MP) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) error {
+	buf, err := mp.parseHeader(buf)
+	if err != nil {
+		return err
+	}
+
 	// nlri
 	mp.Data = buf
 
@@ -104,20 +163,34 @@ func (mp *MP) Marshal(dst []byte, cps caps.Caps, dir dir.Dir) []byte {
 		mp.Value.Marshal(cps, dir)
 	}
 
-	tl := 2 + 1 + len(mp.Data) // afi + safi + nlri
-	if mp.Code() == ATTR_MP_REACH {
-		tl += 1 + len(mp.NH) + 1 // next-hop len + data + reserved
-	}
-	dst = mp.CodeFlags.MarshalLen(dst, tl)
+	dst = mp.CodeFlags.MarshalLen(dst, mp.headerLen()+len(mp.Data))
+	dst = mp.appendHeader(dst)
+	return append(dst, mp.Data...)
+}
 
-	dst = mp.AS.Marshal3(dst)
-	if mp.Code() == ATTR_MP_REACH {
-		dst = append(dst, byte(len(mp.NH)))
-		dst = append(dst, mp.NH...)
-		dst = append(dst, 0) // reserved byte
+// clone returns a deep copy of mp, or nil if its Value can't be copied directly
+func (mp *MP) clone() *MP {
+	mp2 := &MP{
+		CodeFlags: mp.CodeFlags,
+		AS:        mp.AS,
+		NH:        bytes.Clone(mp.NH),
+		Data:      bytes.Clone(mp.Data),
 	}
 
-	return append(dst, mp.Data...)
+	switch v := mp.Value.(type) {
+	case nil:
+	case *MPPrefixes:
+		v2 := *v
+		v2.MP = mp2
+		v2.Prefixes = slices.Clone(v.Prefixes)
+		for i := range v2.Prefixes {
+			v2.Prefixes[i].Labels = slices.Clone(v2.Prefixes[i].Labels)
+		}
+		mp2.Value = &v2
+	default:
+		return nil
+	}
+	return mp2
 }
 
 func (mp *MP) ToJSON(dst []byte) []byte {
@@ -128,13 +201,7 @@ func (mp *MP) ToJSON(dst []byte) []byte {
 	if mp.Value != nil {
 		dst = mp.Value.ToJSON(dst)
 	} else {
-		if mp.Code() == ATTR_MP_REACH && len(mp.NH) > 0 {
-			dst = append(dst, `"nh":`...)
-			dst = json.Hex(dst, mp.NH)
-			dst = append(dst, ',')
-		}
-		dst = append(dst, `"data":`...)
-		dst = json.Hex(dst, mp.Data)
+		dst = (&MPRaw{mp}).ToJSON(dst)
 	}
 	return append(dst, '}')
 }
@@ -173,3 +240,65 @@ func (mp *MP) Prefixes() *MPPrefixes {
 	pfx, _ := mp.Value.(*MPPrefixes)
 	return pfx
 }
+
+// Evpn returns mp.Value interpreted as MPEvpn, or nil if not the case
+func (mp *MP) Evpn() *MPEvpn {
+	if mp == nil || mp.Value == nil {
+		return nil
+	}
+
+	evpn, _ := mp.Value.(*MPEvpn)
+	return evpn
+}
+
+// BgpLS returns mp.Value interpreted as MPBgpLS, or nil if not the case
+func (mp *MP) BgpLS() *MPBgpLS {
+	if mp == nil || mp.Value == nil {
+		return nil
+	}
+
+	ls, _ := mp.Value.(*MPBgpLS)
+	return ls
+}
+
+// Raw returns mp.Value interpreted as MPRaw, or nil if not the case
+func (mp *MP) Raw() *MPRaw {
+	if mp == nil || mp.Value == nil {
+		return nil
+	}
+
+	raw, _ := mp.Value.(*MPRaw)
+	return raw
+}
+
+// MPRaw represents ATTR_MP for unsupported AFI/SAFI pairs,
+// keeping the next-hop and NLRI as opaque bytes in the parent.
+type MPRaw struct {
+	*MP
+}
+
+func NewMPRaw(mp *MP) MPValue {
+	return &MPRaw{MP: mp}
+}
+
+func (a *MPRaw) Unmarshal(cps caps.Caps, dir dir.Dir) error {
+	return nil // already in the parent
+}
+
+func (a *MPRaw) Marshal(cps caps.Caps, dir dir.Dir) {
+	// already in the parent
+}
+
+func (a *MPRaw) ToJSON(dst []byte) []byte {
+	if a.Code() == ATTR_MP_REACH && len(a.NH) > 0 {
+		dst = append(dst, `"nh":`...)
+		dst = json.Hex(dst, a.NH)
+		dst = append(dst, ',')
+	}
+	dst = append(dst, `"data":`...)
+	return json.Hex(dst, a.Data)
+}
+
+func (a *MPRaw) FromJSON(src []byte) error {
+	return nil // already parsed by the parent
+}
@@ -0,0 +1,368 @@
+package attrs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestMPRaw(t *testing.T) {
+	// VPLS (AFI 25, SAFI 65) is not supported, must pass through as-is
+	buf := []byte{
+		0x00, 0x19, 0x41, // AFI/SAFI
+		0x04, 0xc0, 0x00, 0x02, 0x01, // next-hop
+		0x00,                               // reserved
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, // NLRI
+	}
+	js := `{"af":"L2VPN/VPLS","nh":"0xc0000201","data":"0x001122334455"}`
+
+	var cps caps.Caps
+	mp := NewAttr(ATTR_MP_REACH).(*MP)
+	if err := mp.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if mp.Raw() == nil {
+		t.Fatalf("Value = %T, want *MPRaw", mp.Value)
+	}
+	if got := string(mp.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON = '%s', want '%s'", got, js)
+	}
+	if got := mp.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("Marshal = '%x', want '%x'", got[3:], buf)
+	}
+
+	mp2 := NewAttr(ATTR_MP_REACH).(*MP)
+	if err := mp2.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	if mp2.Raw() == nil {
+		t.Fatalf("FromJSON Value = %T, want *MPRaw", mp2.Value)
+	}
+	if got := mp2.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("FromJSON Marshal = '%x', want '%x'", got[3:], buf)
+	}
+}
+
+// testMPValue is a dummy MPValue decoder, counting Unmarshal calls
+type testMPValue struct {
+	MPRaw
+	calls int
+}
+
+func (a *testMPValue) Unmarshal(cps caps.Caps, dir dir.Dir) error {
+	a.calls++
+	return nil
+}
+
+func TestRegisterMPValue(t *testing.T) {
+	// register a custom decoder for VPLS (AFI 25, SAFI 65)
+	vpls := afi.NewAS(afi.AFI_L2VPN, afi.SAFI_VPLS)
+	RegisterMPValue(vpls, func(mp *MP) MPValue {
+		return &testMPValue{MPRaw: MPRaw{mp}}
+	})
+	defer RegisterMPValue(vpls, nil)
+
+	buf := []byte{
+		0x00, 0x19, 0x41, // AFI/SAFI
+		0x04, 0xc0, 0x00, 0x02, 0x01, // next-hop
+		0x00,                               // reserved
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, // NLRI
+	}
+
+	var cps caps.Caps
+	mp := NewAttr(ATTR_MP_REACH).(*MP)
+	if err := mp.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	val, ok := mp.Value.(*testMPValue)
+	if !ok {
+		t.Fatalf("Value = %T, want *testMPValue", mp.Value)
+	}
+	if val.calls != 1 {
+		t.Errorf("Unmarshal calls = %d, want 1", val.calls)
+	}
+	if mp.Raw() != nil {
+		t.Errorf("Raw() = non-nil for a registered decoder")
+	}
+	if got := mp.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("Marshal = '%x', want '%x'", got[3:], buf)
+	}
+
+	// built-in decoders still in place
+	if _, ok := MPNewFuncs[afi.AS_IPV4_FLOWSPEC]; !ok {
+		t.Errorf("IPV4/FLOWSPEC decoder missing")
+	}
+	if _, ok := MPNewFuncs[afi.AS_IPV6_UNICAST]; !ok {
+		t.Errorf("IPV6/UNICAST decoder missing")
+	}
+
+	// unregistered: back to MPRaw
+	RegisterMPValue(vpls, nil)
+	mp2 := NewAttr(ATTR_MP_REACH).(*MP)
+	if err := mp2.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if mp2.Raw() == nil {
+		t.Errorf("Value = %T, want *MPRaw", mp2.Value)
+	}
+}
+
+func TestMPLabeled(t *testing.T) {
+	tests := []struct {
+		name string
+		code Code
+		buf  []byte
+		js   string
+	}{
+		{
+			"ipv4 labeled unicast reach",
+			ATTR_MP_REACH,
+			[]byte{
+				0x00, 0x01, 0x04, // AFI/SAFI
+				0x04, 0xc0, 0x00, 0x02, 0x01, // next-hop: 192.0.2.1
+				0x00,             // reserved
+				0x30,             // 48 bits
+				0x00, 0x06, 0x41, // label 100, BoS
+				0x0a, 0x01, 0x01, // 10.1.1.0/24
+			},
+			`{"af":"IPV4/MPLS","nexthop":"192.0.2.1","prefixes":["label 100 10.1.1.0/24"]}`,
+		},
+		{
+			"ipv6 labeled unicast reach, implicit-null",
+			ATTR_MP_REACH,
+			[]byte{
+				0x00, 0x02, 0x04, // AFI/SAFI
+				0x10, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, // next-hop: 2001:db8::1
+				0x00,             // reserved
+				0x38,             // 56 bits
+				0x00, 0x00, 0x31, // label 3, BoS
+				0x20, 0x01, 0x0d, 0xb8, // 2001:db8::/32
+			},
+			`{"af":"IPV6/MPLS","nexthop":"2001:db8::1","prefixes":["label 3 2001:db8::/32"]}`,
+		},
+		{
+			"ipv4 labeled unicast unreach",
+			ATTR_MP_UNREACH,
+			[]byte{
+				0x00, 0x01, 0x04, // AFI/SAFI
+				0x30,             // 48 bits
+				0x80, 0x00, 0x00, // withdraw label
+				0x0a, 0x01, 0x01, // 10.1.1.0/24
+			},
+			`{"af":"IPV4/MPLS","prefixes":["label 524288 10.1.1.0/24"]}`,
+		},
+		{
+			"vpnv4 reach",
+			ATTR_MP_REACH,
+			[]byte{
+				0x00, 0x01, 0x80, // AFI/SAFI
+				0x0c, 0, 0, 0, 0, 0, 0, 0, 0, 0xc0, 0x00, 0x02, 0x01, // next-hop: zero RD + 192.0.2.1
+				0x00,             // reserved
+				0x70,             // 112 bits
+				0x00, 0x06, 0x41, // label 100, BoS
+				0x00, 0x00, 0xfd, 0xe8, 0x00, 0x00, 0x00, 0x64, // RD 65000:100
+				0x0a, 0x01, 0x01, // 10.1.1.0/24
+			},
+			`{"af":"IPV4/MPLS_VPN","nexthop":"192.0.2.1","prefixes":["label 100 rd 65000:100 10.1.1.0/24"]}`,
+		},
+		{
+			"vpnv6 unreach",
+			ATTR_MP_UNREACH,
+			[]byte{
+				0x00, 0x02, 0x80, // AFI/SAFI
+				0x78,             // 120 bits
+				0x80, 0x00, 0x00, // withdraw label
+				0x00, 0x01, 0xc0, 0x00, 0x02, 0x01, 0x00, 0x05, // RD 192.0.2.1:5
+				0x20, 0x01, 0x0d, 0xb8, // 2001:db8::/32
+			},
+			`{"af":"IPV6/MPLS_VPN","prefixes":["label 524288 rd 192.0.2.1:5 2001:db8::/32"]}`,
+		},
+		{
+			"vpnv6 reach, small RD",
+			ATTR_MP_REACH,
+			[]byte{
+				0x00, 0x02, 0x80, // AFI/SAFI
+				0x18, 0, 0, 0, 0, 0, 0, 0, 0, // next-hop: zero RD +
+				0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, // 2001:db8::1
+				0x00,             // reserved
+				0x78,             // 120 bits
+				0x00, 0x06, 0x41, // label 100, BoS
+				0x00, 0x00, 0x00, 0x64, 0x00, 0x00, 0x00, 0x01, // RD 100:1
+				0x20, 0x01, 0x0d, 0xb8, // 2001:db8::/32
+			},
+			`{"af":"IPV6/MPLS_VPN","nexthop":"2001:db8::1","prefixes":["label 100 rd 100:1 2001:db8::/32"]}`,
+		},
+	}
+
+	var cps caps.Caps
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp := NewAttr(tt.code).(*MP)
+			if err := mp.Unmarshal(tt.buf, cps, dir.DIR_L); err != nil {
+				t.Fatalf("Unmarshal error = %v", err)
+			}
+			if mp.Prefixes() == nil {
+				t.Fatalf("Value = %T, want *MPPrefixes", mp.Value)
+			}
+			if got := string(mp.ToJSON(nil)); got != tt.js {
+				t.Errorf("ToJSON = '%s', want '%s'", got, tt.js)
+			}
+			if got := mp.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("Marshal = '%x', want '%x'", got[3:], tt.buf)
+			}
+
+			mp2 := NewAttr(tt.code).(*MP)
+			if err := mp2.FromJSON([]byte(tt.js)); err != nil {
+				t.Fatalf("FromJSON error = %v", err)
+			}
+			if got := mp2.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("FromJSON Marshal = '%x', want '%x'", got[3:], tt.buf)
+			}
+		})
+	}
+}
+
+func TestMPMulticast(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  []byte
+		js   string
+		as   afi.AS
+	}{
+		{
+			"ipv4 multicast unreach",
+			[]byte{
+				0x00, 0x01, 0x02, // AFI/SAFI
+				0x18, 0xe8, 0x01, 0x02, // 232.1.2.0/24
+			},
+			`{"af":"IPV4/MULTICAST","prefixes":["232.1.2.0/24"]}`,
+			afi.AS_IPV4_MULTICAST,
+		},
+		{
+			"ipv6 multicast unreach",
+			[]byte{
+				0x00, 0x02, 0x02, // AFI/SAFI
+				0x20, 0x20, 0x01, 0x0d, 0xb8, // 2001:db8::/32
+			},
+			`{"af":"IPV6/MULTICAST","prefixes":["2001:db8::/32"]}`,
+			afi.AS_IPV6_MULTICAST,
+		},
+	}
+
+	var cps caps.Caps
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp := NewAttr(ATTR_MP_UNREACH).(*MP)
+			if err := mp.Unmarshal(tt.buf, cps, dir.DIR_L); err != nil {
+				t.Fatalf("Unmarshal error = %v", err)
+			}
+			pfx := mp.Prefixes()
+			if pfx == nil {
+				t.Fatalf("Value = %T, want *MPPrefixes", mp.Value)
+			}
+			if pfx.AS != tt.as {
+				t.Errorf("AS = %v, want %v", pfx.AS, tt.as)
+			}
+			if got := string(mp.ToJSON(nil)); got != tt.js {
+				t.Errorf("ToJSON = '%s', want '%s'", got, tt.js)
+			}
+			if got := mp.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("Marshal = '%x', want '%x'", got[3:], tt.buf)
+			}
+
+			mp2 := NewAttr(ATTR_MP_UNREACH).(*MP)
+			if err := mp2.FromJSON([]byte(tt.js)); err != nil {
+				t.Fatalf("FromJSON error = %v", err)
+			}
+			if got := mp2.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("FromJSON Marshal = '%x', want '%x'", got[3:], tt.buf)
+			}
+		})
+	}
+}
+
+func TestMPExtNH(t *testing.T) {
+	// IPv4 unicast over IPv6 next-hop, rfc8950
+	buf := []byte{
+		0x00, 0x01, 0x01, // AFI/SAFI
+		0x10, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, // next-hop: 2001:db8::1
+		0x00,                   // reserved
+		0x18, 0xc6, 0x33, 0x64, // 198.51.100.0/24
+	}
+	js := `{"af":"IPV4/UNICAST","nexthop":"2001:db8::1","prefixes":["198.51.100.0/24"]}`
+
+	// not negotiated: must fail
+	var cps caps.Caps
+	mp := NewAttr(ATTR_MP_REACH).(*MP)
+	if err := mp.Unmarshal(buf, cps, dir.DIR_L); err == nil {
+		t.Fatalf("Unmarshal without CAP_EXTENDED_NEXTHOP: want error")
+	}
+
+	// negotiated
+	cps.Use(caps.CAP_EXTENDED_NEXTHOP).(*caps.ExtNH).Add(afi.AS_IPV4_UNICAST, afi.AFI_IPV6)
+	mp = NewAttr(ATTR_MP_REACH).(*MP)
+	if err := mp.Unmarshal(buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if got := string(mp.ToJSON(nil)); got != js {
+		t.Errorf("ToJSON = '%s', want '%s'", got, js)
+	}
+	if got := mp.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("Marshal = '%x', want '%x'", got[3:], buf)
+	}
+
+	mp2 := NewAttr(ATTR_MP_REACH).(*MP)
+	if err := mp2.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	if got := mp2.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], buf) {
+		t.Errorf("FromJSON Marshal = '%x', want '%x'", got[3:], buf)
+	}
+}
+
+func TestMPHeader(t *testing.T) {
+	// the reserved octet follows the next-hop in ATTR_MP_REACH for all AFI/SAFI pairs
+	tests := []struct {
+		name string
+		code Code
+		buf  []byte
+	}{
+		{"ipv4 unicast reach", ATTR_MP_REACH, []byte{0x00, 0x01, 0x01, 0x04, 192, 0, 2, 1, 0x00}},
+		{"ipv4 flowspec reach", ATTR_MP_REACH, []byte{0x00, 0x01, 0x85, 0x00, 0x00}},
+		{"ipv6 flowspec reach", ATTR_MP_REACH, []byte{0x00, 0x02, 0x85, 0x00, 0x00}},
+		{"vpnv4 reach", ATTR_MP_REACH, []byte{0x00, 0x01, 0x80, 0x0c, 0, 0, 0, 0, 0, 0, 0, 0, 192, 0, 2, 1, 0x00}},
+		{"evpn reach", ATTR_MP_REACH, []byte{0x00, 0x19, 0x46, 0x04, 192, 0, 2, 1, 0x00}},
+		{"vpls reach (raw)", ATTR_MP_REACH, []byte{0x00, 0x19, 0x41, 0x04, 192, 0, 2, 1, 0x00}},
+		{"ipv4 flowspec unreach", ATTR_MP_UNREACH, []byte{0x00, 0x01, 0x85}},
+	}
+
+	var cps caps.Caps
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp := NewAttr(tt.code).(*MP)
+			if err := mp.Unmarshal(tt.buf, cps, dir.DIR_L); err != nil {
+				t.Fatalf("Unmarshal error = %v", err)
+			}
+			if len(mp.Data) != 0 {
+				t.Errorf("Data = %x, want empty", mp.Data)
+			}
+			if got := mp.headerLen(); got != len(tt.buf) {
+				t.Errorf("headerLen = %d, want %d", got, len(tt.buf))
+			}
+			if got := mp.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("Marshal = '%x', want '%x'", got[3:], tt.buf)
+			}
+
+			// without the reserved octet
+			if tt.code == ATTR_MP_REACH {
+				mp2 := NewAttr(tt.code).(*MP)
+				if err := mp2.Unmarshal(tt.buf[:len(tt.buf)-1], cps, dir.DIR_L); err != ErrLength {
+					t.Errorf("Unmarshal without reserved octet: error = %v, want %v", err, ErrLength)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package attrs
+
+import (
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/json"
+)
+
+// Otc represents ATTR_OTC, the Only to Customer attribute rfc9234
+type Otc struct {
+	CodeFlags
+	ASN uint32
+}
+
+func NewOtc(at CodeFlags) Attr {
+	return &Otc{CodeFlags: at}
+}
+
+func (a *Otc) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) error {
+	if len(buf) != 4 {
+		return ErrLength
+	}
+
+	a.ASN = msb.Uint32(buf)
+	return nil
+}
+
+func (a *Otc) Marshal(dst []byte, cps caps.Caps, dir dir.Dir) []byte {
+	dst = a.CodeFlags.MarshalLen(dst, 4)
+	return msb.AppendUint32(dst, a.ASN)
+}
+
+func (a *Otc) ToJSON(dst []byte) []byte {
+	return json.Uint32(dst, a.ASN)
+}
+
+func (a *Otc) FromJSON(src []byte) (err error) {
+	a.ASN, err = json.UnUint32(src)
+	return
+}
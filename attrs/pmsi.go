@@ -0,0 +1,111 @@
+package attrs
+
+import (
+	"net/netip"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/json"
+	"github.com/bgpfix/bgpfix/nlri"
+)
+
+// PmsiTunnel represents ATTR_PMSI_TUNNEL, the P-Multicast Service Interface Tunnel attribute (RFC 6514)
+type PmsiTunnel struct {
+	CodeFlags
+	TunnelFlags uint8  // tunnel flags, eg. PMSI_LEAF_REQUIRED (not the attribute Flags)
+	TunnelType  uint8  // tunnel type, eg. PMSI_INGRESS_REPLICATION
+	MplsLabel   uint32 // MPLS label, as a 20-bit number (the high-order bits of the 3-byte field)
+	TunnelID    []byte // Tunnel Identifier, depending on TunnelType
+}
+
+// PMSI tunnel flags, rfc6514/5
+const (
+	PMSI_LEAF_REQUIRED uint8 = 0x01
+)
+
+// PMSI tunnel types, rfc6514/5 and rfc7524
+const (
+	PMSI_NONE                uint8 = 0
+	PMSI_RSVP_TE_P2MP        uint8 = 1
+	PMSI_MLDP_P2MP           uint8 = 2
+	PMSI_PIM_SSM             uint8 = 3
+	PMSI_PIM_SM              uint8 = 4
+	PMSI_BIDIR_PIM           uint8 = 5
+	PMSI_INGRESS_REPLICATION uint8 = 6
+	PMSI_MLDP_MP2MP          uint8 = 7
+)
+
+func NewPmsiTunnel(at CodeFlags) Attr {
+	return &PmsiTunnel{CodeFlags: at}
+}
+
+func (a *PmsiTunnel) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) error {
+	if len(buf) < 5 {
+		return ErrLength
+	}
+	a.TunnelFlags = buf[0]
+	a.TunnelType = buf[1]
+	a.MplsLabel, _, _ = nlri.ReadLabel(buf[2:5])
+	a.TunnelID = append(a.TunnelID[:0], buf[5:]...)
+	return nil
+}
+
+// Endpoint returns the tunnel endpoint address for PMSI_INGRESS_REPLICATION, rfc6514/5.
+// Returns an invalid address for other tunnel types, or if TunnelID is not an IP address.
+func (a *PmsiTunnel) Endpoint() netip.Addr {
+	if a.TunnelType == PMSI_INGRESS_REPLICATION {
+		if addr, ok := netip.AddrFromSlice(a.TunnelID); ok {
+			return addr
+		}
+	}
+	return netip.Addr{}
+}
+
+func (a *PmsiTunnel) Marshal(dst []byte, cps caps.Caps, dir dir.Dir) []byte {
+	dst = a.CodeFlags.MarshalLen(dst, 5+len(a.TunnelID))
+	dst = append(dst, a.TunnelFlags, a.TunnelType)
+	dst = nlri.AppendLabel(dst, a.MplsLabel, false)
+	return append(dst, a.TunnelID...)
+}
+
+func (a *PmsiTunnel) ToJSON(dst []byte) []byte {
+	dst = append(dst, `{"flags":`...)
+	dst = json.Byte(dst, a.TunnelFlags)
+	dst = append(dst, `,"type":`...)
+	dst = json.Byte(dst, a.TunnelType)
+	dst = append(dst, `,"label":`...)
+	dst = json.Uint32(dst, a.MplsLabel)
+	if addr := a.Endpoint(); addr.IsValid() {
+		dst = append(dst, `,"endpoint":"`...)
+		dst = addr.AppendTo(dst)
+		dst = append(dst, '"')
+	} else if len(a.TunnelID) > 0 {
+		dst = append(dst, `,"id":`...)
+		dst = json.Hex(dst, a.TunnelID)
+	}
+	return append(dst, '}')
+}
+
+func (a *PmsiTunnel) FromJSON(src []byte) error {
+	a.TunnelFlags, a.TunnelType, a.MplsLabel, a.TunnelID = 0, 0, 0, nil
+	return json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "flags":
+			a.TunnelFlags, err = json.UnByte(val)
+		case "type":
+			a.TunnelType, err = json.UnByte(val)
+		case "label":
+			a.MplsLabel, err = json.UnUint32(val)
+			if err == nil && a.MplsLabel > nlri.LABEL_MAX {
+				err = ErrValue
+			}
+		case "endpoint":
+			var addr netip.Addr
+			addr, err = netip.ParseAddr(json.S(val))
+			a.TunnelID = addr.AsSlice()
+		case "id":
+			a.TunnelID, err = json.UnHex(val, nil)
+		}
+		return
+	})
+}
@@ -0,0 +1,80 @@
+package attrs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestPmsiTunnel(t *testing.T) {
+	tests := []struct {
+		name     string
+		buf      []byte
+		label    uint32
+		endpoint string
+		json     string
+	}{
+		{
+			name: "ingress replication",
+			buf: []byte{
+				0x00, 0x06, // no flags, Ingress Replication
+				0x06, 0x4b, 0x00, // label 25776
+				192, 0, 2, 1, // endpoint
+			},
+			label:    25776,
+			endpoint: "192.0.2.1",
+			json:     `{"flags":0,"type":6,"label":25776,"endpoint":"192.0.2.1"}`,
+		},
+		{
+			name: "mldp leaf required",
+			buf: []byte{
+				0x01, 0x02, // leaf required, mLDP P2MP
+				0x00, 0x00, 0x00, // no label
+				0x06, 0x00, 0x01, 0x04, 0xc0, 0x00, 0x02, 0x01, 0x00, 0x00, // opaque id
+			},
+			json: `{"flags":1,"type":2,"label":0,"id":"0x06000104c00002010000"}`,
+		},
+	}
+
+	var cps caps.Caps
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAttr(ATTR_PMSI_TUNNEL).(*PmsiTunnel)
+			if err := a.Unmarshal(tt.buf, cps, dir.DIR_L); err != nil {
+				t.Fatalf("Unmarshal error = %v", err)
+			}
+			if a.MplsLabel != tt.label {
+				t.Errorf("MplsLabel = %d, want %d", a.MplsLabel, tt.label)
+			}
+			if got := a.Endpoint(); (got.IsValid() || tt.endpoint != "") && got.String() != tt.endpoint {
+				t.Errorf("Endpoint = %s, want %s", got, tt.endpoint)
+			}
+			if got := string(a.ToJSON(nil)); got != tt.json {
+				t.Errorf("ToJSON = %s, want %s", got, tt.json)
+			}
+			if got := a.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("Marshal = %x, want %x", got[3:], tt.buf)
+			}
+
+			b := NewAttr(ATTR_PMSI_TUNNEL).(*PmsiTunnel)
+			if err := b.FromJSON([]byte(tt.json)); err != nil {
+				t.Fatalf("FromJSON error = %v", err)
+			}
+			if got := b.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("FromJSON Marshal = %x, want %x", got[3:], tt.buf)
+			}
+		})
+	}
+
+	// malformed
+	a := NewAttr(ATTR_PMSI_TUNNEL).(*PmsiTunnel)
+	if err := a.Unmarshal([]byte{0x00, 0x06, 0x00, 0x00}, cps, dir.DIR_L); !errors.Is(err, ErrLength) {
+		t.Errorf("Unmarshal short error = %v, want %v", err, ErrLength)
+	}
+	if err := a.FromJSON([]byte(`{"type":6,"label":1048576}`)); !errors.Is(err, ErrValue) {
+		t.Errorf("FromJSON big label error = %v, want %v", err, ErrValue)
+	}
+}
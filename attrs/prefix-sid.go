@@ -0,0 +1,478 @@
+package attrs
+
+import (
+	"net/netip"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/json"
+)
+
+// PrefixSid represents ATTR_PREFIX_SID, the BGP Prefix-SID attribute (RFC 8669)
+type PrefixSid struct {
+	CodeFlags
+
+	TLV []PrefixSidTLV // TLVs, in wire order
+}
+
+// PrefixSidTLV represents a single Prefix-SID TLV, sub-TLV, or sub-sub-TLV.
+// Known types are decoded into one of the typed views, which take precedence
+// over Value when marshaling. Other types keep their opaque Value.
+type PrefixSidTLV struct {
+	Type  byte
+	Value []byte // opaque value, iff no typed view is set
+
+	Label     *PrefixSidLabel // Label-Index TLV, rfc8669/3.1
+	Srv6      *Srv6Service    // SRv6 L3/L2 Service TLV, rfc9252/2
+	SID       *Srv6SID        // SRv6 SID Information sub-TLV, rfc9252/3.1
+	Structure *Srv6Structure  // SRv6 SID Structure sub-sub-TLV, rfc9252/3.2.1
+}
+
+// PrefixSidLabel represents the Label-Index TLV value, rfc8669/3.1
+type PrefixSidLabel struct {
+	Reserved byte   // reserved field, kept as-is
+	Flags    uint16 // flags
+	Index    uint32 // the label index
+}
+
+// Srv6Service represents the SRv6 Service TLV value, rfc9252/2
+type Srv6Service struct {
+	Reserved byte           // reserved field, kept as-is
+	Sub      []PrefixSidTLV // sub-TLVs, in wire order
+}
+
+// Srv6SID represents the SRv6 SID Information sub-TLV value, rfc9252/3.1
+type Srv6SID struct {
+	Reserved1 byte           // first reserved field, kept as-is
+	SID       netip.Addr     // SRv6 SID value
+	Flags     byte           // SRv6 Service SID flags
+	Behavior  uint16         // SRv6 Endpoint Behavior
+	Reserved2 byte           // second reserved field, kept as-is
+	Sub       []PrefixSidTLV // sub-sub-TLVs, in wire order
+}
+
+// Srv6Structure represents the SRv6 SID Structure sub-sub-TLV value, rfc9252/3.2.1
+type Srv6Structure struct {
+	LocatorBlock byte // Locator Block Length
+	LocatorNode  byte // Locator Node Length
+	Function     byte // Function Length
+	Argument     byte // Argument Length
+	TransLen     byte // Transposition Length
+	TransOff     byte // Transposition Offset
+}
+
+// Prefix-SID TLV types
+const (
+	PREFIX_SID_LABEL_INDEX byte = 1 // rfc8669/3.1
+	PREFIX_SID_SRGB        byte = 3 // rfc8669/3.2
+	PREFIX_SID_SRV6_L3     byte = 5 // rfc9252/2
+	PREFIX_SID_SRV6_L2     byte = 6 // rfc9252/2
+
+	SRV6_SID_INFO      byte = 1 // SRv6 SID Information sub-TLV, rfc9252/3.1
+	SRV6_SID_STRUCTURE byte = 1 // SRv6 SID Structure sub-sub-TLV, rfc9252/3.2.1
+)
+
+// Prefix-SID TLV nesting levels
+const (
+	sidLevelAttr    = iota // Prefix-SID TLVs
+	sidLevelService        // SRv6 Service sub-TLVs
+	sidLevelInfo           // SRv6 SID Information sub-sub-TLVs
+)
+
+func NewPrefixSid(at CodeFlags) Attr {
+	return &PrefixSid{CodeFlags: at}
+}
+
+// Label returns the first Label-Index TLV in a, or nil if not present
+func (a *PrefixSid) Label() *PrefixSidLabel {
+	for i := range a.TLV {
+		if a.TLV[i].Label != nil {
+			return a.TLV[i].Label
+		}
+	}
+	return nil
+}
+
+// Srv6L3 returns the first SRv6 L3 Service TLV in a, or nil if not present
+func (a *PrefixSid) Srv6L3() *Srv6Service {
+	for i := range a.TLV {
+		if a.TLV[i].Type == PREFIX_SID_SRV6_L3 && a.TLV[i].Srv6 != nil {
+			return a.TLV[i].Srv6
+		}
+	}
+	return nil
+}
+
+// SIDs returns all SRv6 SID Information sub-TLVs in s, in wire order
+func (s *Srv6Service) SIDs() (sids []*Srv6SID) {
+	for i := range s.Sub {
+		if s.Sub[i].SID != nil {
+			sids = append(sids, s.Sub[i].SID)
+		}
+	}
+	return sids
+}
+
+// Structure returns the first SRv6 SID Structure sub-sub-TLV in s, or nil
+func (s *Srv6SID) Structure() *Srv6Structure {
+	for i := range s.Sub {
+		if s.Sub[i].Structure != nil {
+			return s.Sub[i].Structure
+		}
+	}
+	return nil
+}
+
+// readTLVs calls cb for each TLV in buf, with 1-byte type and 2-byte length
+func readTLVs(buf []byte, cb func(typ byte, val []byte) error) error {
+	for len(buf) > 0 {
+		if len(buf) < 3 {
+			return ErrLength
+		}
+		typ, l := buf[0], int(msb.Uint16(buf[1:3]))
+		if len(buf) < 3+l {
+			return ErrLength
+		}
+		if err := cb(typ, buf[3:3+l]); err != nil {
+			return err
+		}
+		buf = buf[3+l:]
+	}
+	return nil
+}
+
+// unmarshalTLVs parses TLVs at given nesting level in buf, appending to dst
+func unmarshalTLVs(buf []byte, level int, dst []PrefixSidTLV) ([]PrefixSidTLV, error) {
+	err := readTLVs(buf, func(typ byte, val []byte) error {
+		tlv := PrefixSidTLV{Type: typ}
+		switch {
+		case level == sidLevelAttr && typ == PREFIX_SID_LABEL_INDEX:
+			if len(val) != 7 {
+				return ErrLength
+			}
+			tlv.Label = &PrefixSidLabel{
+				Reserved: val[0],
+				Flags:    msb.Uint16(val[1:3]),
+				Index:    msb.Uint32(val[3:7]),
+			}
+		case level == sidLevelAttr && (typ == PREFIX_SID_SRV6_L3 || typ == PREFIX_SID_SRV6_L2):
+			tlv.Srv6 = new(Srv6Service)
+			if err := tlv.Srv6.Unmarshal(val); err != nil {
+				return err
+			}
+		case level == sidLevelService && typ == SRV6_SID_INFO:
+			tlv.SID = new(Srv6SID)
+			if err := tlv.SID.Unmarshal(val); err != nil {
+				return err
+			}
+		case level == sidLevelInfo && typ == SRV6_SID_STRUCTURE && len(val) == 6:
+			tlv.Structure = &Srv6Structure{val[0], val[1], val[2], val[3], val[4], val[5]}
+		default:
+			tlv.Value = append([]byte(nil), val...)
+		}
+		dst = append(dst, tlv)
+		return nil
+	})
+	return dst, err
+}
+
+func (a *PrefixSid) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) (err error) {
+	a.TLV, err = unmarshalTLVs(buf, sidLevelAttr, a.TLV[:0])
+	return err
+}
+
+// Unmarshal parses the SRv6 Service TLV value in buf
+func (s *Srv6Service) Unmarshal(buf []byte) (err error) {
+	if len(buf) < 1 {
+		return ErrLength
+	}
+	s.Reserved = buf[0]
+	s.Sub, err = unmarshalTLVs(buf[1:], sidLevelService, s.Sub[:0])
+	return err
+}
+
+// Unmarshal parses the SRv6 SID Information sub-TLV value in buf
+func (s *Srv6SID) Unmarshal(buf []byte) (err error) {
+	if len(buf) < 21 {
+		return ErrLength
+	}
+	s.Reserved1 = buf[0]
+	s.SID = netip.AddrFrom16([16]byte(buf[1:17]))
+	s.Flags = buf[17]
+	s.Behavior = msb.Uint16(buf[18:20])
+	s.Reserved2 = buf[20]
+	s.Sub, err = unmarshalTLVs(buf[21:], sidLevelInfo, s.Sub[:0])
+	return err
+}
+
+// appendTLVs appends TLVs in src to dst, in order
+func appendTLVs(dst []byte, src []PrefixSidTLV) []byte {
+	for i := range src {
+		dst = src[i].Marshal(dst)
+	}
+	return dst
+}
+
+// Marshal appends wire representation of t to dst
+func (t *PrefixSidTLV) Marshal(dst []byte) []byte {
+	dst = append(dst, t.Type, 0, 0) // length (tbd [1])
+	off := len(dst)
+	switch {
+	case t.Label != nil:
+		dst = append(dst, t.Label.Reserved)
+		dst = msb.AppendUint16(dst, t.Label.Flags)
+		dst = msb.AppendUint32(dst, t.Label.Index)
+	case t.Srv6 != nil:
+		dst = t.Srv6.Marshal(dst)
+	case t.SID != nil:
+		dst = t.SID.Marshal(dst)
+	case t.Structure != nil:
+		st := t.Structure
+		dst = append(dst, st.LocatorBlock, st.LocatorNode, st.Function, st.Argument, st.TransLen, st.TransOff)
+	default:
+		dst = append(dst, t.Value...)
+	}
+	msb.PutUint16(dst[off-2:], uint16(len(dst)-off)) // [1]
+	return dst
+}
+
+func (a *PrefixSid) Marshal(dst []byte, cps caps.Caps, dir dir.Dir) []byte {
+	val := appendTLVs(nil, a.TLV)
+	dst = a.CodeFlags.MarshalLen(dst, len(val))
+	return append(dst, val...)
+}
+
+// Marshal appends the SRv6 Service TLV value to dst
+func (s *Srv6Service) Marshal(dst []byte) []byte {
+	dst = append(dst, s.Reserved)
+	return appendTLVs(dst, s.Sub)
+}
+
+// Marshal appends the SRv6 SID Information sub-TLV value to dst
+func (s *Srv6SID) Marshal(dst []byte) []byte {
+	dst = append(dst, s.Reserved1)
+	if s.SID.Is6() {
+		dst = append(dst, s.SID.AsSlice()...)
+	} else {
+		dst = append(dst, make([]byte, 16)...)
+	}
+	dst = append(dst, s.Flags)
+	dst = msb.AppendUint16(dst, s.Behavior)
+	dst = append(dst, s.Reserved2)
+	return appendTLVs(dst, s.Sub)
+}
+
+// tlvsToJSON appends JSON representation of TLVs in src to dst
+func tlvsToJSON(dst []byte, src []PrefixSidTLV) []byte {
+	dst = append(dst, '[')
+	for i := range src {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = src[i].ToJSON(dst)
+	}
+	return append(dst, ']')
+}
+
+// ToJSON appends JSON representation of t to dst
+func (t *PrefixSidTLV) ToJSON(dst []byte) []byte {
+	switch {
+	case t.Label != nil:
+		dst = append(dst, `{"label-index":`...)
+		dst = json.Uint32(dst, t.Label.Index)
+		if t.Label.Flags != 0 {
+			dst = append(dst, `,"label-flags":`...)
+			dst = json.Uint16(dst, t.Label.Flags)
+		}
+		if t.Label.Reserved != 0 {
+			dst = append(dst, `,"reserved":`...)
+			dst = json.Byte(dst, t.Label.Reserved)
+		}
+		return append(dst, '}')
+	case t.Srv6 != nil:
+		if t.Type == PREFIX_SID_SRV6_L2 {
+			dst = append(dst, `{"srv6-l2":`...)
+		} else {
+			dst = append(dst, `{"srv6-l3":`...)
+		}
+		dst = t.Srv6.ToJSON(dst)
+		return append(dst, '}')
+	case t.SID != nil:
+		return t.SID.ToJSON(dst)
+	case t.Structure != nil:
+		st := t.Structure
+		dst = append(dst, `{"structure":[`...)
+		for j, v := range []byte{st.LocatorBlock, st.LocatorNode, st.Function, st.Argument, st.TransLen, st.TransOff} {
+			if j > 0 {
+				dst = append(dst, ',')
+			}
+			dst = json.Byte(dst, v)
+		}
+		return append(dst, "]}"...)
+	default:
+		dst = append(dst, `{"type":`...)
+		dst = json.Byte(dst, t.Type)
+		dst = append(dst, `,"value":`...)
+		dst = json.Hex(dst, t.Value)
+		return append(dst, '}')
+	}
+}
+
+func (a *PrefixSid) ToJSON(dst []byte) []byte {
+	return tlvsToJSON(dst, a.TLV)
+}
+
+// ToJSON appends JSON representation of s to dst
+func (s *Srv6Service) ToJSON(dst []byte) []byte {
+	dst = append(dst, `{"tlv":`...)
+	dst = tlvsToJSON(dst, s.Sub)
+	if s.Reserved != 0 {
+		dst = append(dst, `,"reserved":`...)
+		dst = json.Byte(dst, s.Reserved)
+	}
+	return append(dst, '}')
+}
+
+// ToJSON appends JSON representation of s to dst
+func (s *Srv6SID) ToJSON(dst []byte) []byte {
+	dst = append(dst, `{"sid":`...)
+	dst = json.Addr(dst, s.SID)
+	dst = append(dst, `,"flags":`...)
+	dst = json.Byte(dst, s.Flags)
+	dst = append(dst, `,"behavior":`...)
+	dst = json.Uint16(dst, s.Behavior)
+	if s.Reserved1 != 0 {
+		dst = append(dst, `,"reserved1":`...)
+		dst = json.Byte(dst, s.Reserved1)
+	}
+	if s.Reserved2 != 0 {
+		dst = append(dst, `,"reserved2":`...)
+		dst = json.Byte(dst, s.Reserved2)
+	}
+	if len(s.Sub) > 0 {
+		dst = append(dst, `,"tlv":`...)
+		dst = tlvsToJSON(dst, s.Sub)
+	}
+	return append(dst, '}')
+}
+
+// tlvsFromJSON reads TLVs at given nesting level from JSON in src, appending to dst
+func tlvsFromJSON(src []byte, level int, dst []PrefixSidTLV) ([]PrefixSidTLV, error) {
+	err := json.ArrayEach(src, func(key int, val []byte, typ json.Type) error {
+		var tlv PrefixSidTLV
+		err := tlv.FromJSON(val, level)
+		dst = append(dst, tlv)
+		return err
+	})
+	return dst, err
+}
+
+// FromJSON reads t at given nesting level from its JSON representation in src
+func (t *PrefixSidTLV) FromJSON(src []byte, level int) error {
+	switch level {
+	case sidLevelService:
+		if json.Get(src, "sid") != nil {
+			t.Type, t.SID = SRV6_SID_INFO, new(Srv6SID)
+			return t.SID.FromJSON(src)
+		}
+	case sidLevelInfo:
+		if v := json.Get(src, "structure"); v != nil {
+			var b []byte
+			err := json.ArrayEach(v, func(key int, val []byte, typ json.Type) error {
+				x, err := json.UnByte(val)
+				b = append(b, x)
+				return err
+			})
+			if err != nil {
+				return err
+			} else if len(b) != 6 {
+				return ErrLength
+			}
+			t.Type, t.Structure = SRV6_SID_STRUCTURE, &Srv6Structure{b[0], b[1], b[2], b[3], b[4], b[5]}
+			return nil
+		}
+	}
+
+	return json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "type":
+			t.Type, err = json.UnByte(val)
+		case "value":
+			t.Value, err = json.UnHex(val, nil)
+		}
+
+		// typed views?
+		if level != sidLevelAttr || err != nil {
+			return
+		}
+		switch key {
+		case "label-index":
+			t.Label, t.Type = t.label(), PREFIX_SID_LABEL_INDEX
+			t.Label.Index, err = json.UnUint32(val)
+		case "label-flags":
+			t.Label, t.Type = t.label(), PREFIX_SID_LABEL_INDEX
+			t.Label.Flags, err = json.UnUint16(val)
+		case "reserved":
+			t.Label, t.Type = t.label(), PREFIX_SID_LABEL_INDEX
+			t.Label.Reserved, err = json.UnByte(val)
+		case "srv6-l3", "srv6-l2":
+			t.Type = PREFIX_SID_SRV6_L3
+			if key == "srv6-l2" {
+				t.Type = PREFIX_SID_SRV6_L2
+			}
+			t.Srv6 = new(Srv6Service)
+			err = t.Srv6.FromJSON(val)
+		}
+		return
+	})
+}
+
+// label returns t.Label, or a new PrefixSidLabel if nil
+func (t *PrefixSidTLV) label() *PrefixSidLabel {
+	if t.Label != nil {
+		return t.Label
+	}
+	return new(PrefixSidLabel)
+}
+
+func (a *PrefixSid) FromJSON(src []byte) (err error) {
+	a.TLV, err = tlvsFromJSON(src, sidLevelAttr, a.TLV[:0])
+	return err
+}
+
+// FromJSON reads s from its JSON representation in src
+func (s *Srv6Service) FromJSON(src []byte) error {
+	return json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "reserved":
+			s.Reserved, err = json.UnByte(val)
+		case "tlv":
+			s.Sub, err = tlvsFromJSON(val, sidLevelService, s.Sub)
+		}
+		return
+	})
+}
+
+// FromJSON reads s from its JSON representation in src
+func (s *Srv6SID) FromJSON(src []byte) error {
+	return json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "sid":
+			s.SID, err = json.UnAddr(val)
+			if err == nil && !s.SID.Is6() {
+				err = ErrValue
+			}
+		case "flags":
+			s.Flags, err = json.UnByte(val)
+		case "behavior":
+			s.Behavior, err = json.UnUint16(val)
+		case "reserved1":
+			s.Reserved1, err = json.UnByte(val)
+		case "reserved2":
+			s.Reserved2, err = json.UnByte(val)
+		case "tlv":
+			s.Sub, err = tlvsFromJSON(val, sidLevelInfo, s.Sub)
+		}
+		return
+	})
+}
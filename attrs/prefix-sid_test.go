@@ -0,0 +1,133 @@
+package attrs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestPrefixSid(t *testing.T) {
+	tests := []struct {
+		name  string
+		buf   []byte
+		js    string
+		index uint32
+	}{
+		{
+			name: "label index + SRGB",
+			buf: []byte{
+				0x01, 0x00, 0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0xe8, // label index 1000
+				0x03, 0x00, 0x08, 0x00, 0x00, 0x00, 0x3e, 0x80, 0x00, 0x1f, 0x40, // SRGB 16000, 8000
+			},
+			js:    `[{"label-index":1000},{"type":3,"value":"0x0000003e80001f40"}]`,
+			index: 1000,
+		},
+		{
+			name: "SRGB + label index",
+			buf: []byte{
+				0x03, 0x00, 0x08, 0x00, 0x00, 0x00, 0x3e, 0x80, 0x00, 0x1f, 0x40, // SRGB 16000, 8000
+				0x01, 0x00, 0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0xe8, // label index 1000
+			},
+			js:    `[{"type":3,"value":"0x0000003e80001f40"},{"label-index":1000}]`,
+			index: 1000,
+		},
+		{
+			name: "reserved bytes",
+			buf: []byte{
+				0x01, 0x00, 0x07, 0xaa, 0x00, 0x01, 0x00, 0x00, 0x00, 0x05, // label index 5, flags 1
+				0x05, 0x00, 0x19, // SRv6 L3 Service TLV
+				0xbb,             // reserved
+				0x01, 0x00, 0x15, // SID Information sub-TLV
+				0xcc,                                                                                           // reserved
+				0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // SID
+				0x00, 0x00, 0x12, 0xdd, // flags, behavior End.DT4 (0x12), reserved
+			},
+			js:    `[{"label-index":5,"label-flags":1,"reserved":170},{"srv6-l3":{"tlv":[{"sid":"2001:db8::1","flags":0,"behavior":18,"reserved1":204,"reserved2":221}],"reserved":187}}]`,
+			index: 5,
+		},
+		{
+			name: "srv6 l3 service",
+			buf: []byte{
+				0x05, 0x00, 0x22, // SRv6 L3 Service TLV
+				0x00,             // reserved
+				0x01, 0x00, 0x1e, // SID Information sub-TLV
+				0x00,                                                                                           // reserved
+				0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // SID
+				0x00, 0x00, 0x13, 0x00, // flags, behavior End.DT46 (0x13), reserved
+				0x01, 0x00, 0x06, 0x20, 0x10, 0x10, 0x00, 0x10, 0x40, // SID Structure
+			},
+			js: `[{"srv6-l3":{"tlv":[{"sid":"2001:db8:1:2::","flags":0,"behavior":19,"tlv":[{"structure":[32,16,16,0,16,64]}]}]}}]`,
+		},
+	}
+
+	var cps caps.Caps
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAttr(ATTR_PREFIX_SID).(*PrefixSid)
+			if err := a.Unmarshal(tt.buf, cps, dir.DIR_L); err != nil {
+				t.Fatalf("Unmarshal error = %v", err)
+			}
+			var index uint32
+			if l := a.Label(); l != nil {
+				index = l.Index
+			}
+			if index != tt.index {
+				t.Errorf("Label().Index = %d, want %d", index, tt.index)
+			}
+			if got := string(a.ToJSON(nil)); got != tt.js {
+				t.Errorf("ToJSON = %s, want %s", got, tt.js)
+			}
+			if got := a.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("Marshal = %x, want %x", got[3:], tt.buf)
+			}
+
+			b := NewAttr(ATTR_PREFIX_SID).(*PrefixSid)
+			if err := b.FromJSON([]byte(tt.js)); err != nil {
+				t.Fatalf("FromJSON error = %v", err)
+			}
+			if got := b.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("FromJSON Marshal = %x, want %x", got[3:], tt.buf)
+			}
+		})
+	}
+
+	// modify the label index, SRGB must stay first
+	d := NewAttr(ATTR_PREFIX_SID).(*PrefixSid)
+	if err := d.Unmarshal(tests[1].buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	d.Label().Index = 2000
+	want := bytes.Clone(tests[1].buf)
+	want[19], want[20] = 0x07, 0xd0
+	if got := d.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], want) {
+		t.Errorf("re-Marshal = %x, want %x", got[3:], want)
+	}
+
+	// typed views
+	e := NewAttr(ATTR_PREFIX_SID).(*PrefixSid)
+	if err := e.Unmarshal(tests[3].buf, cps, dir.DIR_L); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if e.Label() != nil {
+		t.Errorf("Label() = %v, want nil", e.Label())
+	}
+	if sids := e.Srv6L3().SIDs(); len(sids) != 1 || sids[0].Structure() == nil || sids[0].Structure().LocatorBlock != 32 {
+		t.Errorf("Srv6L3().SIDs() = %v, want 1 SID with structure", sids)
+	}
+
+	// malformed
+	for _, bad := range [][]byte{
+		{0x01, 0x00},                               // short header
+		{0x01, 0x00, 0x07, 0x00, 0x00},             // truncated
+		{0x01, 0x00, 0x02, 0x00, 0x00},             // label index not 7 bytes
+		{0x05, 0x00, 0x04, 0x00, 0x01, 0x00, 0x00}, // SID Information too short
+	} {
+		c := NewAttr(ATTR_PREFIX_SID).(*PrefixSid)
+		if err := c.Unmarshal(bad, cps, dir.DIR_L); !errors.Is(err, ErrLength) {
+			t.Errorf("Unmarshal(%x) error = %v, want ErrLength", bad, err)
+		}
+	}
+}
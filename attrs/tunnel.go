@@ -0,0 +1,280 @@
+package attrs
+
+import (
+	"net/netip"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/json"
+)
+
+// TunnelEncap represents ATTR_TUNNEL, the Tunnel Encapsulation attribute (RFC 9012)
+type TunnelEncap struct {
+	CodeFlags
+	Tunnels []Tunnel
+}
+
+// Tunnel represents a single Tunnel TLV in ATTR_TUNNEL
+type Tunnel struct {
+	Type     uint16      // tunnel type
+	Endpoint netip.Addr  // Tunnel Egress Endpoint (sub-TLV 6), if valid
+	Color    uint32      // Color (sub-TLV 4), if non-zero
+	Sub      []TunnelSub // other sub-TLVs, eg. the Encapsulation sub-TLV
+}
+
+// TunnelSub represents a Tunnel sub-TLV, with opaque value
+type TunnelSub struct {
+	Type  byte
+	Value []byte
+}
+
+// Tunnel types, rfc9012/14.1 (the IANA registry)
+const (
+	TUNNEL_L2TPV3      uint16 = 1
+	TUNNEL_GRE         uint16 = 2
+	TUNNEL_IPIP        uint16 = 7
+	TUNNEL_VXLAN       uint16 = 8
+	TUNNEL_NVGRE       uint16 = 9
+	TUNNEL_MPLS        uint16 = 10
+	TUNNEL_MPLS_IN_GRE uint16 = 11
+	TUNNEL_VXLAN_GPE   uint16 = 12
+	TUNNEL_MPLS_IN_UDP uint16 = 13
+	TUNNEL_SR_POLICY   uint16 = 15
+	TUNNEL_GENEVE      uint16 = 19
+)
+
+// Tunnel sub-TLV types, rfc9012/3
+const (
+	TUNNEL_SUB_ENCAP    byte = 1 // Encapsulation
+	TUNNEL_SUB_PROTO    byte = 2 // Protocol Type
+	TUNNEL_SUB_COLOR    byte = 4 // Color
+	TUNNEL_SUB_ENDPOINT byte = 6 // Tunnel Egress Endpoint
+	TUNNEL_SUB_UDP_PORT byte = 8 // UDP Destination Port
+)
+
+func NewTunnelEncap(at CodeFlags) Attr {
+	return &TunnelEncap{CodeFlags: at}
+}
+
+func (a *TunnelEncap) Unmarshal(buf []byte, cps caps.Caps, dir dir.Dir) error {
+	for len(buf) > 0 {
+		if len(buf) < 4 {
+			return ErrLength
+		}
+		t := Tunnel{Type: msb.Uint16(buf[0:2])}
+		tl := int(msb.Uint16(buf[2:4]))
+		buf = buf[4:]
+		if len(buf) < tl {
+			return ErrLength
+		}
+		if err := t.unmarshalSub(buf[:tl]); err != nil {
+			return err
+		}
+		a.Tunnels = append(a.Tunnels, t)
+		buf = buf[tl:]
+	}
+	return nil
+}
+
+// unmarshalSub parses the sub-TLVs in buf
+func (t *Tunnel) unmarshalSub(buf []byte) error {
+	for len(buf) > 0 {
+		if len(buf) < 2 {
+			return ErrLength
+		}
+
+		// 1- or 2-byte length, rfc9012/2
+		typ, sl := buf[0], int(buf[1])
+		buf = buf[2:]
+		if typ >= 128 {
+			if len(buf) < 1 {
+				return ErrLength
+			}
+			sl = sl<<8 | int(buf[0])
+			buf = buf[1:]
+		}
+		if len(buf) < sl {
+			return ErrLength
+		}
+
+		val := buf[:sl]
+		buf = buf[sl:]
+		switch {
+		case typ == TUNNEL_SUB_ENDPOINT && !t.Endpoint.IsValid():
+			if addr, ok := parseEndpoint(val); ok {
+				t.Endpoint = addr
+				continue
+			}
+		case typ == TUNNEL_SUB_COLOR && t.Color == 0:
+			if color := parseColor(val); color != 0 {
+				t.Color = color
+				continue
+			}
+		}
+		t.Add(typ, val)
+	}
+	return nil
+}
+
+// parseEndpoint parses the Tunnel Egress Endpoint sub-TLV value in buf, rfc9012/3.1
+func parseEndpoint(buf []byte) (addr netip.Addr, ok bool) {
+	if len(buf) < 6 {
+		return addr, false
+	}
+	switch af, buf := afi.AFI(msb.Uint16(buf[4:6])), buf[6:]; {
+	case af == afi.AFI_IPV4 && len(buf) == 4:
+		return netip.AddrFrom4([4]byte(buf)), true
+	case af == afi.AFI_IPV6 && len(buf) == 16:
+		return netip.AddrFrom16([16]byte(buf)), true
+	default:
+		return addr, false
+	}
+}
+
+// parseColor parses the Color sub-TLV value in buf, rfc9012/3.4.
+// Returns 0 if buf is not a Color Extended Community with no flags set.
+func parseColor(buf []byte) uint32 {
+	if len(buf) != 8 || buf[0] != 0x03 || buf[1] != 0x0b || buf[2] != 0 || buf[3] != 0 {
+		return 0
+	}
+	return msb.Uint32(buf[4:8])
+}
+
+// Add appends a copy of sub-TLV typ with given value
+func (t *Tunnel) Add(typ byte, value []byte) {
+	t.Sub = append(t.Sub, TunnelSub{
+		Type:  typ,
+		Value: append([]byte(nil), value...),
+	})
+}
+
+// Find returns the value of the first sub-TLV of type typ, or nil if not found
+func (t *Tunnel) Find(typ byte) []byte {
+	for i := range t.Sub {
+		if t.Sub[i].Type == typ {
+			return t.Sub[i].Value
+		}
+	}
+	return nil
+}
+
+// marshalSub appends the wire representation of the sub-TLVs in t to dst
+func (t *Tunnel) marshalSub(dst []byte) []byte {
+	if t.Endpoint.IsValid() {
+		addr := t.Endpoint.AsSlice()
+		dst = append(dst, TUNNEL_SUB_ENDPOINT, byte(6+len(addr)), 0, 0, 0, 0)
+		if t.Endpoint.Is4() {
+			dst = msb.AppendUint16(dst, uint16(afi.AFI_IPV4))
+		} else {
+			dst = msb.AppendUint16(dst, uint16(afi.AFI_IPV6))
+		}
+		dst = append(dst, addr...)
+	}
+
+	if t.Color != 0 {
+		dst = append(dst, TUNNEL_SUB_COLOR, 8, 0x03, 0x0b, 0, 0)
+		dst = msb.AppendUint32(dst, t.Color)
+	}
+
+	for _, s := range t.Sub {
+		if s.Type >= 128 {
+			dst = append(dst, s.Type)
+			dst = msb.AppendUint16(dst, uint16(len(s.Value)))
+		} else {
+			dst = append(dst, s.Type, byte(len(s.Value)))
+		}
+		dst = append(dst, s.Value...)
+	}
+	return dst
+}
+
+func (a *TunnelEncap) Marshal(dst []byte, cps caps.Caps, dir dir.Dir) []byte {
+	var val []byte
+	for i := range a.Tunnels {
+		t := &a.Tunnels[i]
+		val = msb.AppendUint16(val, t.Type)
+		val = append(val, 0, 0) // length (tbd [1])
+		off := len(val)
+		val = t.marshalSub(val)
+		msb.PutUint16(val[off-2:], uint16(len(val)-off)) // [1]
+	}
+
+	dst = a.CodeFlags.MarshalLen(dst, len(val))
+	return append(dst, val...)
+}
+
+func (a *TunnelEncap) ToJSON(dst []byte) []byte {
+	dst = append(dst, '[')
+	for i := range a.Tunnels {
+		t := &a.Tunnels[i]
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `{"type":`...)
+		dst = json.Uint16(dst, t.Type)
+		if t.Endpoint.IsValid() {
+			dst = append(dst, `,"endpoint":"`...)
+			dst = t.Endpoint.AppendTo(dst)
+			dst = append(dst, '"')
+		}
+		if t.Color != 0 {
+			dst = append(dst, `,"color":`...)
+			dst = json.Uint32(dst, t.Color)
+		}
+		if len(t.Sub) > 0 {
+			dst = append(dst, `,"sub":[`...)
+			for j := range t.Sub {
+				if j > 0 {
+					dst = append(dst, ',')
+				}
+				dst = append(dst, `{"type":`...)
+				dst = json.Byte(dst, t.Sub[j].Type)
+				dst = append(dst, `,"value":`...)
+				dst = json.Hex(dst, t.Sub[j].Value)
+				dst = append(dst, '}')
+			}
+			dst = append(dst, ']')
+		}
+		dst = append(dst, '}')
+	}
+	return append(dst, ']')
+}
+
+func (a *TunnelEncap) FromJSON(src []byte) error {
+	a.Tunnels = a.Tunnels[:0]
+	return json.ArrayEach(src, func(key int, val []byte, typ json.Type) error {
+		var t Tunnel
+		err := json.ObjectEach(val, func(key string, val []byte, typ json.Type) (err error) {
+			switch key {
+			case "type":
+				t.Type, err = json.UnUint16(val)
+			case "endpoint":
+				t.Endpoint, err = netip.ParseAddr(json.S(val))
+			case "color":
+				t.Color, err = json.UnUint32(val)
+			case "sub":
+				err = json.ArrayEach(val, func(key int, val []byte, typ json.Type) error {
+					var s TunnelSub
+					err := json.ObjectEach(val, func(key string, val []byte, typ json.Type) (err error) {
+						switch key {
+						case "type":
+							s.Type, err = json.UnByte(val)
+						case "value":
+							s.Value, err = json.UnHex(val, nil)
+						}
+						return
+					})
+					t.Sub = append(t.Sub, s)
+					return err
+				})
+			}
+			return
+		})
+		if err != nil {
+			return err
+		}
+		a.Tunnels = append(a.Tunnels, t)
+		return nil
+	})
+}
@@ -0,0 +1,102 @@
+package attrs
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+)
+
+func TestTunnelEncap_Endpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		buf      []byte
+		endpoint string
+		json     string
+	}{
+		{
+			name: "ipv4",
+			buf: []byte{
+				0x00, 0x08, 0x00, 0x16, // VXLAN, len 22
+				0x06, 0x0a, 0, 0, 0, 0, 0x00, 0x01, 192, 0, 2, 1, // endpoint
+				0x04, 0x08, 0x03, 0x0b, 0, 0, 0, 0, 0, 100, // color
+			},
+			endpoint: "192.0.2.1",
+			json:     `[{"type":8,"endpoint":"192.0.2.1","color":100}]`,
+		},
+		{
+			name: "ipv6",
+			buf: []byte{
+				0x00, 0x08, 0x00, 0x1e, // VXLAN, len 30
+				0x06, 0x16, 0, 0, 0, 0, 0x00, 0x02, // endpoint
+				0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+				0x80, 0x00, 0x03, 0xaa, 0xbb, 0xcc, // sub-TLV 128 with 2-byte length
+			},
+			endpoint: "2001:db8::1",
+			json:     `[{"type":8,"endpoint":"2001:db8::1","sub":[{"type":128,"value":"0xaabbcc"}]}]`,
+		},
+		{
+			name: "no address",
+			buf: []byte{
+				0x00, 0x08, 0x00, 0x08, // VXLAN, len 8
+				0x06, 0x06, 0, 0, 0, 0, 0x00, 0x00, // endpoint, AFI 0
+			},
+			json: `[{"type":8,"sub":[{"type":6,"value":"0x000000000000"}]}]`,
+		},
+		{
+			name: "vxlan encap",
+			buf: []byte{
+				0x00, 0x08, 0x00, 0x1a, // VXLAN, len 26
+				0x06, 0x0a, 0, 0, 0, 0, 0x00, 0x01, 192, 0, 2, 1, // endpoint
+				0x01, 0x0c, 0x80, 0x00, 0x27, 0x10, 0, 0, 0, 0, 0, 0, 0, 0, // encapsulation, VNI 10000
+			},
+			endpoint: "192.0.2.1",
+			json:     `[{"type":8,"endpoint":"192.0.2.1","sub":[{"type":1,"value":"0x800027100000000000000000"}]}]`,
+		},
+		{
+			name: "color with flags",
+			buf: []byte{
+				0x00, 0x0b, 0x00, 0x0a, // MPLS-in-GRE, len 10
+				0x04, 0x08, 0x03, 0x0b, 0x40, 0, 0, 0, 0, 100, // color, flags set
+			},
+			json: `[{"type":11,"sub":[{"type":4,"value":"0x030b400000000064"}]}]`,
+		},
+	}
+
+	var cps caps.Caps
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAttr(ATTR_TUNNEL).(*TunnelEncap)
+			if err := a.Unmarshal(tt.buf, cps, dir.DIR_L); err != nil {
+				t.Fatalf("Unmarshal error = %v", err)
+			}
+			if len(a.Tunnels) != 1 {
+				t.Fatalf("got %d tunnels, want 1", len(a.Tunnels))
+			}
+
+			var want netip.Addr
+			if tt.endpoint != "" {
+				want = netip.MustParseAddr(tt.endpoint)
+			}
+			if got := a.Tunnels[0].Endpoint; got != want {
+				t.Errorf("Endpoint = %s, want %s", got, want)
+			}
+			if got := string(a.ToJSON(nil)); got != tt.json {
+				t.Errorf("ToJSON = %s, want %s", got, tt.json)
+			}
+			if got := a.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("Marshal = %x, want %x", got[3:], tt.buf)
+			}
+
+			b := NewAttr(ATTR_TUNNEL).(*TunnelEncap)
+			if err := b.FromJSON([]byte(tt.json)); err != nil {
+				t.Fatalf("FromJSON error = %v", err)
+			}
+			if got := b.Marshal(nil, cps, dir.DIR_L); !bytes.Equal(got[3:], tt.buf) {
+				t.Errorf("FromJSON Marshal = %x, want %x", got[3:], tt.buf)
+			}
+		})
+	}
+}
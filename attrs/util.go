@@ -1,7 +1,12 @@
 package attrs
 
 import (
+	"bytes"
 	"net/netip"
+	"slices"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
 )
 
 // ParseNH is best-effort parser for Next Hop value in buf
@@ -24,3 +29,76 @@ func ParseNH(buf []byte) (addr, ll netip.Addr, ok bool) {
 	}
 	return
 }
+
+// cloneAttr returns a deep copy of at. The most common attributes are copied
+// directly, others through their wire representation (with 4-byte ASNs),
+// and MP values other than prefixes through JSON. Falls back to Raw.
+func cloneAttr(at Attr) Attr {
+	switch a := at.(type) {
+	case *Aspath:
+		return a.Clone()
+	case *Origin:
+		a2 := *a
+		return &a2
+	case *U32:
+		a2 := *a
+		return &a2
+	case *IP:
+		a2 := *a
+		return &a2
+	case *Aggregator:
+		a2 := *a
+		return &a2
+	case *Otc:
+		a2 := *a
+		return &a2
+	case *IPList:
+		a2 := *a
+		a2.Addr = slices.Clone(a.Addr)
+		return &a2
+	case *Community:
+		a2 := *a
+		a2.ASN = slices.Clone(a.ASN)
+		a2.Value = slices.Clone(a.Value)
+		return &a2
+	case *LargeCom:
+		a2 := *a
+		a2.ASN = slices.Clone(a.ASN)
+		a2.Value1 = slices.Clone(a.Value1)
+		a2.Value2 = slices.Clone(a.Value2)
+		return &a2
+	case *Raw:
+		a2 := *a
+		a2.Raw = bytes.Clone(a.Raw)
+		return &a2
+	case *MP:
+		if mp2 := a.clone(); mp2 != nil {
+			return mp2
+		}
+		mp2 := NewAttr(a.Code())
+		mp2.SetFlags(a.Flags())
+		if mp2.FromJSON(a.ToJSON(nil)) == nil {
+			return mp2
+		}
+	}
+
+	// marshal with 4-byte ASNs and read back
+	cf := CodeFlags(at.Flags())<<8 | CodeFlags(at.Code())
+	var cps caps.Caps
+	cps.Use(caps.CAP_AS4)
+	buf := at.Marshal(nil, cps, dir.DIR_L)
+	if len(buf) < 3 {
+		return &Raw{CodeFlags: cf}
+	} else if Flags(buf[0])&ATTR_EXTENDED != 0 {
+		buf = buf[4:]
+	} else {
+		buf = buf[3:]
+	}
+
+	at2 := NewAttr(at.Code())
+	at2.SetFlags(at.Flags())
+	if at2.Unmarshal(buf, cps, dir.DIR_L) == nil {
+		return at2
+	}
+	return &Raw{CodeFlags: cf, Raw: buf}
+}
@@ -69,6 +69,8 @@ var NewFuncs = map[Code]NewFunc{
 	CAP_EXTENDED_NEXTHOP: NewExtNH,
 	CAP_FQDN:             NewFqdn,
 	CAP_ADDPATH:          NewAddPath,
+	CAP_ROLE:             NewRole,
+	CAP_LLGR:             NewLLGR,
 }
 
 // NewCap returns a new Cap instance for given code cc
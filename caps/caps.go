@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/bgpfix/bgpfix/afi"
 	"github.com/bgpfix/bgpfix/binary"
 	"github.com/bgpfix/bgpfix/json"
 	"github.com/puzpuzpuz/xsync/v3"
@@ -151,6 +152,45 @@ func (cps *Caps) Each(cb func(i int, cc Code, cap Cap)) {
 	}
 }
 
+// Intersect returns the capabilities present in both cps and other, eg. the capabilities
+// of a BGP session after exchanging OPENs. Where Cap.Intersect returns a new value
+// (eg. the common AFI/SAFIs in CAP_MP), it is used; otherwise, the value in cps is referenced.
+func (cps *Caps) Intersect(other Caps) (dst Caps) {
+	dst.Init()
+	cps.Each(func(i int, cc Code, cap Cap) {
+		cap2 := other.Get(cc)
+		if cap2 == nil {
+			return // not in other
+		} else if icap := cap.Intersect(cap2); icap != nil {
+			dst.Set(cc, icap)
+		} else {
+			dst.Set(cc, cap)
+		}
+	})
+	return dst
+}
+
+// Diff returns the sorted list of capability codes present in cps, but not in other,
+// eg. to find which capabilities a peer did not send back.
+func (cps *Caps) Diff(other Caps) (codes []Code) {
+	cps.Each(func(i int, cc Code, cap Cap) {
+		if !other.Has(cc) {
+			codes = append(codes, cc)
+		}
+	})
+	return codes
+}
+
+// Families returns the sorted list of address families enabled in cps:
+// from CAP_MP if present, or just the implicit IPv4 unicast otherwise.
+func (cps *Caps) Families() []afi.AS {
+	if c, ok := cps.Get(CAP_MP).(*MP); ok {
+		return c.Sorted()
+	} else {
+		return []afi.AS{afi.AS_IPV4_UNICAST}
+	}
+}
+
 func (cps *Caps) String() string {
 	return string(cps.ToJSON(nil))
 }
@@ -0,0 +1,125 @@
+package caps
+
+import (
+	"testing"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaps_Families(t *testing.T) {
+	var cps Caps
+	assert.Equal(t, []afi.AS{afi.AS_IPV4_UNICAST}, cps.Families())
+
+	mp := cps.Use(CAP_MP).(*MP)
+	mp.Add(afi.AFI_IPV6, afi.SAFI_UNICAST)
+	mp.Add(afi.AFI_IPV4, afi.SAFI_FLOWSPEC)
+	assert.Equal(t, []afi.AS{afi.AS_IPV4_FLOWSPEC, afi.AS_IPV6_UNICAST}, cps.Families())
+}
+
+func TestCaps_Intersect(t *testing.T) {
+	var local, remote Caps
+	lmp := local.Use(CAP_MP).(*MP)
+	lmp.Add(afi.AFI_IPV4, afi.SAFI_UNICAST)
+	lmp.Add(afi.AFI_IPV6, afi.SAFI_UNICAST)
+	lmp.Add(afi.AFI_IPV4, afi.SAFI_FLOWSPEC)
+	local.Use(CAP_AS4).(*AS4).ASN = 4200000001
+	local.Use(CAP_ROUTE_REFRESH)
+
+	rmp := remote.Use(CAP_MP).(*MP)
+	rmp.Add(afi.AFI_IPV6, afi.SAFI_UNICAST)
+	rmp.Add(afi.AFI_IPV4, afi.SAFI_FLOWSPEC)
+	rmp.Add(afi.AFI_L2VPN, afi.SAFI_EVPNS)
+	remote.Use(CAP_ROUTE_REFRESH)
+
+	// no AS4 on remote side: not common
+	common := local.Intersect(remote)
+	assert.Equal(t, []afi.AS{afi.AS_IPV4_FLOWSPEC, afi.AS_IPV6_UNICAST}, common.Families())
+	assert.False(t, common.Has(CAP_AS4))
+	assert.True(t, common.Has(CAP_ROUTE_REFRESH))
+	assert.Equal(t, 2, common.Len())
+	assert.Equal(t, []Code{CAP_AS4}, local.Diff(remote))
+	assert.Empty(t, remote.Diff(local))
+
+	// AS4 on both sides: value of the receiver
+	remote.Use(CAP_AS4).(*AS4).ASN = 65001
+	common = remote.Intersect(local)
+	if c, ok := common.Get(CAP_AS4).(*AS4); assert.True(t, ok) {
+		assert.EqualValues(t, 65001, c.ASN)
+	}
+	assert.Empty(t, local.Diff(remote))
+
+	// the originals are not modified
+	assert.Len(t, lmp.Proto, 3)
+	assert.Len(t, rmp.Proto, 3)
+
+	// invalid Caps
+	var empty Caps
+	common = empty.Intersect(local)
+	assert.Equal(t, 0, common.Len())
+	common = local.Intersect(empty)
+	assert.Equal(t, 0, common.Len())
+	assert.Len(t, local.Diff(empty), 3)
+}
+
+func TestLLGR(t *testing.T) {
+	buf := []byte{
+		0x00, 0x01, 0x01, 0x80, 0x01, 0x51, 0x80, // IPV4/UNICAST, F, 86400s
+		0x00, 0x02, 0x01, 0x00, 0x00, 0x0e, 0x10, // IPV6/UNICAST, 3600s
+	}
+	js := `[{"af":"IPV4/UNICAST","flags":128,"stale":86400},{"af":"IPV6/UNICAST","flags":0,"stale":3600}]`
+
+	c, ok := NewCap(CAP_LLGR).(*LLGR)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.NoError(t, c.Unmarshal(buf, Caps{}))
+	fam, ok := c.Get(afi.AS_IPV4_UNICAST)
+	assert.True(t, ok)
+	assert.Equal(t, LLGRFamily{LLGR_FORWARDING, 86400}, fam)
+	assert.Equal(t, js, string(c.ToJSON(nil)))
+	assert.Equal(t, append([]byte{byte(CAP_LLGR), 14}, buf...), c.Marshal(nil))
+	assert.ErrorIs(t, c.Unmarshal(buf[:6], Caps{}), ErrLength)
+
+	c2 := NewCap(CAP_LLGR).(*LLGR)
+	assert.NoError(t, c2.FromJSON([]byte(js)))
+	assert.Equal(t, c.Proto, c2.Proto)
+
+	c2.Add(afi.AS_IPV6_UNICAST, LLGR_FORWARDING, 60)
+	c2.Drop(afi.AS_IPV4_UNICAST)
+	c2.Add(afi.AS_IPV4_FLOWSPEC, 0, 60)
+	neg := c.Intersect(c2).(*LLGR)
+	assert.Equal(t, map[afi.AS]LLGRFamily{afi.AS_IPV6_UNICAST: {0, 60}}, neg.Proto)
+}
+
+func TestFqdn(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  []byte
+		js   string
+	}{
+		{"host and domain", []byte{3, 'r', 't', 'r', 7, 'e', 'x', '.', 'c', 'o', 'm', '.'}, `{"host":"rtr","domain":"ex.com."}`},
+		{"empty domain", []byte{3, 'r', 't', 'r', 0}, `{"host":"rtr"}`},
+		{"empty host", []byte{0, 0}, `{"host":""}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := NewCap(CAP_FQDN).(*Fqdn)
+			if !assert.True(t, ok) {
+				return
+			}
+			assert.NoError(t, c.Unmarshal(tt.buf, Caps{}))
+			assert.Equal(t, tt.js, string(c.ToJSON(nil)))
+			assert.Equal(t, append([]byte{byte(CAP_FQDN), byte(len(tt.buf))}, tt.buf...), c.Marshal(nil))
+
+			c2 := NewCap(CAP_FQDN).(*Fqdn)
+			assert.NoError(t, c2.FromJSON([]byte(tt.js)))
+			assert.Equal(t, append([]byte{byte(CAP_FQDN), byte(len(tt.buf))}, tt.buf...), c2.Marshal(nil))
+		})
+	}
+
+	// truncated
+	c := NewCap(CAP_FQDN).(*Fqdn)
+	assert.ErrorIs(t, c.Unmarshal([]byte{3, 'r', 't', 'r'}, Caps{}), ErrLength)
+	assert.ErrorIs(t, c.Unmarshal([]byte{3, 'r', 't', 'r', 2, 'x'}, Caps{}), ErrLength)
+}
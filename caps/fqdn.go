@@ -27,6 +27,9 @@ func (c *Fqdn) Unmarshal(buf []byte, caps Caps) error {
 	c.Host, buf = buf[:l], buf[l:]
 
 	// domain name length (1) + domain (variable)
+	if len(buf) < 1 {
+		return ErrLength
+	}
 	l, buf = int(buf[0]), buf[1:]
 	if len(buf) < l {
 		return ErrLength
@@ -64,7 +67,7 @@ func (c *Fqdn) ToJSON(dst []byte) []byte {
 	dst = append(dst, `"`...)
 
 	if len(c.Domain) > 0 {
-		dst = append(dst, `","domain":"`...)
+		dst = append(dst, `,"domain":"`...)
 		dst = json.Ascii(dst, c.Domain)
 		dst = append(dst, `"`...)
 	}
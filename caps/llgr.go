@@ -0,0 +1,162 @@
+package caps
+
+import (
+	"slices"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/json"
+)
+
+// LLGR implements CAP_LLGR rfc9494
+type LLGR struct {
+	// Proto maps AFI+SAFI pairs to their LLGR flags and stale time
+	Proto map[afi.AS]LLGRFamily
+}
+
+// LLGRFamily represents LLGR parameters for one AFI+SAFI pair
+type LLGRFamily struct {
+	Flags     byte   // LLGR_* flags
+	StaleTime uint32 // long-lived stale time in seconds (24 bits)
+}
+
+// LLGR flags, rfc9494/3
+const (
+	LLGR_FORWARDING byte = 0x80 // forwarding state preserved (F bit)
+)
+
+// LLGR_STALE_MAX is the maximum long-lived stale time (24 bits)
+const LLGR_STALE_MAX = 1<<24 - 1
+
+func NewLLGR(cc Code) Cap {
+	return &LLGR{make(map[afi.AS]LLGRFamily)}
+}
+
+func (c *LLGR) Unmarshal(buf []byte, caps Caps) error {
+	for len(buf) > 0 {
+		if len(buf) < 7 {
+			return ErrLength
+		}
+
+		as := afi.NewASBytes(buf[0:3])
+		c.Add(as, buf[3], uint32(buf[4])<<16|uint32(msb.Uint16(buf[5:7])))
+		buf = buf[7:]
+	}
+
+	return nil
+}
+
+// Add adds LLGR for AFI+SAFI pair in as, with given flags and stale time.
+// The stale time is truncated to LLGR_STALE_MAX.
+func (c *LLGR) Add(as afi.AS, flags byte, stale uint32) {
+	c.Proto[as] = LLGRFamily{flags, min(stale, LLGR_STALE_MAX)}
+}
+
+// Get returns LLGR parameters for AFI+SAFI pair in as, and true if found.
+func (c *LLGR) Get(as afi.AS) (fam LLGRFamily, ok bool) {
+	if c == nil {
+		return fam, false
+	}
+	fam, ok = c.Proto[as]
+	return
+}
+
+// Drop drops LLGR for AFI+SAFI pair in as.
+func (c *LLGR) Drop(as afi.AS) {
+	delete(c.Proto, as)
+}
+
+// Sorted returns all AFI+SAFI pairs in sorted order.
+func (c *LLGR) Sorted() (dst []afi.AS) {
+	for as := range c.Proto {
+		dst = append(dst, as)
+	}
+	slices.Sort(dst)
+	return
+}
+
+// Intersect returns AFI+SAFI pairs present in both c and cap2,
+// with the lower of the two stale times and the common flags.
+func (c *LLGR) Intersect(cap2 Cap) Cap {
+	c2, ok := cap2.(*LLGR)
+	if !ok {
+		return nil
+	}
+
+	dst := &LLGR{make(map[afi.AS]LLGRFamily)}
+	for as, f1 := range c.Proto {
+		if f2, ok := c2.Proto[as]; ok {
+			dst.Proto[as] = LLGRFamily{f1.Flags & f2.Flags, min(f1.StaleTime, f2.StaleTime)}
+		}
+	}
+	return dst
+}
+
+func (c *LLGR) Marshal(dst []byte) []byte {
+	todo := c.Sorted()
+
+	var step []afi.AS
+	for len(todo) > 0 {
+		if len(todo) > 36 {
+			dst = append(dst, byte(CAP_LLGR), 7*36)
+			step = todo[:36] // the first 36 elements
+			todo = todo[36:]
+		} else {
+			dst = append(dst, byte(CAP_LLGR), byte(7*len(todo)))
+			step = todo // all
+			todo = nil  // last iteration
+		}
+
+		for _, as := range step {
+			fam := c.Proto[as]
+			dst = as.Marshal3(dst)
+			dst = append(dst, fam.Flags, byte(fam.StaleTime>>16))
+			dst = msb.AppendUint16(dst, uint16(fam.StaleTime))
+		}
+	}
+
+	return dst
+}
+
+func (c *LLGR) ToJSON(dst []byte) []byte {
+	dst = append(dst, '[')
+	for i, as := range c.Sorted() {
+		if i > 0 {
+			dst = append(dst, `,`...)
+		}
+		fam := c.Proto[as]
+		dst = as.ToJSONKey(append(dst, '{'), "af")
+		dst = append(dst, `,"flags":`...)
+		dst = json.Byte(dst, fam.Flags)
+		dst = append(dst, `,"stale":`...)
+		dst = json.Uint32(dst, fam.StaleTime)
+		dst = append(dst, '}')
+	}
+	return append(dst, ']')
+}
+
+func (c *LLGR) FromJSON(src []byte) (err error) {
+	return json.ArrayEach(src, func(key int, val []byte, typ json.Type) error {
+		var (
+			as  afi.AS
+			fam LLGRFamily
+		)
+		err := json.ObjectEach(val, func(key string, val []byte, typ json.Type) (err error) {
+			switch key {
+			case "af":
+				err = as.FromJSON(val)
+			case "flags":
+				fam.Flags, err = json.UnByte(val)
+			case "stale":
+				fam.StaleTime, err = json.UnUint32(val)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		} else if as == 0 || fam.StaleTime > LLGR_STALE_MAX {
+			return ErrValue
+		}
+		c.Proto[as] = fam
+		return nil
+	})
+}
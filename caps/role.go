@@ -0,0 +1,102 @@
+package caps
+
+import (
+	"strconv"
+
+	"github.com/bgpfix/bgpfix/json"
+)
+
+// Role implements CAP_ROLE rfc9234
+type Role struct {
+	Role byte // the BGP Role of the speaker that sent the capability
+}
+
+// BGP Role values, rfc9234/4.1
+const (
+	ROLE_PROVIDER  byte = 0
+	ROLE_RS        byte = 1
+	ROLE_RS_CLIENT byte = 2
+	ROLE_CUSTOMER  byte = 3
+	ROLE_PEER      byte = 4
+)
+
+// RoleName maps BGP Role values to their names
+var RoleName = map[byte]string{
+	ROLE_PROVIDER:  "provider",
+	ROLE_RS:        "rs",
+	ROLE_RS_CLIENT: "rs-client",
+	ROLE_CUSTOMER:  "customer",
+	ROLE_PEER:      "peer",
+}
+
+// RoleValue maps BGP Role names to their values
+var RoleValue = map[string]byte{
+	"provider":  ROLE_PROVIDER,
+	"rs":        ROLE_RS,
+	"rs-client": ROLE_RS_CLIENT,
+	"customer":  ROLE_CUSTOMER,
+	"peer":      ROLE_PEER,
+}
+
+func NewRole(cc Code) Cap {
+	return &Role{}
+}
+
+func (c *Role) Unmarshal(buf []byte, caps Caps) error {
+	if len(buf) != 1 {
+		return ErrLength
+	}
+
+	c.Role = buf[0]
+	return nil
+}
+
+func (c *Role) Intersect(cap2 Cap) Cap {
+	return nil
+}
+
+func (c *Role) Marshal(dst []byte) []byte {
+	return append(dst, byte(CAP_ROLE), 1, c.Role)
+}
+
+func (c *Role) ToJSON(dst []byte) []byte {
+	if name, ok := RoleName[c.Role]; ok {
+		dst = append(dst, '"')
+		dst = append(dst, name...)
+		return append(dst, '"')
+	}
+	return json.Byte(dst, c.Role)
+}
+
+func (c *Role) FromJSON(src []byte) error {
+	if val, ok := RoleValue[json.SQ(src)]; ok {
+		c.Role = val
+		return nil
+	}
+
+	val, err := strconv.ParseUint(json.SQ(src), 0, 8)
+	if err != nil {
+		return ErrValue
+	}
+	c.Role = byte(val)
+	return nil
+}
+
+// Peer returns the BGP Role expected from the peer, ie. the other end of c.Role,
+// and true if the pair is valid (rfc9234/4.2).
+func (c *Role) Peer() (role byte, ok bool) {
+	switch c.Role {
+	case ROLE_PROVIDER:
+		return ROLE_CUSTOMER, true
+	case ROLE_CUSTOMER:
+		return ROLE_PROVIDER, true
+	case ROLE_RS:
+		return ROLE_RS_CLIENT, true
+	case ROLE_RS_CLIENT:
+		return ROLE_RS, true
+	case ROLE_PEER:
+		return ROLE_PEER, true
+	default:
+		return c.Role, false
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/netip"
 	"strconv"
+	"unicode/utf8"
 	"unsafe"
 
 	jsp "github.com/buger/jsonparser"
@@ -102,6 +103,14 @@ func UnUint32(src []byte) (uint32, error) {
 	return uint32(v), err
 }
 
+func Uint64(dst []byte, src uint64) []byte {
+	return strconv.AppendUint(dst, src, 10)
+}
+
+func UnUint64(src []byte) (uint64, error) {
+	return strconv.ParseUint(SQ(src), 0, 64)
+}
+
 func Bool(dst []byte, val bool) []byte {
 	if val {
 		return append(dst, True...)
@@ -144,27 +153,61 @@ func UnPrefix(src []byte) (netip.Prefix, error) {
 // Ascii appends ASCII characters from src to JSON string in dst
 func Ascii(dst, src []byte) []byte {
 	for _, c := range src {
-		if c >= 0x20 && c <= 0x7e {
-			dst = append(dst, c)
+		dst = asciiByte(dst, c)
+	}
+	return dst
+}
+
+// Utf8 appends UTF-8 string src to JSON string in dst, escaping ASCII as in Ascii.
+// Invalid UTF-8 bytes are replaced with U+FFFD.
+func Utf8(dst []byte, src string) []byte {
+	for len(src) > 0 {
+		if c := src[0]; c < utf8.RuneSelf {
+			dst = asciiByte(dst, c)
+			src = src[1:]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(src)
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, `\ufffd`...)
 		} else {
-			switch c {
-			case '"', '\\':
-				dst = append(dst, '\\', c)
-			case '\r':
-				dst = append(dst, '\\', 'r')
-			case '\n':
-				dst = append(dst, '\\', 'n')
-			case '\t':
-				dst = append(dst, '\\', 't')
-			default:
-				dst = append(dst, "\\u00"...)
-				dst = append(dst, hextable[c>>4], hextable[c&0x0f])
-			}
+			dst = append(dst, src[:size]...)
 		}
+		src = src[size:]
 	}
 	return dst
 }
 
+// asciiByte appends ASCII character c to JSON string in dst, escaping if needed
+func asciiByte(dst []byte, c byte) []byte {
+	if c >= 0x20 && c <= 0x7e && c != '"' && c != '\\' {
+		return append(dst, c)
+	}
+	switch c {
+	case '"', '\\':
+		return append(dst, '\\', c)
+	case '\r':
+		return append(dst, '\\', 'r')
+	case '\n':
+		return append(dst, '\\', 'n')
+	case '\t':
+		return append(dst, '\\', 't')
+	default:
+		dst = append(dst, "\\u00"...)
+		return append(dst, hextable[c>>4], hextable[c&0x0f])
+	}
+}
+
+// UnString returns the unescaped value of JSON string in src (optionally "quoted")
+func UnString(src []byte) (string, error) {
+	s, err := jsp.ParseString(Q(src))
+	if err != nil {
+		return "", ErrValue
+	}
+	return s, nil
+}
+
 // S returns string from byte slice, in an unsafe way
 func S(buf []byte) string {
 	return unsafe.String(&buf[0], len(buf))
@@ -0,0 +1,183 @@
+package msg
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/bgpfix/bgpfix/attrs"
+)
+
+// EgressPolicy combines common outbound UPDATE transforms, similar to a router route-map.
+// Call Compile() once after setting the fields, then Apply() to each message,
+// eg. as a pipe callback for UPDATEs sent to the peer.
+//
+// The transforms are applied to announcements only, in the following order:
+//
+//  1. RemovePrivateAS: remove private ASNs from AS_PATH (see attrs.IsPrivateASN)
+//  2. PrependASN: prepend to AS_PATH, PrependCount times
+//  3. NextHopSelf4 / NextHopSelf6: rewrite NEXT_HOP and the MP_REACH next-hop
+//  4. StripAllCommunities or StripCommunities, then AddCommunities
+//  5. ClearMED, then SetMED
+//  6. SetLocalPref
+//
+// Withdrawals (see Update.Kind) pass through unchanged.
+type EgressPolicy struct {
+	RemovePrivateAS bool   // remove private ASNs from AS_PATH
+	PrependASN      uint32 // if non-zero, ASN to prepend to AS_PATH
+	PrependCount    int    // how many times to prepend PrependASN (default 1)
+
+	NextHopSelf4 netip.Addr // if valid, IPv4 next-hop to set
+	NextHopSelf6 netip.Addr // if valid, IPv6 next-hop to set (drops the link-local address)
+
+	StripAllCommunities bool     // drop all standard communities
+	StripCommunities    []uint32 // standard communities to drop, as ASN<<16 | value
+	AddCommunities      []uint32 // standard communities to add, as ASN<<16 | value
+
+	ClearMED     bool    // drop MULTI_EXIT_DISC
+	SetMED       *uint32 // if non-nil, MULTI_EXIT_DISC value to set
+	SetLocalPref *uint32 // if non-nil, LOCAL_PREF value to set
+
+	compiled bool                // true iff Compile() succeeded
+	prepend  int                 // how many times to prepend PrependASN
+	strip    map[uint32]struct{} // communities to strip
+}
+
+// Compile validates pol and prepares it for Apply().
+// Must be called again after modifying pol.
+func (pol *EgressPolicy) Compile() error {
+	pol.compiled = false
+
+	// AS_PATH prepend
+	pol.prepend = 0
+	if pol.PrependASN != 0 {
+		n := pol.PrependCount
+		if n == 0 {
+			n = 1
+		} else if n < 0 || n > 255 {
+			return fmt.Errorf("PrependCount: %w", ErrValue)
+		}
+		pol.prepend = n
+	} else if pol.PrependCount != 0 {
+		return fmt.Errorf("PrependCount: %w: no PrependASN", ErrValue)
+	}
+
+	// next-hops
+	if pol.NextHopSelf4.IsValid() && !pol.NextHopSelf4.Is4() {
+		return fmt.Errorf("NextHopSelf4: %w", ErrValue)
+	}
+	if pol.NextHopSelf6.IsValid() && (!pol.NextHopSelf6.Is6() || pol.NextHopSelf6.Is4In6()) {
+		return fmt.Errorf("NextHopSelf6: %w", ErrValue)
+	}
+
+	// communities
+	pol.strip = nil
+	if len(pol.StripCommunities) > 0 {
+		pol.strip = make(map[uint32]struct{}, len(pol.StripCommunities))
+		for _, c := range pol.StripCommunities {
+			pol.strip[c] = struct{}{}
+		}
+	}
+
+	pol.compiled = true
+	return nil
+}
+
+// Apply applies pol to UPDATE m, marking it as modified if needed.
+// Does nothing if pol was not compiled successfully. Always returns true.
+// Implements pipe.CallbackFunc.
+func (pol *EgressPolicy) Apply(m *Msg) bool {
+	if !pol.compiled || m.Type != UPDATE || m.Upper != UPDATE {
+		return true
+	}
+
+	u := &m.Update
+	if k := u.Kind(); k != KIND_ANNOUNCE && k != KIND_MIXED {
+		return true
+	}
+
+	var modified bool
+	if pol.RemovePrivateAS && u.AsPath().HasPrivate() {
+		u.Attrs.Own(attrs.ATTR_ASPATH).(*attrs.Aspath).DropPrivate()
+		modified = true
+	}
+	if pol.prepend > 0 {
+		u.Attrs.Use(attrs.ATTR_ASPATH).(*attrs.Aspath).Prepend(pol.PrependASN, pol.prepend)
+		modified = true
+	}
+	if pol.applyNextHop(u) {
+		modified = true
+	}
+	if pol.applyCommunities(u) {
+		modified = true
+	}
+	if pol.ClearMED && u.Attrs.Has(attrs.ATTR_MED) {
+		u.Attrs.Drop(attrs.ATTR_MED)
+		modified = true
+	}
+	if pol.SetMED != nil {
+		u.Attrs.Use(attrs.ATTR_MED).(*attrs.U32).Val = *pol.SetMED
+		modified = true
+	}
+	if pol.SetLocalPref != nil {
+		u.Attrs.Use(attrs.ATTR_LOCALPREF).(*attrs.U32).Val = *pol.SetLocalPref
+		modified = true
+	}
+
+	if modified {
+		m.Modified()
+	}
+	return true
+}
+
+// applyNextHop rewrites the next-hops in u
+func (pol *EgressPolicy) applyNextHop(u *Update) (modified bool) {
+	if u.SetNextHop(pol.NextHopSelf4, netip.Addr{}) {
+		modified = true
+	}
+	if u.SetNextHop(pol.NextHopSelf6, netip.Addr{}) {
+		modified = true
+	}
+	return modified
+}
+
+// applyCommunities strips and adds communities in u
+func (pol *EgressPolicy) applyCommunities(u *Update) (modified bool) {
+	if pol.StripAllCommunities {
+		if u.Attrs.Has(attrs.ATTR_COMMUNITY) {
+			u.Attrs.Drop(attrs.ATTR_COMMUNITY)
+			modified = true
+		}
+	} else if len(pol.strip) == 0 {
+		// nothing to strip
+	} else if com, ok := u.Attrs.Own(attrs.ATTR_COMMUNITY).(*attrs.Community); ok {
+		j := 0
+		for i := range com.ASN {
+			if _, drop := pol.strip[uint32(com.ASN[i])<<16|uint32(com.Value[i])]; drop {
+				continue
+			}
+			com.ASN[j], com.Value[j] = com.ASN[i], com.Value[i]
+			j++
+		}
+		if j != len(com.ASN) {
+			com.ASN, com.Value = com.ASN[:j], com.Value[:j]
+			modified = true
+		}
+	}
+
+	if len(pol.AddCommunities) > 0 {
+		com := u.Attrs.Use(attrs.ATTR_COMMUNITY).(*attrs.Community)
+		for _, c := range pol.AddCommunities {
+			com.Add(uint16(c>>16), uint16(c))
+		}
+		com.Sort()
+		modified = true
+	}
+
+	// an empty COMMUNITY attribute is malformed
+	if com, ok := u.Attrs.Get(attrs.ATTR_COMMUNITY).(*attrs.Community); ok && len(com.ASN) == 0 {
+		u.Attrs.Drop(attrs.ATTR_COMMUNITY)
+		modified = true
+	}
+
+	return modified
+}
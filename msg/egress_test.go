@@ -0,0 +1,119 @@
+package msg
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/attrs"
+	"github.com/bgpfix/bgpfix/caps"
+)
+
+func TestEgressPolicy(t *testing.T) {
+	med, lp := uint32(10), uint32(200)
+	pol := EgressPolicy{
+		RemovePrivateAS:  true,
+		PrependASN:       65000,
+		PrependCount:     2,
+		NextHopSelf4:     netip.MustParseAddr("192.0.2.1"),
+		NextHopSelf6:     netip.MustParseAddr("2001:db8::1"),
+		StripCommunities: []uint32{666<<16 | 666},
+		AddCommunities:   []uint32{100<<16 | 1},
+		ClearMED:         true,
+		SetMED:           &med,
+		SetLocalPref:     &lp,
+	}
+	if err := pol.Compile(); err != nil {
+		t.Fatalf("Compile error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{
+			"ipv4",
+			`{"reach":["10.0.0.0/8"],"attrs":{"ASPATH":[64512,100,4200000001,200],"NEXTHOP":"198.51.100.1","MED":5,"COMMUNITY":["666:666","200:2"]}}`,
+			`{"reach":["10.0.0.0/8"],"attrs":{"ASPATH":{"flags":"T","value":[65000,65000,100,200]},"NEXTHOP":{"flags":"T","value":"192.0.2.1"},"MED":{"flags":"O","value":10},"LOCALPREF":{"flags":"T","value":200},"COMMUNITY":{"flags":"OT","value":["100:1","200:2"]}}}`,
+		},
+		{
+			"ipv6",
+			`{"attrs":{"ASPATH":[64513],"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::ff","link-local":"fe80::1","prefixes":["2001:db8:1::/48"]}}}}`,
+			`{"attrs":{"ASPATH":{"flags":"T","value":[65000,65000]},"MED":{"flags":"O","value":10},"LOCALPREF":{"flags":"T","value":200},"COMMUNITY":{"flags":"OT","value":["100:1"]},"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::1","prefixes":["2001:db8:1::/48"]}}}}`,
+		},
+		{
+			"withdraw",
+			`{"unreach":["10.0.0.0/8"]}`,
+			`{"unreach":["10.0.0.0/8"],"attrs":null}`,
+		},
+	}
+
+	var cps caps.Caps
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMsg()
+			m.Use(UPDATE)
+			if err := m.Update.FromJSON([]byte(tt.in)); err != nil {
+				t.Fatalf("FromJSON error = %v", err)
+			}
+			if !pol.Apply(m) {
+				t.Fatalf("Apply returned false")
+			}
+			if err := m.Marshal(cps); err != nil {
+				t.Fatalf("Marshal error = %v", err)
+			}
+			if got := string(m.Update.ToJSON(nil)); got != tt.out {
+				t.Errorf("got  %s\nwant %s", got, tt.out)
+			}
+		})
+	}
+}
+
+func TestEgressPolicy_Compile(t *testing.T) {
+	bad := []EgressPolicy{
+		{PrependCount: 1},
+		{PrependASN: 1, PrependCount: 256},
+		{NextHopSelf4: netip.MustParseAddr("2001:db8::1")},
+		{NextHopSelf6: netip.MustParseAddr("192.0.2.1")},
+	}
+	for i := range bad {
+		if err := bad[i].Compile(); err == nil {
+			t.Errorf("Compile(%d) error = nil, want error", i)
+		}
+	}
+
+	// not compiled: no-op
+	var pol EgressPolicy
+	pol.RemovePrivateAS = true
+	m := NewMsg()
+	m.Use(UPDATE)
+	if err := m.Update.FromJSON([]byte(`{"reach":["10.0.0.0/8"],"attrs":{"ASPATH":[64512]}}`)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	pol.Apply(m)
+	if got := m.Update.AsPath().Len(); got != 1 {
+		t.Errorf("uncompiled Apply changed AS_PATH length to %d", got)
+	}
+}
+
+func TestEgressPolicy_EmptyCommunity(t *testing.T) {
+	pol := EgressPolicy{StripCommunities: []uint32{666<<16 | 666}}
+	if err := pol.Compile(); err != nil {
+		t.Fatalf("Compile error = %v", err)
+	}
+
+	// an empty COMMUNITY, eg. left by another stage
+	m := NewMsg()
+	m.Use(UPDATE)
+	if err := m.Update.FromJSON([]byte(`{"reach":["10.0.0.0/8"],"attrs":{"ORIGIN":"IGP"}}`)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	m.Update.Attrs.Use(attrs.ATTR_COMMUNITY)
+
+	if !pol.applyCommunities(&m.Update) {
+		t.Errorf("applyCommunities = false, want true")
+	}
+	if m.Update.Attrs.Has(attrs.ATTR_COMMUNITY) {
+		t.Errorf("empty COMMUNITY not dropped")
+	}
+}
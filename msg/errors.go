@@ -1,6 +1,9 @@
 package msg
 
-import "errors"
+import (
+	"errors"
+	"slices"
+)
 
 var (
 	// generic errors
@@ -24,4 +27,25 @@ var (
 	ErrAttrs     = errors.New("invalid attributes")
 	ErrSegType   = errors.New("invalid segment type")
 	ErrSegLen    = errors.New("invalid segment length")
+	ErrMaxNLRI   = errors.New("too many prefixes")
 )
+
+// ParseError wraps a parse error for a specific part of a message,
+// eg. a path attribute or an NLRI block.
+type ParseError struct {
+	Err  error  // the underlying error
+	Data []byte // a copy of the offending bytes
+}
+
+// parseError returns err wrapped in ParseError, with a copy of data
+func parseError(err error, data []byte) error {
+	return &ParseError{Err: err, Data: slices.Clone(data)}
+}
+
+func (pe *ParseError) Error() string {
+	return pe.Err.Error()
+}
+
+func (pe *ParseError) Unwrap() error {
+	return pe.Err
+}
@@ -0,0 +1,140 @@
+package msg
+
+import (
+	"sync"
+
+	"github.com/bgpfix/bgpfix/attrs"
+	"github.com/bgpfix/bgpfix/caps"
+)
+
+// Interner deduplicates identical attribute sets parsed by Update.ParseAttrsWith,
+// see ParsePolicy.Intern. This can save a lot of memory when holding many UPDATEs,
+// eg. a full BGP table, where most prefixes share only a few thousand attribute sets.
+//
+// The attribute sets are fingerprinted by their wire representation, skipping
+// ATTR_MP_REACH and ATTR_MP_UNREACH, which are always parsed per message.
+// Interned values are shared between messages, see attrs.Attrs.Share.
+//
+// The number of interned sets is bounded by Max, using two generations: when the
+// current generation is full, it becomes the old one, and the previous old one is
+// dropped. Sets found in the old generation are moved back to the current one.
+// Dropped sets already shared with messages stay valid.
+//
+// Interner is safe for concurrent use. Use one Interner per ParsePolicy.
+type Interner struct {
+	Max int // max number of interned sets; if <= 0, use INTERN_MAX
+
+	mu  sync.Mutex
+	db  map[string]*attrs.Attrs // current generation: fingerprint -> parsed attributes
+	old map[string]*attrs.Attrs // previous generation
+}
+
+// INTERN_MAX is the default value of Interner.Max
+const INTERN_MAX = 1 << 20
+
+// NewInterner returns a new, empty Interner
+func NewInterner() *Interner {
+	return &Interner{db: make(map[string]*attrs.Attrs)}
+}
+
+// Len returns the number of interned attribute sets
+func (in *Interner) Len() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return len(in.db) + len(in.old)
+}
+
+// Reset drops all interned attribute sets.
+// Attribute sets already shared with messages stay valid.
+func (in *Interner) Reset() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	clear(in.db)
+	in.old = nil
+}
+
+// get returns the attribute set for fingerprint fp, or nil
+func (in *Interner) get(fp []byte) *attrs.Attrs {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if ats, ok := in.db[string(fp)]; ok {
+		return ats
+	} else if ats, ok := in.old[string(fp)]; ok {
+		delete(in.old, string(fp))
+		in.store(string(fp), ats)
+		return ats
+	}
+	return nil
+}
+
+// put stores ats under fingerprint fp and returns the interned set,
+// which may be different than ats if stored concurrently.
+func (in *Interner) put(fp []byte, ats *attrs.Attrs) *attrs.Attrs {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if old, ok := in.db[string(fp)]; ok {
+		return old
+	} else if old, ok := in.old[string(fp)]; ok {
+		delete(in.old, string(fp))
+		ats = old
+	}
+	in.store(string(fp), ats)
+	return ats
+}
+
+// store stores ats under fp in the current generation, rotating it first if full.
+// Must be called with in.mu locked.
+func (in *Interner) store(fp string, ats *attrs.Attrs) {
+	limit := in.Max
+	if limit <= 0 {
+		limit = INTERN_MAX
+	}
+
+	if in.db == nil {
+		in.db = make(map[string]*attrs.Attrs)
+	} else if len(in.db) >= max(limit/2, 1) {
+		in.old = in.db
+		in.db = make(map[string]*attrs.Attrs, len(in.old))
+	}
+	in.db[fp] = ats
+}
+
+// fingerprint returns the fingerprint of raw attributes, or nil if not possible.
+//
+// Of all capabilities, only AS4 changes how the fingerprinted attributes are parsed
+// (AS_PATH and AGGREGATOR). ADD_PATH changes only the NLRI encoding, and Extended
+// Next Hop only the ATTR_MP_REACH next-hop, which are never fingerprinted. Neither
+// depends on the message direction.
+func (in *Interner) fingerprint(raw []byte, cps caps.Caps) (fp []byte) {
+	// NB: the AS_PATH encoding depends on AS4
+	if cps.Has(caps.CAP_AS4) {
+		fp = append(fp, 4)
+	} else {
+		fp = append(fp, 2)
+	}
+
+	for len(raw) > 0 {
+		if len(raw) < 3 {
+			return nil
+		}
+
+		atyp := attrs.CodeFlags(msb.Uint16(raw[0:2]))
+		hlen, alen := 3, int(raw[2])
+		if atyp.HasFlags(attrs.ATTR_EXTENDED) {
+			if len(raw) < 4 {
+				return nil
+			}
+			hlen, alen = 4, int(msb.Uint16(raw[2:4]))
+		}
+		if len(raw) < hlen+alen {
+			return nil
+		}
+
+		if ac := atyp.Code(); ac != attrs.ATTR_MP_REACH && ac != attrs.ATTR_MP_UNREACH {
+			fp = append(fp, raw[:hlen+alen]...)
+		}
+		raw = raw[hlen+alen:]
+	}
+
+	return fp
+}
@@ -36,9 +36,11 @@ type Msg struct {
 
 	// upper layer
 
-	Upper  Type   // which of the upper layers is valid?
-	Open   Open   // BGP OPEN message
-	Update Update // BGP UPDATE message
+	Upper   Type    // which of the upper layers is valid?
+	Open    Open    // BGP OPEN message
+	Update  Update  // BGP UPDATE message
+	Notify  Notify  // BGP NOTIFICATION message
+	Refresh Refresh // BGP ROUTE-REFRESH message
 
 	// for optional use beyond this pkg, eg. to store pipe.Context
 
@@ -100,6 +102,8 @@ func NewMsg() *Msg {
 	msg := new(Msg)
 	msg.Open.Init(msg)
 	msg.Update.Init(msg)
+	msg.Notify.Init(msg)
+	msg.Refresh.Init(msg)
 	return msg
 }
 
@@ -123,6 +127,10 @@ func (msg *Msg) Reset() *Msg {
 		msg.Open.Reset()
 	case UPDATE:
 		msg.Update.Reset()
+	case NOTIFY:
+		msg.Notify.Reset()
+	case REFRESH:
+		msg.Refresh.Reset()
 	}
 	msg.Upper = INVALID
 
@@ -190,6 +198,43 @@ func (msg *Msg) CopyData() *Msg {
 	return msg
 }
 
+// Clone returns a deep copy of msg, sharing no memory with it, eg. to safely
+// fan out a message to many consumers that may modify it (see also CopyData).
+// The UPDATE upper layer is copied directly (see Update.Clone), while other upper layers
+// are copied through their JSON representation. msg.Value is copied by reference.
+func (msg *Msg) Clone() *Msg {
+	m2 := NewMsg()
+	m2.Dir = msg.Dir
+	m2.Seq = msg.Seq
+	m2.Time = msg.Time
+	m2.Type = msg.Type
+	m2.Value = msg.Value
+
+	// upper layer
+	var err error
+	switch m2.Upper = msg.Upper; m2.Upper {
+	case OPEN:
+		err = m2.Open.FromJSON(msg.Open.ToJSON(nil))
+	case UPDATE:
+		msg.Update.cloneTo(&m2.Update)
+	case NOTIFY:
+		err = m2.Notify.FromJSON(msg.Notify.ToJSON(nil))
+	case REFRESH:
+		err = m2.Refresh.FromJSON(msg.Refresh.ToJSON(nil))
+	}
+
+	// raw data
+	if msg.Data != nil {
+		m2.buf = append(make([]byte, 0, len(msg.Data)), msg.Data...)
+		m2.Data = m2.buf
+		if err != nil {
+			m2.Upper = INVALID // will need to parse again
+		}
+	}
+
+	return m2
+}
+
 // FromBytes reads one BGP message from buf, referencing buf data inside msg.Data.
 // If needed, call CopyData(), DropData() or Reset() later to remove the reference.
 // Returns the number of bytes read from buf, which can be less than len(buf).
@@ -235,8 +280,14 @@ func (msg *Msg) FromBytes(buf []byte) (off int, err error) {
 
 // Parse parses msg.Data into the upper layer iff needed.
 // Capabilities in caps can infuence the upper layer decoders.
-// Does not reference data in msg.Data.
+// Does not reference data in msg.Data. Uses StrictPolicy for UPDATEs, see ParseWith.
 func (msg *Msg) Parse(cps caps.Caps) error {
+	return msg.ParseWith(cps, nil)
+}
+
+// ParseWith is like Parse, but uses given parse policy pol for UPDATEs
+// (nil means StrictPolicy).
+func (msg *Msg) ParseWith(cps caps.Caps, pol *ParsePolicy) error {
 	if msg.Upper != INVALID {
 		return nil // assume already done
 	} else if msg.Data == nil {
@@ -254,17 +305,19 @@ func (msg *Msg) Parse(cps caps.Caps) error {
 		err = o.ParseCaps()
 	case UPDATE:
 		u := &msg.Update
-		err = u.Parse(cps)
+		err = u.ParseWith(cps, pol)
 		if err != nil {
 			break
 		}
-		err = u.ParseAttrs(cps)
+		err = u.ParseAttrsWith(cps, pol)
 	case KEEPALIVE:
 		if len(msg.Data) != 0 {
 			err = ErrLength
 		}
-	case NOTIFY, REFRESH:
-		// err = ErrTODO // TODO
+	case NOTIFY:
+		err = msg.Notify.Parse()
+	case REFRESH:
+		err = msg.Refresh.Parse()
 	default:
 		err = ErrType
 	}
@@ -302,6 +355,10 @@ func (msg *Msg) Marshal(cps caps.Caps) error {
 			break
 		}
 		err = u.Marshal(cps)
+	case NOTIFY:
+		err = msg.Notify.Marshal()
+	case REFRESH:
+		err = msg.Refresh.Marshal()
 	case KEEPALIVE:
 		if msg.buf == nil {
 			msg.buf = make([]byte, 0)
@@ -318,9 +375,30 @@ func (msg *Msg) Marshal(cps caps.Caps) error {
 	return err
 }
 
+// MaxLen returns the maximum BGP message length for capabilities in cps,
+// which is MAXLEN_EXT iff caps.CAP_EXTENDED_MESSAGE is present (rfc8654).
+func MaxLen(cps caps.Caps) int {
+	if cps.Has(caps.CAP_EXTENDED_MESSAGE) {
+		return MAXLEN_EXT
+	} else {
+		return MAXLEN
+	}
+}
+
 // WriteTo writes raw BGP msg.Data to w, implementing io.WriterTo.
-// Call msg.Marshal() first if needed.
+// Call msg.Marshal() first if needed. Messages longer than MAXLEN
+// are rejected, use WriteToCaps() for the Extended Message support.
 func (msg *Msg) WriteTo(w io.Writer) (n int64, err error) {
+	return msg.writeTo(w, MAXLEN)
+}
+
+// WriteToCaps is like WriteTo, but allows messages up to MaxLen(cps)
+func (msg *Msg) WriteToCaps(w io.Writer, cps caps.Caps) (n int64, err error) {
+	return msg.writeTo(w, MaxLen(cps))
+}
+
+// writeTo writes raw BGP msg.Data to w, allowing up to maxlen bytes
+func (msg *Msg) writeTo(w io.Writer, maxlen int) (n int64, err error) {
 	var m int
 
 	// has data?
@@ -330,7 +408,7 @@ func (msg *Msg) WriteTo(w io.Writer) (n int64, err error) {
 
 	// data length ok?
 	l := msg.Len()
-	if l < HEADLEN || l > MAXLEN {
+	if l < HEADLEN || l > maxlen {
 		return 0, ErrLength
 	}
 
@@ -416,9 +494,9 @@ func (msg *Msg) GetJSON() []byte {
 	case KEEPALIVE:
 		dst = append(dst, json.Null...)
 	case NOTIFY:
-		dst = append(dst, '"')
-		dst = json.Ascii(dst, msg.Data[2:]) // FIXME
-		dst = append(dst, '"')
+		dst = msg.Notify.ToJSON(dst)
+	case REFRESH:
+		dst = msg.Refresh.ToJSON(dst)
 	default:
 		dst = json.Hex(dst, msg.Data)
 	}
@@ -491,6 +569,10 @@ func (msg *Msg) FromJSON(src []byte) (reterr error) {
 					err = msg.Open.FromJSON(val)
 				case UPDATE:
 					err = msg.Update.FromJSON(val)
+				case NOTIFY:
+					err = msg.Notify.FromJSON(val)
+				case REFRESH:
+					err = msg.Refresh.FromJSON(val)
 				default:
 					err = ErrTODO // TODO
 				}
@@ -3,8 +3,11 @@ package msg
 import (
 	"bytes"
 	"io"
+	"net/netip"
 	"testing"
 
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/nlri"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -84,3 +87,44 @@ func TestMsg_Parse(t *testing.T) {
 		})
 	}
 }
+
+func TestMsg_ExtendedMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	var std, ext caps.Caps
+	ext.Use(caps.CAP_EXTENDED_MESSAGE)
+
+	// an UPDATE with 1000 x /32 prefixes, ie. ~5000 bytes
+	m := NewMsg().Use(UPDATE)
+	for i := range 1000 {
+		addr := netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)})
+		m.Update.Reach = append(m.Update.Reach, nlri.FromPrefix(netip.PrefixFrom(addr, 32)))
+	}
+
+	// too long without the capability
+	assert.ErrorIs(m.Marshal(std), ErrLength)
+	assert.Nil(m.Data)
+
+	// ok with the capability
+	assert.NoError(m.Marshal(ext))
+	assert.Equal(5004, len(m.Data))
+
+	var bb bytes.Buffer
+	_, err := m.WriteTo(&bb)
+	assert.ErrorIs(err, ErrLength)
+	_, err = m.WriteToCaps(&bb, std)
+	assert.ErrorIs(err, ErrLength)
+	assert.Zero(bb.Len())
+
+	n, err := m.WriteToCaps(&bb, ext)
+	assert.NoError(err)
+	assert.EqualValues(HEADLEN+5004, n)
+
+	// read back
+	m2 := NewMsg()
+	off, err := m2.FromBytes(bb.Bytes())
+	assert.NoError(err)
+	assert.Equal(bb.Len(), off)
+	assert.NoError(m2.Parse(ext))
+	assert.Len(m2.Update.Reach, 1000)
+}
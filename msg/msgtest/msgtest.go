@@ -0,0 +1,138 @@
+// Package msgtest provides builders of canonical BGP messages for use in tests.
+//
+// All builders return a new msg.Msg with its upper layer set and marshaled
+// to msg.Data (with 4-byte ASN support), ready to be written or parsed again.
+// Invalid arguments are programming errors, so the builders panic on them,
+// similar to net/http/httptest.
+//
+// The package is meant to be imported from _test.go files only.
+package msgtest
+
+import (
+	"bytes"
+	"net/netip"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/attrs"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/nlri"
+)
+
+// Caps are the capabilities used for marshaling messages: only CAP_AS4
+var Caps caps.Caps
+
+func init() {
+	Caps.Use(caps.CAP_AS4)
+}
+
+// Open returns a new OPEN message for given ASN, hold time, and router id,
+// with CAP_AS4 and capabilities in cps.
+func Open(asn int, hold uint16, id string, cps ...caps.Cap) *msg.Msg {
+	m := msg.NewMsg().Use(msg.OPEN)
+	o := &m.Open
+	o.HoldTime = hold
+	o.Identifier = netip.MustParseAddr(id)
+	o.SetASN(asn)
+	for _, c := range cps {
+		must(o.AddCap(c))
+	}
+	return marshal(m)
+}
+
+// Keepalive returns a new KEEPALIVE message
+func Keepalive() *msg.Msg {
+	return marshal(msg.NewMsg().Use(msg.KEEPALIVE))
+}
+
+// Update returns a new UPDATE message from its JSON representation in js,
+// eg. `{"reach":["10.0.0.0/8"],"attrs":{"ORIGIN":"IGP","ASPATH":[65001],"NEXTHOP":"192.0.2.1"}}`.
+func Update(js string) *msg.Msg {
+	m := msg.NewMsg().Use(msg.UPDATE)
+	must(m.Update.FromJSON([]byte(js)))
+	return marshal(m)
+}
+
+// Announce returns a new UPDATE message announcing given prefixes via next-hop nh,
+// with ORIGIN IGP and AS_PATH aspath. The prefixes must be all IPv4 or all IPv6.
+// IPv6 prefixes go in ATTR_MP_REACH.
+func Announce(nh string, aspath []uint32, prefixes ...string) *msg.Msg {
+	m := msg.NewMsg().Use(msg.UPDATE)
+	u := &m.Update
+
+	as, pfx := parse(prefixes)
+	must(u.AddReachAF(as, netip.MustParseAddr(nh), pfx...))
+
+	u.Attrs.Use(attrs.ATTR_ORIGIN)
+	ap := u.Attrs.Use(attrs.ATTR_ASPATH).(*attrs.Aspath)
+	if len(aspath) > 0 {
+		ap.Segments = append(ap.Segments, attrs.AspathSegment{List: aspath})
+	}
+
+	return marshal(m)
+}
+
+// Withdraw returns a new UPDATE message withdrawing given prefixes.
+// The prefixes must be all IPv4 or all IPv6. IPv6 prefixes go in ATTR_MP_UNREACH.
+func Withdraw(prefixes ...string) *msg.Msg {
+	m := msg.NewMsg().Use(msg.UPDATE)
+	as, pfx := parse(prefixes)
+	must(m.Update.AddUnreachAF(as, pfx...))
+	return marshal(m)
+}
+
+// EoR returns a new End-of-RIB marker for address family as, rfc4724/2
+func EoR(as afi.AS) *msg.Msg {
+	m := msg.NewMsg().Use(msg.UPDATE)
+	if as != afi.AS_IPV4_UNICAST {
+		must(m.Update.AddUnreachAF(as))
+	}
+	return marshal(m)
+}
+
+// Notify returns a new NOTIFICATION message with given error code, subcode, and data
+func Notify(code, subcode byte, data []byte) *msg.Msg {
+	return msg.NewNotify(code, subcode, data)
+}
+
+// Refresh returns a new ROUTE-REFRESH message for address family as,
+// with given message subtype (0 for a normal request, see rfc7313).
+func Refresh(as afi.AS, subtype byte) *msg.Msg {
+	return msg.NewRefresh(as.Afi(), as.Safi(), subtype)
+}
+
+// Bytes returns the wire representation of m, including the BGP header
+func Bytes(m *msg.Msg) []byte {
+	var bb bytes.Buffer
+	_, err := m.WriteTo(&bb)
+	must(err)
+	return bb.Bytes()
+}
+
+// parse parses IP prefixes, returning their address family
+func parse(prefixes []string) (as afi.AS, dst []nlri.NLRI) {
+	as = afi.AS_IPV4_UNICAST
+	for i, s := range prefixes {
+		p := netip.MustParsePrefix(s)
+		if i == 0 && p.Addr().Is6() {
+			as = afi.AS_IPV6_UNICAST
+		} else if p.Addr().Is6() != (as == afi.AS_IPV6_UNICAST) {
+			panic("msgtest: mixed IPv4 and IPv6 prefixes")
+		}
+		dst = append(dst, nlri.FromPrefix(p))
+	}
+	return
+}
+
+// marshal marshals m, panicking on error
+func marshal(m *msg.Msg) *msg.Msg {
+	must(m.Marshal(Caps))
+	return m
+}
+
+// must panics if err is non-nil
+func must(err error) {
+	if err != nil {
+		panic("msgtest: " + err.Error())
+	}
+}
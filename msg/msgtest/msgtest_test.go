@@ -0,0 +1,53 @@
+package msgtest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/msg"
+)
+
+func TestBuilders(t *testing.T) {
+	tests := []struct {
+		name string
+		m    *msg.Msg
+		json string
+	}{
+		{"open", Open(4200000001, 90, "192.0.2.1", &caps.Role{Role: caps.ROLE_PEER}),
+			`{"bgp":4,"asn":23456,"id":"192.0.2.1","hold":90,"caps":{"ROLE":"peer","AS4":4200000001}}`},
+		{"keepalive", Keepalive(), `"KEEPALIVE",null`},
+		{"update", Update(`{"reach":["10.0.0.0/8"],"attrs":{"NEXTHOP":"192.0.2.1"}}`),
+			`{"reach":["10.0.0.0/8"],"attrs":{"NEXTHOP":{"flags":"T","value":"192.0.2.1"}}}`},
+		{"announce", Announce("192.0.2.1", []uint32{65001, 65002}, "10.0.0.0/8", "192.168.0.0/16"),
+			`{"reach":["10.0.0.0/8","192.168.0.0/16"],"attrs":{"ORIGIN":{"flags":"T","value":"IGP"},"ASPATH":{"flags":"T","value":[65001,65002]},"NEXTHOP":{"flags":"T","value":"192.0.2.1"}}}`},
+		{"announce6", Announce("2001:db8::1", nil, "2001:db8:1::/48"),
+			`{"attrs":{"ORIGIN":{"flags":"T","value":"IGP"},"ASPATH":{"flags":"T","value":[]},"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::1","prefixes":["2001:db8:1::/48"]}}}}`},
+		{"withdraw", Withdraw("10.0.0.0/8"), `{"unreach":["10.0.0.0/8"],"attrs":{}}`},
+		{"eor6", EoR(afi.AS_IPV6_UNICAST),
+			`{"attrs":{"MP_UNREACH":{"flags":"O","value":{"af":"IPV6/UNICAST","prefixes":[]}}}}`},
+		{"notify", Notify(6, 2, nil), `{"code":"CEASE","subcode":"ADMIN_SHUTDOWN"}`},
+		{"refresh", Refresh(afi.AS_IPV6_UNICAST, 0), `{"af":"IPV6/UNICAST","subtype":"REQUEST"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := msg.NewMsg()
+			buf := Bytes(tt.m)
+			if n, err := m.FromBytes(buf); err != nil || n != len(buf) {
+				t.Fatalf("FromBytes = %d, %v", n, err)
+			}
+			if m.Type != tt.m.Type {
+				t.Errorf("Type = %s, want %s", m.Type, tt.m.Type)
+			}
+			if err := m.Parse(Caps); err != nil {
+				t.Fatalf("Parse error = %v", err)
+			}
+
+			if js := m.GetJSON(); !bytes.Contains(js, []byte(tt.json)) {
+				t.Errorf("JSON = %s, want %s", js, tt.json)
+			}
+		})
+	}
+}
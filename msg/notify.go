@@ -0,0 +1,330 @@
+package msg
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/bgpfix/bgpfix/json"
+)
+
+// Notify represents a BGP NOTIFICATION message
+type Notify struct {
+	Msg *Msg // parent BGP message
+
+	Code    byte   // error code, eg. NOTIFY_CEASE
+	Subcode byte   // error subcode, eg. CEASE_ADMIN_SHUTDOWN
+	Data    []byte // data, depending on Code and Subcode
+
+	Message string // shutdown communication decoded from Data, if any (rfc9003)
+}
+
+const (
+	NOTIFY_MINLEN = 2 // error code + subcode, rfc4271/4.5
+)
+
+// NOTIFICATION error codes, rfc4271/4.5
+const (
+	NOTIFY_HEADER          byte = 1 // Message Header Error
+	NOTIFY_OPEN            byte = 2 // OPEN Message Error
+	NOTIFY_UPDATE          byte = 3 // UPDATE Message Error
+	NOTIFY_HOLD_TIMER      byte = 4 // Hold Timer Expired
+	NOTIFY_FSM             byte = 5 // Finite State Machine Error
+	NOTIFY_CEASE           byte = 6 // Cease
+	NOTIFY_REFRESH         byte = 7 // ROUTE-REFRESH Message Error, rfc7313
+	NOTIFY_SEND_HOLD_TIMER byte = 8 // Send Hold Timer Expired, rfc9687
+)
+
+// Message Header Error subcodes, rfc4271/6.1
+const (
+	HEADER_CONN_NOT_SYNCED byte = 1
+	HEADER_BAD_LENGTH      byte = 2
+	HEADER_BAD_TYPE        byte = 3
+)
+
+// OPEN Message Error subcodes, rfc4271/6.2
+const (
+	OPEN_UNSUPPORTED_VERSION byte = 1
+	OPEN_BAD_PEER_AS         byte = 2
+	OPEN_BAD_BGP_ID          byte = 3
+	OPEN_UNSUPPORTED_PARAM   byte = 4
+	OPEN_BAD_HOLD_TIME       byte = 6
+	OPEN_UNSUPPORTED_CAP     byte = 7  // rfc5492
+	OPEN_ROLE_MISMATCH       byte = 11 // rfc9234
+)
+
+// UPDATE Message Error subcodes, rfc4271/6.3
+const (
+	UPDATE_MALFORMED_ATTRS    byte = 1
+	UPDATE_UNKNOWN_WELL_KNOWN byte = 2
+	UPDATE_MISSING_WELL_KNOWN byte = 3
+	UPDATE_ATTR_FLAGS         byte = 4
+	UPDATE_ATTR_LENGTH        byte = 5
+	UPDATE_INVALID_ORIGIN     byte = 6
+	UPDATE_INVALID_NEXTHOP    byte = 8
+	UPDATE_OPTIONAL_ATTR      byte = 9
+	UPDATE_INVALID_NETWORK    byte = 10
+	UPDATE_MALFORMED_ASPATH   byte = 11
+)
+
+// Finite State Machine Error subcodes, rfc6608
+const (
+	FSM_UNEXPECTED_OPENSENT    byte = 1
+	FSM_UNEXPECTED_OPENCONFIRM byte = 2
+	FSM_UNEXPECTED_ESTABLISHED byte = 3
+)
+
+// Cease subcodes, rfc4486
+const (
+	CEASE_MAX_PREFIX        byte = 1
+	CEASE_ADMIN_SHUTDOWN    byte = 2
+	CEASE_PEER_DECONFIGURED byte = 3
+	CEASE_ADMIN_RESET       byte = 4
+	CEASE_CONN_REJECTED     byte = 5
+	CEASE_CONFIG_CHANGE     byte = 6
+	CEASE_CONN_COLLISION    byte = 7
+	CEASE_OUT_OF_RESOURCES  byte = 8
+	CEASE_HARD_RESET        byte = 9  // rfc8538
+	CEASE_BFD_DOWN          byte = 10 // rfc9384
+)
+
+// ROUTE-REFRESH Message Error subcodes, rfc7313/5
+const (
+	REFRESH_INVALID_LENGTH byte = 1
+)
+
+// NotifyCodeName maps NOTIFICATION error codes to their names
+var NotifyCodeName = map[byte]string{
+	NOTIFY_HEADER:          "HEADER",
+	NOTIFY_OPEN:            "OPEN",
+	NOTIFY_UPDATE:          "UPDATE",
+	NOTIFY_HOLD_TIMER:      "HOLD_TIMER",
+	NOTIFY_FSM:             "FSM",
+	NOTIFY_CEASE:           "CEASE",
+	NOTIFY_REFRESH:         "REFRESH",
+	NOTIFY_SEND_HOLD_TIMER: "SEND_HOLD_TIMER",
+}
+
+// NotifySubcodeName maps NOTIFICATION error codes and subcodes to subcode names
+var NotifySubcodeName = map[byte]map[byte]string{
+	NOTIFY_HEADER: {
+		HEADER_CONN_NOT_SYNCED: "CONN_NOT_SYNCED",
+		HEADER_BAD_LENGTH:      "BAD_LENGTH",
+		HEADER_BAD_TYPE:        "BAD_TYPE",
+	},
+	NOTIFY_OPEN: {
+		OPEN_UNSUPPORTED_VERSION: "UNSUPPORTED_VERSION",
+		OPEN_BAD_PEER_AS:         "BAD_PEER_AS",
+		OPEN_BAD_BGP_ID:          "BAD_BGP_ID",
+		OPEN_UNSUPPORTED_PARAM:   "UNSUPPORTED_PARAM",
+		OPEN_BAD_HOLD_TIME:       "BAD_HOLD_TIME",
+		OPEN_UNSUPPORTED_CAP:     "UNSUPPORTED_CAP",
+		OPEN_ROLE_MISMATCH:       "ROLE_MISMATCH",
+	},
+	NOTIFY_UPDATE: {
+		UPDATE_MALFORMED_ATTRS:    "MALFORMED_ATTRS",
+		UPDATE_UNKNOWN_WELL_KNOWN: "UNKNOWN_WELL_KNOWN",
+		UPDATE_MISSING_WELL_KNOWN: "MISSING_WELL_KNOWN",
+		UPDATE_ATTR_FLAGS:         "ATTR_FLAGS",
+		UPDATE_ATTR_LENGTH:        "ATTR_LENGTH",
+		UPDATE_INVALID_ORIGIN:     "INVALID_ORIGIN",
+		UPDATE_INVALID_NEXTHOP:    "INVALID_NEXTHOP",
+		UPDATE_OPTIONAL_ATTR:      "OPTIONAL_ATTR",
+		UPDATE_INVALID_NETWORK:    "INVALID_NETWORK",
+		UPDATE_MALFORMED_ASPATH:   "MALFORMED_ASPATH",
+	},
+	NOTIFY_FSM: {
+		FSM_UNEXPECTED_OPENSENT:    "UNEXPECTED_OPENSENT",
+		FSM_UNEXPECTED_OPENCONFIRM: "UNEXPECTED_OPENCONFIRM",
+		FSM_UNEXPECTED_ESTABLISHED: "UNEXPECTED_ESTABLISHED",
+	},
+	NOTIFY_CEASE: {
+		CEASE_MAX_PREFIX:        "MAX_PREFIX",
+		CEASE_ADMIN_SHUTDOWN:    "ADMIN_SHUTDOWN",
+		CEASE_PEER_DECONFIGURED: "PEER_DECONFIGURED",
+		CEASE_ADMIN_RESET:       "ADMIN_RESET",
+		CEASE_CONN_REJECTED:     "CONN_REJECTED",
+		CEASE_CONFIG_CHANGE:     "CONFIG_CHANGE",
+		CEASE_CONN_COLLISION:    "CONN_COLLISION",
+		CEASE_OUT_OF_RESOURCES:  "OUT_OF_RESOURCES",
+		CEASE_HARD_RESET:        "HARD_RESET",
+		CEASE_BFD_DOWN:          "BFD_DOWN",
+	},
+	NOTIFY_REFRESH: {
+		REFRESH_INVALID_LENGTH: "INVALID_LENGTH",
+	},
+}
+
+// NewNotify returns a new NOTIFICATION message with given error code, subcode, and data,
+// marshaled and ready to be sent. See Notify.SetShutdownMessage to attach a shutdown communication.
+func NewNotify(code, subcode byte, data []byte) *Msg {
+	m := NewMsg().Use(NOTIFY)
+	n := &m.Notify
+	n.Code = code
+	n.Subcode = subcode
+	n.Data = append(n.Data, data...)
+	n.Marshal() // NB: can't fail without n.Message
+	return m
+}
+
+// Init initializes n to use parent m
+func (n *Notify) Init(m *Msg) {
+	n.Msg = m
+}
+
+// Reset prepares n for re-use
+func (n *Notify) Reset() {
+	n.Code = 0
+	n.Subcode = 0
+	n.Data = nil
+	n.Message = ""
+}
+
+// Parse parses n.Msg.Data as BGP NOTIFICATION.
+// Does not reference data in n.Msg.Data.
+func (n *Notify) Parse() error {
+	buf := n.Msg.Data
+	if len(buf) < NOTIFY_MINLEN {
+		return ErrShort
+	}
+
+	n.Code = buf[0]
+	n.Subcode = buf[1]
+	n.Data = append(n.Data[:0], buf[2:]...)
+	n.Message, _ = n.ShutdownMessage()
+	return nil
+}
+
+// ShutdownMessage returns the shutdown communication in n.Data and true,
+// iff n is an Administrative Shutdown or Reset with a valid message (rfc9003/2).
+func (n *Notify) ShutdownMessage() (string, bool) {
+	if n.Code != NOTIFY_CEASE || (n.Subcode != CEASE_ADMIN_SHUTDOWN && n.Subcode != CEASE_ADMIN_RESET) {
+		return "", false
+	} else if len(n.Data) < 1 || len(n.Data) < 1+int(n.Data[0]) {
+		return "", false
+	} else if msg := n.Data[1 : 1+n.Data[0]]; !utf8.Valid(msg) {
+		return "", false
+	} else {
+		return string(msg), len(msg) > 0
+	}
+}
+
+// SetShutdownMessage sets n.Data to the shutdown communication s (rfc9003/2),
+// which must be valid UTF-8 of at most 255 bytes, and marks the message as modified.
+// Does not check n.Code and n.Subcode, which should be an Administrative Shutdown or Reset.
+func (n *Notify) SetShutdownMessage(s string) error {
+	if len(s) > 255 {
+		return fmt.Errorf("SetShutdownMessage: %w (%d)", ErrLong, len(s))
+	} else if !utf8.ValidString(s) {
+		return fmt.Errorf("SetShutdownMessage: %w: invalid UTF-8", ErrValue)
+	}
+
+	n.Data = append(n.Data[:0], byte(len(s)))
+	n.Data = append(n.Data, s...)
+	n.Message = s
+	n.Msg.Modified()
+	return nil
+}
+
+// Marshal marshals n to n.Msg.Data.
+// If n.Data is empty, n.Message is encoded as the shutdown communication (rfc9003/2).
+func (n *Notify) Marshal() error {
+	if len(n.Data) == 0 && n.Message != "" {
+		if len(n.Message) > 255 {
+			return fmt.Errorf("Marshal: Message: %w (%d)", ErrLong, len(n.Message))
+		}
+		n.Data = append(n.Data, byte(len(n.Message)))
+		n.Data = append(n.Data, n.Message...)
+	}
+
+	msg := n.Msg
+	buf := msg.buf[:0]
+	buf = append(buf, n.Code, n.Subcode)
+	buf = append(buf, n.Data...)
+
+	msg.Type = NOTIFY
+	msg.Upper = NOTIFY
+	msg.buf = buf
+	msg.Data = buf
+	msg.ref = false
+	return nil
+}
+
+// String dumps n to JSON
+func (n *Notify) String() string {
+	return string(n.ToJSON(nil))
+}
+
+// ToJSON appends JSON representation of n to dst
+func (n *Notify) ToJSON(dst []byte) []byte {
+	dst = append(dst, `{"code":`...)
+	dst = nameToJSON(dst, n.Code, NotifyCodeName)
+
+	dst = append(dst, `,"subcode":`...)
+	dst = nameToJSON(dst, n.Subcode, NotifySubcodeName[n.Code])
+
+	if n.Message != "" {
+		dst = append(dst, `,"message":"`...)
+		dst = json.Utf8(dst, n.Message)
+		dst = append(dst, '"')
+	}
+
+	if len(n.Data) > 0 {
+		dst = append(dst, `,"data":`...)
+		dst = json.Hex(dst, n.Data)
+	}
+
+	return append(dst, '}')
+}
+
+// FromJSON reads n JSON representation from src
+func (n *Notify) FromJSON(src []byte) error {
+	n.Reset()
+
+	var subcode []byte // NB: its name depends on code
+	err := json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "code":
+			n.Code, err = unName(val, NotifyCodeName)
+		case "subcode":
+			subcode = val
+		case "message":
+			n.Message, err = json.UnString(val)
+		case "data":
+			n.Data, err = json.UnHex(val, nil)
+		}
+		return
+	})
+	if err != nil {
+		return err
+	}
+
+	if subcode != nil {
+		n.Subcode, err = unName(subcode, NotifySubcodeName[n.Code])
+	}
+	return err
+}
+
+// unName parses a byte value in src, given by number or its name in names
+func unName(src []byte, names map[byte]string) (byte, error) {
+	s := json.SQ(src)
+	for v, name := range names {
+		if name == s {
+			return v, nil
+		}
+	}
+	v, err := strconv.ParseUint(s, 0, 8)
+	if err != nil {
+		return 0, ErrValue
+	}
+	return byte(v), nil
+}
+
+// nameToJSON appends the name of v in names to dst, or v as a number if not found
+func nameToJSON(dst []byte, v byte, names map[byte]string) []byte {
+	if name, ok := names[v]; ok {
+		return strconv.AppendQuote(dst, name)
+	} else {
+		return json.Byte(dst, v)
+	}
+}
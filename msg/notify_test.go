@@ -0,0 +1,190 @@
+package msg
+
+import (
+	stdjson "encoding/json"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotify_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+		code    byte
+		subcode byte
+		message string
+		json    string
+	}{
+		{
+			name: "hold timer",
+			data: []byte{4, 0},
+			code: NOTIFY_HOLD_TIMER,
+			json: `{"code":"HOLD_TIMER","subcode":0}`,
+		},
+		{
+			name:    "bad peer as",
+			data:    []byte{2, 2, 0xfd, 0xe8},
+			code:    NOTIFY_OPEN,
+			subcode: OPEN_BAD_PEER_AS,
+			json:    `{"code":"OPEN","subcode":"BAD_PEER_AS","data":"0xfde8"}`,
+		},
+		{
+			name:    "admin shutdown",
+			data:    []byte{6, 2, 5, 'b', 'y', 'e', '!', '!'},
+			code:    NOTIFY_CEASE,
+			subcode: CEASE_ADMIN_SHUTDOWN,
+			message: "bye!!",
+			json:    `{"code":"CEASE","subcode":"ADMIN_SHUTDOWN","message":"bye!!","data":"0x056279652121"}`,
+		},
+		{
+			name:    "admin reset, truncated message",
+			data:    []byte{6, 4, 10, 'x'},
+			code:    NOTIFY_CEASE,
+			subcode: CEASE_ADMIN_RESET,
+			json:    `{"code":"CEASE","subcode":"ADMIN_RESET","data":"0x0a78"}`,
+		},
+		{
+			name:    "unknown code",
+			data:    []byte{99, 1},
+			code:    99,
+			subcode: 1,
+			json:    `{"code":99,"subcode":1}`,
+		},
+		{
+			name:    "too short",
+			data:    []byte{6},
+			wantErr: ErrShort,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMsg()
+			m.Dir = dir.DIR_L
+			m.Type = NOTIFY
+			m.Data = tt.data
+			err := m.Parse(caps.Caps{})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			n := &m.Notify
+			assert.Equal(t, tt.code, n.Code)
+			assert.Equal(t, tt.subcode, n.Subcode)
+			assert.Equal(t, tt.message, n.Message)
+			assert.Equal(t, tt.json, n.String())
+
+			// JSON round-trip
+			m2 := NewMsg()
+			assert.NoError(t, m2.FromJSON([]byte(m.String())))
+			assert.NoError(t, m2.Marshal(caps.Caps{}))
+			assert.Equal(t, tt.data, m2.Data)
+		})
+	}
+}
+
+func TestNotify_Marshal(t *testing.T) {
+	assert := assert.New(t)
+
+	// shutdown communication given by message only, subcode given by number
+	m := NewMsg()
+	assert.NoError(m.FromJSON([]byte(`["L",1,"",-1,"NOTIFY",{"subcode":2,"code":"CEASE","message":"maintenance"}]`)))
+	assert.NoError(m.Marshal(caps.Caps{}))
+	assert.Equal(append([]byte{6, 2, 11}, "maintenance"...), m.Data)
+
+	// parse back
+	m2 := NewMsg()
+	m2.Type = NOTIFY
+	m2.Data = m.Data
+	assert.NoError(m2.Parse(caps.Caps{}))
+	msg, ok := m2.Notify.ShutdownMessage()
+	assert.True(ok)
+	assert.Equal("maintenance", msg)
+
+	// subcode name given before code
+	m.Reset()
+	assert.NoError(m.FromJSON([]byte(`["L",1,"",-1,"NOTIFY",{"subcode":"HARD_RESET","code":6}]`)))
+	assert.NoError(m.Marshal(caps.Caps{}))
+	assert.Equal([]byte{NOTIFY_CEASE, CEASE_HARD_RESET}, m.Data)
+
+	// invalid names
+	m.Reset()
+	assert.ErrorIs(m.FromJSON([]byte(`["L",1,"",-1,"NOTIFY",{"code":"CEASE","subcode":"BAD_PEER_AS"}]`)), ErrValue)
+
+	// message too long
+	m.Reset()
+	n := &m.Use(NOTIFY).Notify
+	n.Code, n.Subcode = NOTIFY_CEASE, CEASE_ADMIN_SHUTDOWN
+	for range 256 {
+		n.Message += "x"
+	}
+	assert.ErrorIs(m.Marshal(caps.Caps{}), ErrLong)
+}
+
+func TestNewNotify(t *testing.T) {
+	assert := assert.New(t)
+
+	// plain Cease
+	m := NewNotify(NOTIFY_CEASE, CEASE_MAX_PREFIX, []byte{0, 1, 1, 0, 0, 0, 100})
+	assert.Equal(NOTIFY, m.Type)
+	assert.Equal([]byte{6, 1, 0, 1, 1, 0, 0, 0, 100}, m.Data)
+
+	// admin shutdown with a reason
+	m = NewNotify(NOTIFY_CEASE, CEASE_ADMIN_SHUTDOWN, nil)
+	assert.NoError(m.Notify.SetShutdownMessage("planned maintenance, back at 10:00 UTC ✓"))
+	assert.Nil(m.Data)
+	assert.NoError(m.Marshal(caps.Caps{}))
+	assert.Len(m.Data, 2+1+len("planned maintenance, back at 10:00 UTC ✓"))
+
+	// parse it back
+	m2 := NewMsg()
+	m2.Type = NOTIFY
+	m2.Data = m.Data
+	assert.NoError(m2.Parse(caps.Caps{}))
+	n := &m2.Notify
+	assert.Equal(NOTIFY_CEASE, n.Code)
+	assert.Equal(CEASE_ADMIN_SHUTDOWN, n.Subcode)
+	assert.Equal("planned maintenance, back at 10:00 UTC ✓", n.Message)
+	assert.Contains(m2.String(), `"subcode":"ADMIN_SHUTDOWN","message":"planned maintenance`)
+
+	// invalid messages
+	long := make([]byte, 256)
+	for i := range long {
+		long[i] = 'x'
+	}
+	assert.ErrorIs(m.Notify.SetShutdownMessage(string(long)), ErrLong)
+	assert.ErrorIs(m.Notify.SetShutdownMessage("\xff\xfe"), ErrValue)
+}
+
+func TestNotify_JSONMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	// quote, backslash, control byte, and a multi-byte character
+	txt := "say \"bye\" \\ now\x01 ✓"
+	n := &NewNotify(NOTIFY_CEASE, CEASE_ADMIN_SHUTDOWN, nil).Notify
+	assert.NoError(n.SetShutdownMessage(txt))
+
+	js := n.ToJSON(nil)
+	assert.True(stdjson.Valid(js), string(js))
+	assert.Contains(string(js), `"message":"say \"bye\" \\ now\u0001 ✓"`)
+
+	var n2 Notify
+	assert.NoError(n2.FromJSON(js))
+	assert.Equal(txt, n2.Message)
+
+	// the whole message, too
+	m := NewMsg()
+	m.Dir = dir.DIR_L
+	m.Use(NOTIFY).Notify = *n
+	m2 := NewMsg()
+	assert.NoError(m2.FromJSON(m.GetJSON()))
+	assert.Equal(txt, m2.Notify.Message)
+
+	// invalid escape
+	assert.Error(n2.FromJSON([]byte(`{"code":"CEASE","subcode":2,"message":"bad \x"}`)))
+}
@@ -20,7 +20,15 @@ type Open struct {
 	Params     []byte     // raw Optional Parameters
 	ParamsExt  bool       // true iff Params use extended length
 
-	Caps caps.Caps // BGP capabilities, usually parsed from Params
+	Caps    caps.Caps // BGP capabilities, usually parsed from Params
+	RawCaps []RawCap  // raw capabilities seen by ParseCaps, in order
+}
+
+// RawCap represents a raw capability TLV in Open.Params, for diagnostics
+type RawCap struct {
+	Code   caps.Code // capability code
+	Data   []byte    // capability value (references Open.Params)
+	Offset int       // offset of the capability TLV in Open.Params
 }
 
 const (
@@ -47,6 +55,7 @@ func (o *Open) Reset() {
 	o.Params = nil
 	o.ParamsExt = false
 	o.Caps.Reset()
+	o.RawCaps = o.RawCaps[:0]
 }
 
 // Parse parses o.Msg.Data as BGP OPEN
@@ -69,8 +78,8 @@ func (o *Open) Parse() error {
 	if pslen > 0 {
 		params, ext := buf[10:], false
 
-		// extended length?
-		if len(params) >= 3 && params[0] == PARAM_EXTLEN {
+		// extended length? rfc9072/2
+		if pslen == 255 && len(params) >= 3 && params[0] == PARAM_EXTLEN {
 			ext = true
 			pslen = int(msb.Uint16(params[1:3]))
 			params = params[3:]
@@ -89,7 +98,11 @@ func (o *Open) Parse() error {
 	return nil
 }
 
-// ParseCaps parses all capability codes from Params to Caps
+// ParseCaps parses all capability codes from Params to Caps.
+// Repeated capability codes, also across separate parameters, are parsed into the same
+// Caps entry, so eg. all CAP_MP entries are merged into one AFI+SAFI set.
+// It also stores all capabilities in RawCaps as-is, including duplicates,
+// with the last one being malformed in case of an error.
 func (o *Open) ParseCaps() error {
 	var (
 		params = o.Params // all parameters
@@ -102,6 +115,7 @@ func (o *Open) ParseCaps() error {
 	// parse params one-by-one, in practice there's only the
 	// capabilities param, or we return an error
 	cps.Init()
+	o.RawCaps = o.RawCaps[:0]
 	for len(params) > 0 {
 		if o.ParamsExt {
 			if len(params) < 3 {
@@ -126,6 +140,7 @@ func (o *Open) ParseCaps() error {
 			params = pval[plen:]
 			pval = pval[:plen]
 		}
+		pend := len(o.Params) - len(params) // end of pval in o.Params
 
 		// we only support capabilities
 		if ptyp != PARAM_CAPS {
@@ -138,11 +153,14 @@ func (o *Open) ParseCaps() error {
 				return ErrCaps
 			}
 			cc, clen, cval := caps.Code(pval[0]), int(pval[1]), pval[2:]
+			offset := pend - len(pval)
 			if len(cval) < clen {
+				o.RawCaps = append(o.RawCaps, RawCap{cc, cval, offset})
 				return ErrCaps
 			} else {
 				pval = cval[clen:]
 				cval = cval[:clen]
+				o.RawCaps = append(o.RawCaps, RawCap{cc, cval, offset})
 			}
 
 			// fetch cap
@@ -267,6 +285,37 @@ func (o *Open) MarshalCaps() error {
 	return nil
 }
 
+// HasCap returns true iff capability cc is set in o.Caps
+func (o *Open) HasCap(cc caps.Code) bool {
+	return o.Caps.Has(cc)
+}
+
+// AddCap sets capability c in o.Caps, overwriting any existing capability with the same code,
+// and marks the message as modified, so that o.Params are regenerated on Marshal.
+// The capability code is taken from the wire representation of c, which must be non-empty.
+func (o *Open) AddCap(c caps.Cap) error {
+	raw := c.Marshal(nil)
+	if len(raw) < 2 {
+		return fmt.Errorf("AddCap: %w", ErrCaps)
+	}
+
+	o.Caps.Set(caps.Code(raw[0]), c)
+	o.Msg.Modified()
+	return nil
+}
+
+// DropCap drops capability cc from o.Caps, if present, and marks the message as modified,
+// so that o.Params are regenerated on Marshal. Returns true iff cc was present.
+func (o *Open) DropCap(cc caps.Code) bool {
+	if !o.Caps.Has(cc) {
+		return false
+	}
+
+	o.Caps.Drop(cc)
+	o.Msg.Modified()
+	return true
+}
+
 // String dumps o to JSON
 func (o *Open) String() string {
 	return string(o.ToJSON(nil))
@@ -0,0 +1,138 @@
+package msg
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen_RawCaps(t *testing.T) {
+	assert := assert.New(t)
+
+	// two capability parameters, AS4 sent twice
+	o := &NewMsg().Open
+	o.Params = []byte{
+		0x02, 0x06, 0x41, 0x04, 0x00, 0x00, 0xfd, 0xe8, // AS4 65000
+		0x02, 0x0c,
+		0x01, 0x04, 0x00, 0x01, 0x00, 0x01, // MP IPv4 unicast
+		0x41, 0x04, 0x00, 0x00, 0xfd, 0xe9, // AS4 65001
+	}
+	assert.NoError(o.ParseCaps())
+	assert.Equal([]RawCap{
+		{caps.CAP_AS4, o.Params[4:8], 2},
+		{caps.CAP_MP, o.Params[12:16], 10},
+		{caps.CAP_AS4, o.Params[18:22], 16},
+	}, o.RawCaps)
+	assert.Equal(65001, o.GetASN())
+
+	// truncated capability
+	o.Params = []byte{0x02, 0x04, 0x41, 0x04, 0x00, 0x00}
+	assert.ErrorIs(o.ParseCaps(), ErrCaps)
+	assert.Equal([]RawCap{{caps.CAP_AS4, o.Params[4:6], 2}}, o.RawCaps)
+}
+
+func TestOpen_AddCap(t *testing.T) {
+	assert := assert.New(t)
+	var cps caps.Caps
+
+	// an OPEN with MP IPv4 unicast and ADD_PATH
+	m := NewMsg()
+	assert.NoError(m.FromJSON([]byte(`[ "L", 1, "2024-01-01T00:00:00.000", 0, "OPEN", {
+		"bgp": 4, "asn": 65001, "id": "192.0.2.1", "hold": 90,
+		"caps": { "MP": [ "IPV4/UNICAST" ], "ADDPATH": [ "IPV4/UNICAST/BIDIR" ] }
+	} ] `)))
+	assert.NoError(m.Marshal(cps))
+
+	o := &m.Open
+	assert.True(o.HasCap(caps.CAP_ADDPATH))
+	assert.True(o.DropCap(caps.CAP_AS4))
+	assert.False(o.HasCap(caps.CAP_AS4))
+
+	// drop ADD_PATH, add AS4
+	assert.True(o.DropCap(caps.CAP_ADDPATH))
+	assert.False(o.DropCap(caps.CAP_ADDPATH))
+	assert.NoError(o.AddCap(&caps.AS4{ASN: 4200000001}))
+	assert.Nil(m.Data)
+	assert.Error(o.AddCap(&caps.MP{}))
+
+	// re-marshal and parse back
+	assert.NoError(m.Marshal(cps))
+	m2 := NewMsg()
+	m2.Type = OPEN
+	m2.Data = m.Data
+	assert.NoError(m2.Parse(cps))
+	assert.True(m2.Open.HasCap(caps.CAP_AS4))
+	assert.True(m2.Open.HasCap(caps.CAP_MP))
+	assert.False(m2.Open.HasCap(caps.CAP_ADDPATH))
+	assert.Equal(4200000001, m2.Open.GetASN())
+}
+
+func TestOpen_MergeCaps(t *testing.T) {
+	assert := assert.New(t)
+
+	// two capability parameters, one MP entry each, IPv4 unicast repeated
+	o := &NewMsg().Open
+	o.Params = []byte{
+		0x02, 0x06, 0x01, 0x04, 0x00, 0x01, 0x00, 0x01, // MP IPv4 unicast
+		0x02, 0x0c,
+		0x01, 0x04, 0x00, 0x02, 0x00, 0x01, // MP IPv6 unicast
+		0x01, 0x04, 0x00, 0x01, 0x00, 0x01, // MP IPv4 unicast (again)
+	}
+	assert.NoError(o.ParseCaps())
+	mp, ok := o.Caps.Get(caps.CAP_MP).(*caps.MP)
+	if assert.True(ok) {
+		assert.Equal([]afi.AS{afi.AS_IPV4_UNICAST, afi.AS_IPV6_UNICAST}, mp.Sorted())
+	}
+	assert.Len(o.RawCaps, 3)
+
+	// must be deterministic when parsed again
+	assert.NoError(o.ParseCaps())
+	assert.Equal([]afi.AS{afi.AS_IPV4_UNICAST, afi.AS_IPV6_UNICAST}, o.Caps.Get(caps.CAP_MP).(*caps.MP).Sorted())
+}
+
+func TestOpen_ParamsExt(t *testing.T) {
+	assert := assert.New(t)
+	var cps caps.Caps
+
+	// an OPEN with capabilities longer than 255 bytes
+	m := NewMsg().Use(OPEN)
+	o := &m.Open
+	o.Version = OPEN_VERSION
+	o.SetASN(65001)
+	o.Identifier = netip.MustParseAddr("192.0.2.1")
+	mp := o.Caps.Use(caps.CAP_MP).(*caps.MP)
+	for safi := range 64 {
+		mp.Add(afi.AFI_IPV4, afi.SAFI(safi+1))
+	}
+	assert.NoError(m.Marshal(cps))
+	assert.True(o.ParamsExt)
+
+	// rfc9072/2: 255 + 255 + 2-byte length
+	data := m.Data
+	assert.Equal([]byte{255, PARAM_EXTLEN}, data[9:11])
+	assert.Equal(len(data)-13, int(data[11])<<8|int(data[12]))
+
+	// parse back
+	m2 := NewMsg()
+	m2.Type = OPEN
+	m2.Data = data
+	assert.NoError(m2.Parse(cps))
+	assert.True(m2.Open.ParamsExt)
+	assert.Equal(mp.Sorted(), m2.Open.Caps.Get(caps.CAP_MP).(*caps.MP).Sorted())
+	assert.Equal(65001, m2.Open.GetASN())
+
+	// re-marshal gives the same result
+	m2.Modified()
+	assert.NoError(m2.Marshal(cps))
+	assert.Equal(data, m2.Data)
+
+	// short capabilities: 1-byte form
+	o.Caps.Drop(caps.CAP_MP)
+	m.Modified()
+	assert.NoError(m.Marshal(cps))
+	assert.False(o.ParamsExt)
+	assert.NotEqual(byte(255), m.Data[9])
+}
@@ -0,0 +1,60 @@
+package msg
+
+// ParsePolicy controls how UPDATE messages are parsed from wire format,
+// in particular how to handle malformed or unusual input.
+// The zero value is equivalent to StrictPolicy.
+type ParsePolicy struct {
+	AttrDupe     PolicyDupe    // how to handle duplicate attributes
+	AttrUnknown  PolicyUnknown // how to handle unknown attribute codes
+	AspathRepair bool          // if true, keep the valid leading segments of a broken AS_PATH / AS4_PATH
+	NonCanonical PolicyPrefix  // how to handle IP prefixes with host bits set
+	MaxNLRI      int           // if non-zero, max number of IPv4 plus MP prefixes in an UPDATE
+	Intern       *Interner     // if non-nil, share identical attribute sets between UPDATEs
+}
+
+// PolicyDupe tells how to handle duplicate attributes
+type PolicyDupe byte
+
+const (
+	DUPE_ERROR PolicyDupe = iota // return ErrAttrDupe
+	DUPE_FIRST                   // keep the first occurrence
+	DUPE_LAST                    // keep the last occurrence
+)
+
+// PolicyUnknown tells how to handle unknown attribute codes
+type PolicyUnknown byte
+
+const (
+	UNKNOWN_KEEP  PolicyUnknown = iota // keep as attrs.Raw
+	UNKNOWN_DROP                       // drop silently
+	UNKNOWN_ERROR                      // return ErrAttrCode
+)
+
+// PolicyPrefix tells how to handle non-canonical IP prefixes
+type PolicyPrefix byte
+
+const (
+	PREFIX_MASK  PolicyPrefix = iota // clear the host bits
+	PREFIX_ERROR                     // return nlri.ErrNonCanonical
+)
+
+var (
+	// StrictPolicy is the default: it rejects duplicate attributes and any broken
+	// AS_PATH / AS4_PATH, but keeps unknown attributes (as attrs.Raw) and clears
+	// the host bits of non-canonical prefixes. There is no limit on prefixes.
+	StrictPolicy = ParsePolicy{
+		AttrDupe:     DUPE_ERROR,
+		AttrUnknown:  UNKNOWN_KEEP,
+		NonCanonical: PREFIX_MASK,
+	}
+
+	// LenientPolicy is like StrictPolicy, but tries to recover from common errors
+	// seen in the wild: it keeps the first of duplicate attributes, and the valid
+	// leading segments of a broken AS_PATH / AS4_PATH.
+	LenientPolicy = ParsePolicy{
+		AttrDupe:     DUPE_FIRST,
+		AttrUnknown:  UNKNOWN_KEEP,
+		AspathRepair: true,
+		NonCanonical: PREFIX_MASK,
+	}
+)
@@ -0,0 +1,358 @@
+package msg
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/json"
+	"github.com/bgpfix/bgpfix/nlri"
+)
+
+// Refresh represents a BGP ROUTE-REFRESH message (rfc2918),
+// with optional Outbound Route Filtering entries (rfc5291).
+type Refresh struct {
+	Msg *Msg // parent BGP message
+
+	AS      afi.AS // address family to refresh
+	Subtype byte   // message subtype, eg. REFRESH_BORR (rfc7313)
+
+	When byte  // when-to-refresh, if ORF is non-empty (rfc5291/4)
+	ORF  []ORF // ORF entries, grouped by ORF type
+}
+
+// ORF represents a group of Outbound Route Filtering entries of the same type, rfc5291/4
+type ORF struct {
+	Type    byte       // ORF type, eg. ORF_PREFIX
+	Entries []ORFEntry // parsed entries, for ORF_PREFIX
+	Raw     []byte     // raw entries, for other ORF types
+}
+
+// ORFEntry represents an Address Prefix ORF entry, rfc5292/3
+type ORFEntry struct {
+	Action byte // ORF_ADD, ORF_REMOVE, or ORF_REMOVE_ALL
+	Match  byte // ORF_PERMIT or ORF_DENY
+
+	// NB: below not used for ORF_REMOVE_ALL
+
+	Seq    uint32       // sequence number
+	MinLen byte         // minimum prefix length to match
+	MaxLen byte         // maximum prefix length to match
+	Prefix netip.Prefix // IP prefix
+}
+
+const (
+	REFRESH_MINLEN = 4 // AFI + subtype + SAFI, rfc2918/3
+)
+
+// ROUTE-REFRESH message subtypes, rfc7313/3.2
+const (
+	REFRESH_REQUEST byte = 0 // normal route refresh request
+	REFRESH_BORR    byte = 1 // Beginning of Route Refresh
+	REFRESH_EORR    byte = 2 // End of Route Refresh
+)
+
+// ORF when-to-refresh values, rfc5291/4
+const (
+	ORF_IMMEDIATE byte = 1
+	ORF_DEFER     byte = 2
+)
+
+// ORF types
+const (
+	ORF_PREFIX byte = 64 // Address Prefix ORF, rfc5292
+)
+
+// ORF entry actions and matches, rfc5291/4
+const (
+	ORF_ADD        byte = 0
+	ORF_REMOVE     byte = 1
+	ORF_REMOVE_ALL byte = 2
+
+	ORF_PERMIT byte = 0
+	ORF_DENY   byte = 1
+)
+
+var (
+	refreshSubtypeName = map[byte]string{REFRESH_REQUEST: "REQUEST", REFRESH_BORR: "BORR", REFRESH_EORR: "EORR"}
+	orfWhenName        = map[byte]string{ORF_IMMEDIATE: "IMMEDIATE", ORF_DEFER: "DEFER"}
+	orfActionName      = map[byte]string{ORF_ADD: "ADD", ORF_REMOVE: "REMOVE", ORF_REMOVE_ALL: "REMOVE-ALL"}
+	orfMatchName       = map[byte]string{ORF_PERMIT: "PERMIT", ORF_DENY: "DENY"}
+)
+
+// NewRefresh returns a new ROUTE-REFRESH message for given address family and subtype,
+// eg. REFRESH_BORR to mark the beginning of an Enhanced Route Refresh (rfc7313/4),
+// marshaled and ready to be sent.
+func NewRefresh(af afi.AFI, safi afi.SAFI, subtype byte) *Msg {
+	m := NewMsg().Use(REFRESH)
+	r := &m.Refresh
+	r.AS = afi.NewAS(af, safi)
+	r.Subtype = subtype
+	r.Marshal() // NB: can't fail without ORF
+	return m
+}
+
+// Init initializes r to use parent m
+func (r *Refresh) Init(m *Msg) {
+	r.Msg = m
+}
+
+// Reset prepares r for re-use
+func (r *Refresh) Reset() {
+	r.AS = 0
+	r.Subtype = 0
+	r.When = 0
+	r.ORF = r.ORF[:0]
+}
+
+// Parse parses r.Msg.Data as BGP ROUTE-REFRESH.
+// Does not reference data in r.Msg.Data.
+func (r *Refresh) Parse() error {
+	buf := r.Msg.Data
+	if len(buf) < REFRESH_MINLEN {
+		return ErrShort
+	}
+
+	r.AS = afi.NewASBytes(buf[0:4])
+	r.Subtype = buf[2]
+	r.When = 0
+	r.ORF = r.ORF[:0]
+
+	// any ORF entries? rfc5291/4
+	buf = buf[4:]
+	if len(buf) == 0 {
+		return nil
+	}
+	r.When = buf[0]
+	buf = buf[1:]
+
+	for len(buf) > 0 {
+		if len(buf) < 3 {
+			return fmt.Errorf("ORF: %w", ErrLength)
+		}
+		orf := ORF{Type: buf[0]}
+		l := int(msb.Uint16(buf[1:3]))
+		buf = buf[3:]
+		if len(buf) < l {
+			return fmt.Errorf("ORF: %w", ErrLength)
+		}
+
+		var err error
+		if orf.Type == ORF_PREFIX {
+			orf.Entries, err = parsePrefixORF(buf[:l], r.AS.IsIPv6())
+		} else {
+			orf.Raw = append(orf.Raw, buf[:l]...)
+		}
+		if err != nil {
+			return fmt.Errorf("ORF type %d: %w", orf.Type, err)
+		}
+
+		r.ORF = append(r.ORF, orf)
+		buf = buf[l:]
+	}
+
+	return nil
+}
+
+// parsePrefixORF parses Address Prefix ORF entries in buf, rfc5292/3
+func parsePrefixORF(buf []byte, ipv6 bool) (dst []ORFEntry, err error) {
+	for len(buf) > 0 {
+		e := ORFEntry{
+			Action: buf[0] >> 6,
+			Match:  (buf[0] >> 5) & 0x01,
+		}
+		buf = buf[1:]
+
+		if e.Action != ORF_REMOVE_ALL {
+			if len(buf) < 7 {
+				return dst, ErrLength
+			}
+			e.Seq = msb.Uint32(buf[0:4])
+			e.MinLen = buf[4]
+			e.MaxLen = buf[5]
+			buf = buf[6:]
+
+			var p nlri.NLRI
+			n, err := p.Unmarshal(buf, ipv6, false)
+			if err != nil {
+				return dst, err
+			}
+			e.Prefix = p.Prefix
+			buf = buf[n:]
+		}
+
+		dst = append(dst, e)
+	}
+	return dst, nil
+}
+
+// Marshal marshals r to r.Msg.Data.
+func (r *Refresh) Marshal() error {
+	msg := r.Msg
+	buf := msg.buf[:0]
+	buf = msb.AppendUint16(buf, uint16(r.AS.Afi()))
+	buf = append(buf, r.Subtype, byte(r.AS.Safi()))
+
+	if len(r.ORF) > 0 {
+		buf = append(buf, r.When)
+		for i := range r.ORF {
+			orf := &r.ORF[i]
+			buf = append(buf, orf.Type, 0, 0) // length (tbd [1])
+			off := len(buf)
+			if orf.Type == ORF_PREFIX {
+				buf = marshalPrefixORF(buf, orf.Entries)
+			} else {
+				buf = append(buf, orf.Raw...)
+			}
+			if l := len(buf) - off; l > math.MaxUint16 {
+				return fmt.Errorf("Marshal: ORF type %d: %w (%d)", orf.Type, ErrLength, l)
+			} else {
+				msb.PutUint16(buf[off-2:], uint16(l)) // [1]
+			}
+		}
+	}
+
+	msg.Type = REFRESH
+	msg.Upper = REFRESH
+	msg.buf = buf
+	msg.Data = buf
+	msg.ref = false
+	return nil
+}
+
+// marshalPrefixORF appends Address Prefix ORF entries in src to dst, rfc5292/3
+func marshalPrefixORF(dst []byte, src []ORFEntry) []byte {
+	for i := range src {
+		e := &src[i]
+		dst = append(dst, (e.Action&0x03)<<6|(e.Match&0x01)<<5)
+		if e.Action == ORF_REMOVE_ALL {
+			continue
+		}
+		dst = msb.AppendUint32(dst, e.Seq)
+		dst = append(dst, e.MinLen, e.MaxLen)
+		p := nlri.FromPrefix(e.Prefix)
+		dst = p.Marshal(dst, false)
+	}
+	return dst
+}
+
+// String dumps r to JSON
+func (r *Refresh) String() string {
+	return string(r.ToJSON(nil))
+}
+
+// ToJSON appends JSON representation of r to dst
+func (r *Refresh) ToJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	dst = r.AS.ToJSONKey(dst, "af")
+	dst = append(dst, `,"subtype":`...)
+	dst = nameToJSON(dst, r.Subtype, refreshSubtypeName)
+
+	if len(r.ORF) == 0 {
+		return append(dst, '}')
+	}
+
+	dst = append(dst, `,"when":`...)
+	dst = nameToJSON(dst, r.When, orfWhenName)
+
+	dst = append(dst, `,"orf":[`...)
+	for i := range r.ORF {
+		orf := &r.ORF[i]
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `{"type":`...)
+		dst = json.Byte(dst, orf.Type)
+		if orf.Type != ORF_PREFIX {
+			dst = append(dst, `,"raw":`...)
+			dst = json.Hex(dst, orf.Raw)
+			dst = append(dst, '}')
+			continue
+		}
+
+		dst = append(dst, `,"entries":[`...)
+		for j := range orf.Entries {
+			e := &orf.Entries[j]
+			if j > 0 {
+				dst = append(dst, ',')
+			}
+			dst = append(dst, `{"action":`...)
+			dst = nameToJSON(dst, e.Action, orfActionName)
+			dst = append(dst, `,"match":`...)
+			dst = nameToJSON(dst, e.Match, orfMatchName)
+			if e.Action != ORF_REMOVE_ALL {
+				dst = append(dst, `,"seq":`...)
+				dst = json.Uint32(dst, e.Seq)
+				dst = append(dst, `,"min":`...)
+				dst = json.Byte(dst, e.MinLen)
+				dst = append(dst, `,"max":`...)
+				dst = json.Byte(dst, e.MaxLen)
+				dst = append(dst, `,"prefix":`...)
+				dst = json.Prefix(dst, e.Prefix)
+			}
+			dst = append(dst, '}')
+		}
+		dst = append(dst, "]}"...)
+	}
+	return append(dst, "]}"...)
+}
+
+// FromJSON reads r JSON representation from src
+func (r *Refresh) FromJSON(src []byte) error {
+	r.Reset()
+	return json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "af":
+			err = r.AS.FromJSON(val)
+		case "subtype":
+			r.Subtype, err = unName(val, refreshSubtypeName)
+		case "when":
+			r.When, err = unName(val, orfWhenName)
+		case "orf":
+			err = json.ArrayEach(val, func(key int, val []byte, typ json.Type) error {
+				orf, err := orfFromJSON(val)
+				r.ORF = append(r.ORF, orf)
+				return err
+			})
+		}
+		return
+	})
+}
+
+// orfFromJSON reads ORF JSON representation from src
+func orfFromJSON(src []byte) (orf ORF, err error) {
+	err = json.ObjectEach(src, func(key string, val []byte, typ json.Type) (err error) {
+		switch key {
+		case "type":
+			orf.Type, err = json.UnByte(val)
+		case "raw":
+			orf.Raw, err = json.UnHex(val, nil)
+		case "entries":
+			err = json.ArrayEach(val, func(key int, val []byte, typ json.Type) error {
+				var e ORFEntry
+				err := json.ObjectEach(val, func(key string, val []byte, typ json.Type) (err error) {
+					switch key {
+					case "action":
+						e.Action, err = unName(val, orfActionName)
+					case "match":
+						e.Match, err = unName(val, orfMatchName)
+					case "seq":
+						e.Seq, err = json.UnUint32(val)
+					case "min":
+						e.MinLen, err = json.UnByte(val)
+					case "max":
+						e.MaxLen, err = json.UnByte(val)
+					case "prefix":
+						e.Prefix, err = json.UnPrefix(val)
+					}
+					return
+				})
+				orf.Entries = append(orf.Entries, e)
+				return err
+			})
+		}
+		return
+	})
+	return
+}
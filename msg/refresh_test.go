@@ -0,0 +1,149 @@
+package msg
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefresh_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+		json    string
+	}{
+		{
+			name: "request",
+			data: []byte{0x00, 0x01, 0x00, 0x01},
+			json: `{"af":"IPV4/UNICAST","subtype":"REQUEST"}`,
+		},
+		{
+			name: "eorr",
+			data: []byte{0x00, 0x02, 0x02, 0x01},
+			json: `{"af":"IPV6/UNICAST","subtype":"EORR"}`,
+		},
+		{
+			name: "prefix orf",
+			data: []byte{
+				0x00, 0x01, 0x00, 0x01, // IPv4 unicast
+				ORF_IMMEDIATE,
+				ORF_PREFIX, 0x00, 0x14, // 20 bytes
+				0x00, 0x00, 0x00, 0x00, 0x0a, 0x10, 0x18, 0x08, 0x0a, // ADD PERMIT seq=10 10.0.0.0/8 ge 16 le 24
+				0x60, 0x00, 0x00, 0x00, 0x14, 0x00, 0x00, 0x10, 0xc0, 0xa8, // REMOVE DENY seq=20 192.168.0.0/16
+				0x80, // REMOVE-ALL
+				0x80, 0x00, 0x02, 0xab, 0xcd, // unknown ORF type
+			},
+			json: `{"af":"IPV4/UNICAST","subtype":"REQUEST","when":"IMMEDIATE","orf":[` +
+				`{"type":64,"entries":[` +
+				`{"action":"ADD","match":"PERMIT","seq":10,"min":16,"max":24,"prefix":"10.0.0.0/8"},` +
+				`{"action":"REMOVE","match":"DENY","seq":20,"min":0,"max":0,"prefix":"192.168.0.0/16"},` +
+				`{"action":"REMOVE-ALL","match":"PERMIT"}]},` +
+				`{"type":128,"raw":"0xabcd"}]}`,
+		},
+		{
+			name: "ipv6 prefix orf, deferred",
+			data: []byte{
+				0x00, 0x02, 0x00, 0x01, // IPv6 unicast
+				ORF_DEFER,
+				ORF_PREFIX, 0x00, 0x0c,
+				0x20, 0x00, 0x00, 0x00, 0x01, 0x30, 0x40, 0x20, 0x20, 0x01, 0x0d, 0xb8, // ADD DENY 2001:db8::/32 ge 48 le 64
+			},
+			json: `{"af":"IPV6/UNICAST","subtype":"REQUEST","when":"DEFER","orf":[` +
+				`{"type":64,"entries":[{"action":"ADD","match":"DENY","seq":1,"min":48,"max":64,"prefix":"2001:db8::/32"}]}]}`,
+		},
+		{
+			name:    "too short",
+			data:    []byte{0x00, 0x01, 0x00},
+			wantErr: ErrShort,
+		},
+		{
+			name:    "orf truncated",
+			data:    []byte{0x00, 0x01, 0x00, 0x01, ORF_IMMEDIATE, ORF_PREFIX, 0x00, 0x10, 0x00},
+			wantErr: ErrLength,
+		},
+		{
+			name:    "orf entry truncated",
+			data:    []byte{0x00, 0x01, 0x00, 0x01, ORF_IMMEDIATE, ORF_PREFIX, 0x00, 0x03, 0x00, 0x00, 0x00},
+			wantErr: ErrLength,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMsg()
+			m.Dir = dir.DIR_L
+			m.Type = REFRESH
+			m.Data = tt.data
+			err := m.Parse(caps.Caps{})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.json, m.Refresh.String())
+
+			// wire round-trip
+			m.Modified()
+			assert.NoError(t, m.Marshal(caps.Caps{}))
+			assert.Equal(t, tt.data, m.Data)
+
+			// JSON round-trip
+			m2 := NewMsg()
+			assert.NoError(t, m2.FromJSON([]byte(m.String())))
+			assert.NoError(t, m2.Marshal(caps.Caps{}))
+			assert.Equal(t, tt.data, m2.Data)
+		})
+	}
+}
+
+func TestRefresh_Marshal(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewMsg().Use(REFRESH)
+	r := &m.Refresh
+	r.AS = afi.AS_IPV4_UNICAST
+	r.When = ORF_IMMEDIATE
+	r.ORF = []ORF{{Type: ORF_PREFIX, Entries: []ORFEntry{{
+		Seq:    5,
+		MinLen: 24,
+		MaxLen: 32,
+		Prefix: netip.MustParsePrefix("192.0.2.0/24"),
+	}}}}
+	assert.NoError(m.Marshal(caps.Caps{}))
+	assert.Equal([]byte{
+		0x00, 0x01, 0x00, 0x01, 0x01,
+		0x40, 0x00, 0x0b,
+		0x00, 0x00, 0x00, 0x00, 0x05, 0x18, 0x20, 0x18, 0xc0, 0x00, 0x02,
+	}, m.Data)
+}
+
+func TestNewRefresh(t *testing.T) {
+	assert := assert.New(t)
+
+	// BoRR for IPv6 unicast
+	m := NewRefresh(afi.AFI_IPV6, afi.SAFI_UNICAST, REFRESH_BORR)
+	assert.Equal(REFRESH, m.Type)
+	assert.Equal([]byte{0x00, 0x02, 0x01, 0x01}, m.Data)
+
+	// parse it back
+	m2 := NewMsg()
+	m2.Type = REFRESH
+	m2.Data = m.Data
+	assert.NoError(m2.Parse(caps.Caps{}))
+	assert.Equal(afi.AS_IPV6_UNICAST, m2.Refresh.AS)
+	assert.Equal(REFRESH_BORR, m2.Refresh.Subtype)
+	assert.Contains(m2.String(), `"BORR"`)
+
+	// a plain request, round-trip through JSON
+	m = NewRefresh(afi.AFI_IPV4, afi.SAFI_UNICAST, REFRESH_REQUEST)
+	assert.Equal([]byte{0x00, 0x01, 0x00, 0x01}, m.Data)
+	m.Dir = dir.DIR_L
+	m3 := NewMsg()
+	assert.NoError(m3.FromJSON(m.GetJSON()))
+	assert.NoError(m3.Marshal(caps.Caps{}))
+	assert.Equal(m.Data, m3.Data)
+}
@@ -0,0 +1,74 @@
+package msg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// JSON_MAXLINE is the maximum length of a single JSON line read by JSONReader
+const JSON_MAXLINE = 16 * 1024 * 1024
+
+// JSONWriter writes messages to an io.Writer as newline-delimited JSON (NDJSON).
+type JSONWriter struct {
+	w     io.Writer
+	Count uint64 // number of messages written
+}
+
+// NewJSONWriter returns a new JSONWriter writing to w.
+// Consider wrapping w in a bufio.Writer for performance.
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{w: w}
+}
+
+// Write writes m to the underlying writer as one JSON line,
+// directly from the m internal JSON buffer (see Msg.GetJSON).
+func (jw *JSONWriter) Write(m *Msg) error {
+	if _, err := jw.w.Write(m.GetJSON()); err != nil {
+		return err
+	}
+	jw.Count++
+	return nil
+}
+
+// JSONReader reads newline-delimited JSON (NDJSON) messages from an io.Reader,
+// in the array form understood by Msg.FromJSON. Blank lines are skipped.
+type JSONReader struct {
+	sc    *bufio.Scanner
+	msg   *Msg
+	Lines uint64 // number of lines read so far
+	Count uint64 // number of messages read
+}
+
+// NewJSONReader returns a new JSONReader reading from r.
+func NewJSONReader(r io.Reader) *JSONReader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, JSON_MAXLINE)
+	return &JSONReader{sc: sc, msg: NewMsg()}
+}
+
+// Read reads the next message. It returns io.EOF at the end of input.
+// The returned message is re-used in the next call to Read: copy it if needed.
+// Must not be used concurrently.
+func (jr *JSONReader) Read() (*Msg, error) {
+	for jr.sc.Scan() {
+		jr.Lines++
+		line := bytes.TrimSpace(jr.sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		m := jr.msg.Reset()
+		if err := m.FromJSON(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", jr.Lines, err)
+		}
+		jr.Count++
+		return m, nil
+	}
+
+	if err := jr.sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,95 @@
+package msg
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var streamTestMsgs = []string{
+	`[ "R", 1, "2024-01-01T00:00:00.000", 0, "OPEN", {
+		"bgp": 4, "asn": 65001, "id": "192.0.2.1", "hold": 90,
+		"caps": { "MP": [ "IPV4/UNICAST", "IPV6/UNICAST" ] } } ]`,
+	`[ "L", 2, "2024-01-01T00:00:01.000", 0, "KEEPALIVE", null ]`,
+	`[ "R", 3, "2024-01-01T00:00:02.000", 0, "UPDATE", {
+		"reach": [ "10.1.0.0/16" ],
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"ASPATH": { "flags": "T", "value": [ 65001, 65002 ] },
+			"NEXTHOP": { "flags": "T", "value": "192.0.2.1" }
+		} } ]`,
+}
+
+func TestJSONStream(t *testing.T) {
+	assert := assert.New(t)
+
+	// write all messages, remember their JSON
+	var (
+		buf  bytes.Buffer
+		want []string
+	)
+	jw := NewJSONWriter(&buf)
+	for _, s := range streamTestMsgs {
+		m := NewMsg()
+		if !assert.NoError(m.FromJSON([]byte(s))) {
+			return
+		}
+		want = append(want, m.String())
+		assert.NoError(jw.Write(m))
+		buf.WriteString("\n  \r\n") // blank lines and whitespace
+	}
+	assert.EqualValues(3, jw.Count)
+
+	// read back
+	jr := NewJSONReader(&buf)
+	for i := range want {
+		m, err := jr.Read()
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(want[i], m.String())
+	}
+	_, err := jr.Read()
+	assert.ErrorIs(err, io.EOF)
+	assert.EqualValues(3, jr.Count)
+	assert.EqualValues(9, jr.Lines)
+
+	// garbage
+	jr = NewJSONReader(strings.NewReader("\n[ \"R\", 1, \"2024-01-01T00:00:00.000\", 0, \"FOO\", null ]\n"))
+	_, err = jr.Read()
+	assert.ErrorContains(err, "line 2")
+}
+
+func BenchmarkJSONStream(b *testing.B) {
+	var src bytes.Buffer
+	jw := NewJSONWriter(&src)
+	for _, s := range streamTestMsgs {
+		m := NewMsg()
+		if err := m.FromJSON([]byte(s)); err != nil {
+			b.Fatal(err)
+		}
+		jw.Write(m)
+	}
+	data := src.Bytes()
+
+	var dst bytes.Buffer
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for range b.N {
+		dst.Reset()
+		jr, jw := NewJSONReader(bytes.NewReader(data)), NewJSONWriter(&dst)
+		for {
+			m, err := jr.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+			jw.Write(m)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math"
 	"net/netip"
+	"slices"
+	"strconv"
 
 	"github.com/bgpfix/bgpfix/afi"
 	"github.com/bgpfix/bgpfix/attrs"
@@ -40,9 +42,44 @@ func (u *Update) Reset() {
 	u.Attrs.Reset()
 }
 
+// Clone returns a deep copy of u, sharing no memory with it.
+// The copy references the same parent message as u: use Msg.Clone for a complete copy.
+func (u *Update) Clone() *Update {
+	u2 := &Update{Msg: u.Msg}
+	u.cloneTo(u2)
+	return u2
+}
+
+// cloneTo writes a deep copy of u to dst, keeping dst.Msg
+func (u *Update) cloneTo(dst *Update) {
+	dst.Reach = cloneNLRI(dst.Reach[:0], u.Reach)
+	dst.Unreach = cloneNLRI(dst.Unreach[:0], u.Unreach)
+	dst.RawAttrs = slices.Clone(u.RawAttrs)
+	dst.Attrs = u.Attrs.Clone()
+}
+
+// cloneNLRI appends deep copies of src prefixes to dst
+func cloneNLRI(dst, src []nlri.NLRI) []nlri.NLRI {
+	for _, p := range src {
+		p.Labels = slices.Clone(p.Labels)
+		dst = append(dst, p)
+	}
+	return dst
+}
+
 // Parse parses msg.Data as BGP UPDATE,
 // in the context of BGP capabilities cps, which can be empty.
+// It uses StrictPolicy, see ParseWith.
 func (u *Update) Parse(cps caps.Caps) error {
+	return u.ParseWith(cps, nil)
+}
+
+// ParseWith is like Parse, but uses given parse policy pol (nil means StrictPolicy).
+func (u *Update) ParseWith(cps caps.Caps, pol *ParsePolicy) error {
+	if pol == nil {
+		pol = &StrictPolicy
+	}
+
 	buf := u.Msg.Data
 	if len(buf) < UPDATE_MINLEN {
 		return ErrShort
@@ -70,12 +107,22 @@ func (u *Update) Parse(cps caps.Caps) error {
 		buf = buf[l:]
 	}
 
+	// non-canonical prefixes?
+	if pol.NonCanonical == PREFIX_ERROR {
+		if err := nlri.CheckCanonical(buf, afi.AS_IPV4_UNICAST, cps, u.Msg.Dir); err != nil {
+			return parseError(err, buf)
+		}
+		if err := nlri.CheckCanonical(withdrawn, afi.AS_IPV4_UNICAST, cps, u.Msg.Dir); err != nil {
+			return parseError(err, withdrawn)
+		}
+	}
+
 	// announced routes
 	if len(buf) > 0 {
 		var err error
 		u.Reach, err = nlri.Unmarshal(u.Reach, buf, afi.AS_IPV4_UNICAST, cps, u.Msg.Dir)
 		if err != nil {
-			return err
+			return parseError(err, buf)
 		}
 	}
 
@@ -84,10 +131,15 @@ func (u *Update) Parse(cps caps.Caps) error {
 		var err error
 		u.Unreach, err = nlri.Unmarshal(u.Unreach, withdrawn, afi.AS_IPV4_UNICAST, cps, u.Msg.Dir)
 		if err != nil {
-			return err
+			return parseError(err, withdrawn)
 		}
 	}
 
+	// too many?
+	if pol.MaxNLRI > 0 && len(u.Reach)+len(u.Unreach) > pol.MaxNLRI {
+		return fmt.Errorf("%w: %d", ErrMaxNLRI, len(u.Reach)+len(u.Unreach))
+	}
+
 	// take it
 	u.RawAttrs = ats
 	u.Msg.Upper = UPDATE
@@ -95,50 +147,139 @@ func (u *Update) Parse(cps caps.Caps) error {
 }
 
 // ParseAttrs parses all attributes from RawAttrs into Attrs.
+// It uses StrictPolicy, see ParseAttrsWith.
 func (u *Update) ParseAttrs(cps caps.Caps) error {
+	return u.ParseAttrsWith(cps, nil)
+}
+
+// ParseAttrsWith is like ParseAttrs, but uses given parse policy pol (nil means StrictPolicy).
+func (u *Update) ParseAttrsWith(cps caps.Caps, pol *ParsePolicy) error {
+	if pol == nil {
+		pol = &StrictPolicy
+	}
+
 	var (
 		raw  = u.RawAttrs    // all attributes
 		atyp attrs.CodeFlags // attribute type
 		alen uint16          // attribute length
 		ats  attrs.Attrs     // parsed attributes
+		base = &ats          // where to put non-MP attributes
+		fp   []byte          // attribute set fingerprint, if interning
+		hit  bool            // if true, non-MP attributes are already in ats
 	)
 
 	ats.Init()
+	if pol.Intern != nil {
+		if fp = pol.Intern.fingerprint(raw, cps); fp == nil {
+			// malformed, will fail below
+		} else if shared := pol.Intern.get(fp); shared != nil {
+			ats, hit = shared.Share(), true
+		} else {
+			base = &attrs.Attrs{}
+			base.Init()
+		}
+	}
+
 	for len(raw) > 0 {
 		if len(raw) < 3 {
-			return ErrAttrs
+			return parseError(ErrAttrs, raw)
 		}
+		start := raw // the whole attribute starts here
 
 		// parse attribute type
 		atyp = attrs.CodeFlags(msb.Uint16(raw[0:2]))
 		acode := atyp.Code()
-		if ats.Has(acode) {
-			return fmt.Errorf("%s: %w", acode, ErrAttrDupe)
-		}
 
 		// parse attribute length
 		if !atyp.HasFlags(attrs.ATTR_EXTENDED) {
 			alen = uint16(raw[2])
 			raw = raw[3:]
 		} else if len(raw) < 4 {
-			return ErrParams
+			return parseError(ErrParams, start)
 		} else { // extended length
 			alen = msb.Uint16(raw[2:4])
 			raw = raw[4:]
 		}
 		if len(raw) < int(alen) {
-			return ErrAttrs
+			return parseError(ErrAttrs, start)
 		}
 
 		// put attribute value in buf, skip raw to next
 		buf := raw[:alen]
 		raw = raw[alen:]
 
+		// where to store?
+		dst := &ats
+		if acode != attrs.ATTR_MP_REACH && acode != attrs.ATTR_MP_UNREACH {
+			if hit {
+				continue // already interned
+			}
+			dst = base
+		}
+
+		// duplicate?
+		if dst.Has(acode) {
+			switch pol.AttrDupe {
+			case DUPE_FIRST:
+				continue
+			case DUPE_LAST:
+				dst.Drop(acode)
+			default:
+				return fmt.Errorf("%s: %w", acode, ErrAttrDupe)
+			}
+		}
+
+		// unknown?
+		if _, ok := attrs.NewFuncs[acode]; !ok {
+			switch pol.AttrUnknown {
+			case UNKNOWN_DROP:
+				continue
+			case UNKNOWN_ERROR:
+				return fmt.Errorf("%s: %w", acode, ErrAttrCode)
+			}
+		}
+
 		// create, overwrite flags, try parsing
-		attr := ats.Use(acode)
+		attr := dst.Use(acode)
 		attr.SetFlags(atyp.Flags())
 		if err := attr.Unmarshal(buf, cps, u.Msg.Dir); err != nil {
-			return fmt.Errorf("%s: %w", acode, err)
+			if pol.AspathRepair && (acode == attrs.ATTR_ASPATH || acode == attrs.ATTR_AS4PATH) {
+				continue // keep what was parsed so far
+			}
+			return parseError(fmt.Errorf("%s: %w", acode, err), start[:len(start)-len(raw)])
+		}
+
+		// check MP prefixes?
+		if pol.NonCanonical == PREFIX_ERROR && (acode == attrs.ATTR_MP_REACH || acode == attrs.ATTR_MP_UNREACH) {
+			if mp, ok := attr.(*attrs.MP); ok && mp.Prefixes() != nil {
+				if err := nlri.CheckCanonical(mp.Data, mp.AS, cps, u.Msg.Dir); err != nil {
+					return parseError(fmt.Errorf("%s: %w", acode, err), start[:len(start)-len(raw)])
+				}
+			}
+		}
+	}
+
+	// intern the new attribute set, share with ats
+	if base != &ats {
+		mp := ats
+		ats = pol.Intern.put(fp, base).Share()
+		mp.Each(func(i int, ac attrs.Code, at attrs.Attr) {
+			ats.Set(ac, at)
+		})
+	}
+
+	// too many prefixes?
+	if pol.MaxNLRI > 0 {
+		total := len(u.Reach) + len(u.Unreach)
+		for _, ac := range []attrs.Code{attrs.ATTR_MP_REACH, attrs.ATTR_MP_UNREACH} {
+			if mp, ok := ats.Get(ac).(*attrs.MP); ok {
+				if pfx := mp.Prefixes(); pfx != nil {
+					total += len(pfx.Prefixes)
+				}
+			}
+		}
+		if total > pol.MaxNLRI {
+			return fmt.Errorf("%w: %d", ErrMaxNLRI, total)
 		}
 	}
 
@@ -185,6 +326,11 @@ func (u *Update) Marshal(cps caps.Caps) error {
 	// announced routes
 	buf = nlri.Marshal(buf, u.Reach, afi.AS_IPV4_UNICAST, cps, msg.Dir)
 
+	// fits in a BGP message?
+	if l := HEADLEN + len(buf); l > MaxLen(cps) {
+		return fmt.Errorf("Marshal: message too long: %w (%d)", ErrLength, l)
+	}
+
 	// done
 	msg.Type = UPDATE
 	msg.Upper = UPDATE
@@ -194,6 +340,218 @@ func (u *Update) Marshal(cps caps.Caps) error {
 	return nil
 }
 
+// Normalize brings u to its normal form, and marshals the result to u.Msg.Data,
+// so that semantically equal updates give identical bytes. Parses the attributes
+// first if needed. In the normal form:
+//   - IPv4 and MP prefixes have no host bits set, are sorted, and are unique,
+//   - standard, extended, and large communities are sorted and unique,
+//   - attributes are in ascending order of their codes.
+func (u *Update) Normalize(cps caps.Caps) error {
+	if !u.Attrs.Valid() {
+		if err := u.ParseAttrs(cps); err != nil {
+			return err
+		}
+	}
+
+	// prefixes
+	u.Reach = nlri.Sort(u.Reach)
+	u.Unreach = nlri.Sort(u.Unreach)
+	for _, ac := range []attrs.Code{attrs.ATTR_MP_REACH, attrs.ATTR_MP_UNREACH} {
+		if pfx := u.MP(ac).Prefixes(); pfx != nil {
+			pfx.Prefixes = nlri.Sort(pfx.Prefixes)
+		}
+	}
+
+	// communities
+	if c, ok := u.Attrs.Own(attrs.ATTR_COMMUNITY).(*attrs.Community); ok {
+		c.Sort()
+	}
+	if c, ok := u.Attrs.Own(attrs.ATTR_EXT_COMMUNITY).(*attrs.Extcom); ok {
+		c.Sort(cps)
+	}
+	if c, ok := u.Attrs.Own(attrs.ATTR_LARGE_COMMUNITY).(*attrs.LargeCom); ok {
+		c.Sort()
+	}
+
+	// re-marshal (NB: MarshalAttrs is ordered by attribute code)
+	u.Msg.Modified()
+	if err := u.MarshalAttrs(cps); err != nil {
+		return err
+	}
+	return u.Marshal(cps)
+}
+
+// Withdraw resets u to a withdraw-only UPDATE for given prefixes in address family as,
+// with no other attributes (no ORIGIN, AS_PATH, or NEXT_HOP). IPv4 unicast prefixes
+// go in u.Unreach, others in ATTR_MP_UNREACH. Call u.Msg.Marshal() when done.
+func (u *Update) Withdraw(as afi.AS, prefixes ...nlri.NLRI) {
+	u.Msg.Use(UPDATE)
+	u.Reset()
+	u.Attrs.Init()
+
+	if as == afi.AS_IPV4_UNICAST {
+		u.Unreach = append(u.Unreach, prefixes...)
+		return
+	}
+
+	mp := u.Attrs.Use(attrs.ATTR_MP_UNREACH).(*attrs.MP)
+	mp.AS = as
+	mp.Value = attrs.NewMPValue(mp)
+	if pfx := mp.Prefixes(); pfx != nil {
+		pfx.Prefixes = append(pfx.Prefixes, prefixes...)
+	}
+}
+
+// AddReachAF adds reachable prefixes in address family as to u, with next-hop nh.
+// IPv4 unicast prefixes go in u.Reach, with nh in ATTR_NEXTHOP; others go in ATTR_MP_REACH,
+// which is created if needed. If nh is not valid, the existing next-hop is left intact.
+// Returns an error if ATTR_MP_REACH is already used for another address family,
+// if the address family does not support IP prefixes, or if any of the prefixes
+// or nh is not in the AFI of as (except IPv6 nh for IPv4 in ATTR_MP_REACH, rfc8950).
+// Call u.Msg.Marshal() when done.
+func (u *Update) AddReachAF(as afi.AS, nh netip.Addr, prefixes ...nlri.NLRI) error {
+	if err := checkAF(as, prefixes); err != nil {
+		return err
+	} else if nh.IsValid() && as.Afi() == afi.AFI_IPV6 && !nh.Is6() {
+		return fmt.Errorf("%s: next-hop %s: %w", attrs.ATTR_MP_REACH, nh, ErrValue)
+	}
+
+	u.Msg.Use(UPDATE)
+	u.Attrs.Init()
+
+	if as == afi.AS_IPV4_UNICAST {
+		if nh.IsValid() {
+			if !nh.Is4() {
+				return fmt.Errorf("%s: %w", attrs.ATTR_NEXTHOP, ErrValue)
+			}
+			u.Attrs.Use(attrs.ATTR_NEXTHOP).(*attrs.IP).Addr = nh
+		}
+		u.Reach = append(u.Reach, prefixes...)
+		return nil
+	}
+
+	pfx, err := u.usePrefixes(attrs.ATTR_MP_REACH, as)
+	if err != nil {
+		return err
+	}
+	if nh.IsValid() {
+		pfx.NextHop = nh
+	}
+	pfx.Prefixes = append(pfx.Prefixes, prefixes...)
+	return nil
+}
+
+// AddUnreachAF adds unreachable prefixes in address family as to u.
+// IPv4 unicast prefixes go in u.Unreach, others in ATTR_MP_UNREACH, which is created if needed.
+// Returns an error if ATTR_MP_UNREACH is already used for another address family,
+// if the address family does not support IP prefixes, or if any of the prefixes
+// is not in the AFI of as. Call u.Msg.Marshal() when done.
+func (u *Update) AddUnreachAF(as afi.AS, prefixes ...nlri.NLRI) error {
+	if err := checkAF(as, prefixes); err != nil {
+		return err
+	}
+
+	u.Msg.Use(UPDATE)
+	u.Attrs.Init()
+
+	if as == afi.AS_IPV4_UNICAST {
+		u.Unreach = append(u.Unreach, prefixes...)
+		return nil
+	}
+
+	pfx, err := u.usePrefixes(attrs.ATTR_MP_UNREACH, as)
+	if err != nil {
+		return err
+	}
+	pfx.Prefixes = append(pfx.Prefixes, prefixes...)
+	return nil
+}
+
+// checkAF returns an error if any of the prefixes is not in the AFI of as,
+// or can't be encoded in as (see nlri.NLRI.BitLen)
+func checkAF(as afi.AS, prefixes []nlri.NLRI) error {
+	var v6 bool
+	switch as.Afi() {
+	case afi.AFI_IPV4:
+		v6 = false
+	case afi.AFI_IPV6:
+		v6 = true
+	default:
+		return nil // will fail later
+	}
+	for i := range prefixes {
+		if p := &prefixes[i]; !p.IsValid() || p.Addr().Is6() != v6 || p.BitLen(as) > 255 {
+			return fmt.Errorf("%s: prefix %s: %w", as.Afi(), p.Prefix, ErrValue)
+		}
+	}
+	return nil
+}
+
+// usePrefixes returns the prefixes of MP-BGP attribute ac for address family as,
+// adding the attribute to u if needed.
+func (u *Update) usePrefixes(ac attrs.Code, as afi.AS) (*attrs.MPPrefixes, error) {
+	mp := u.MP(ac)
+	if mp == nil {
+		mp = attrs.NewAttr(ac).(*attrs.MP)
+		mp.AS = as
+		mp.Value = attrs.NewMPValue(mp)
+		if mp.Prefixes() == nil {
+			return nil, fmt.Errorf("%s: %s/%s: %w", ac, as.Afi(), as.Safi(), ErrUnsupported)
+		}
+		u.Attrs.Set(ac, mp)
+	} else if mp.AS != as {
+		return nil, fmt.Errorf("%s: already used for %s/%s: %w", ac, mp.AS.Afi(), mp.AS.Safi(), ErrValue)
+	} else if mp.Prefixes() == nil {
+		return nil, fmt.Errorf("%s: %s/%s: %w", ac, as.Afi(), as.Safi(), ErrUnsupported)
+	}
+	return mp.Prefixes(), nil
+}
+
+// TreatAsWithdraw turns all prefixes announced in u into withdrawals and drops all
+// other attributes, as in the "treat-as-withdraw" approach of rfc7606. Returns false
+// if not possible, ie. for non-prefix MP-BGP NLRI or if ATTR_MP_REACH and ATTR_MP_UNREACH
+// are for different address families; drop the whole message instead in such case.
+// Call u.Msg.Marshal() when done.
+func (u *Update) TreatAsWithdraw() bool {
+	if u == nil || u.Msg.Upper != UPDATE {
+		return false
+	}
+
+	reach, unreach := u.MP(attrs.ATTR_MP_REACH), u.MP(attrs.ATTR_MP_UNREACH)
+	if reach != nil {
+		if reach.Prefixes() == nil {
+			return false
+		} else if unreach != nil && (unreach.AS != reach.AS || unreach.Prefixes() == nil) {
+			return false
+		}
+	}
+
+	// IPv4 unicast
+	u.Unreach = append(u.Unreach, u.Reach...)
+	u.Reach = u.Reach[:0]
+
+	// MP-BGP
+	if reach != nil {
+		if unreach == nil {
+			unreach = u.Attrs.Use(attrs.ATTR_MP_UNREACH).(*attrs.MP)
+			unreach.AS = reach.AS
+			unreach.Value = attrs.NewMPValue(unreach)
+		}
+		pfx := unreach.Prefixes()
+		pfx.Prefixes = append(pfx.Prefixes, reach.Prefixes().Prefixes...)
+	}
+
+	// drop everything else
+	u.Attrs.Each(func(i int, ac attrs.Code, at attrs.Attr) {
+		if ac != attrs.ATTR_MP_UNREACH {
+			u.Attrs.Drop(ac)
+		}
+	})
+
+	u.Msg.Modified()
+	return true
+}
+
 // String dumps u to JSON
 func (u *Update) String() string {
 	return string(u.ToJSON(nil))
@@ -271,6 +629,158 @@ func (u *Update) AS() afi.AS {
 	}
 }
 
+// Families returns all distinct address families referenced in u: IPv4 unicast
+// if u.Reach or u.Unreach is non-empty, followed by ATTR_MP_REACH and ATTR_MP_UNREACH.
+func (u *Update) Families() (afs []afi.AS) {
+	if u == nil || u.Msg.Upper != UPDATE {
+		return nil
+	}
+	if len(u.Reach) > 0 || len(u.Unreach) > 0 {
+		afs = append(afs, afi.AS_IPV4_UNICAST)
+	}
+	for _, ac := range []attrs.Code{attrs.ATTR_MP_REACH, attrs.ATTR_MP_UNREACH} {
+		if mp := u.MP(ac); mp != nil && !slices.Contains(afs, mp.AS) {
+			afs = append(afs, mp.AS)
+		}
+	}
+	return afs
+}
+
+// SplitMP moves ATTR_MP_REACH and ATTR_MP_UNREACH from u to dst, which is reset to
+// a new UPDATE with copies of all other attributes in u, except ATTR_NEXTHOP.
+// As a result, u is left with IPv4 unicast prefixes only. u must be parsed,
+// and dst must not reference u. Call Marshal() on both messages when done.
+func (u *Update) SplitMP(dst *Update, cps caps.Caps) error {
+	dst.Msg.Use(UPDATE)
+	dst.Reset()
+	dst.Msg.Dir = u.Msg.Dir
+	dst.Msg.Time = u.Msg.Time
+
+	// deep copy the common attributes via their wire format
+	u.Attrs.Each(func(i int, ac attrs.Code, at attrs.Attr) {
+		switch ac {
+		case attrs.ATTR_MP_REACH, attrs.ATTR_MP_UNREACH, attrs.ATTR_NEXTHOP:
+			return
+		}
+		dst.RawAttrs = at.Marshal(dst.RawAttrs, cps, u.Msg.Dir)
+	})
+	if err := dst.ParseAttrs(cps); err != nil {
+		return err
+	}
+
+	// move the MP-BGP attributes
+	for _, ac := range []attrs.Code{attrs.ATTR_MP_REACH, attrs.ATTR_MP_UNREACH} {
+		if at := u.Attrs.Get(ac); at != nil {
+			dst.Attrs.Set(ac, at)
+			u.Attrs.Drop(ac)
+		}
+	}
+
+	u.Msg.Modified()
+	dst.Msg.Modified()
+	return nil
+}
+
+// EoR returns the address family of u if it is an End-of-RIB marker (rfc4724/2),
+// ie. an empty UPDATE for IPv4 unicast, or an UPDATE with nothing but an empty
+// ATTR_MP_UNREACH for other families. Returns afi.AS_INVALID otherwise.
+func (u *Update) EoR() afi.AS {
+	if u == nil || u.Msg.Upper != UPDATE || len(u.Reach) > 0 || len(u.Unreach) > 0 {
+		return afi.AS_INVALID
+	}
+
+	switch u.Attrs.Len() {
+	case 0:
+		if len(u.RawAttrs) == 0 {
+			return afi.AS_IPV4_UNICAST
+		}
+	case 1:
+		mp := u.MP(attrs.ATTR_MP_UNREACH)
+		if mp == nil {
+			break
+		} else if pfx := mp.Prefixes(); pfx != nil && len(pfx.Prefixes) == 0 {
+			return mp.AS
+		} else if pfx == nil && len(mp.Data) == 0 {
+			return mp.AS
+		}
+	}
+
+	return afi.AS_INVALID
+}
+
+// UpdateKind classifies UPDATE messages, see Update.Kind
+type UpdateKind byte
+
+const (
+	KIND_EMPTY    UpdateKind = iota // no NLRI at all, eg. attributes only
+	KIND_ANNOUNCE                   // reachable NLRI only
+	KIND_WITHDRAW                   // unreachable NLRI only
+	KIND_MIXED                      // both reachable and unreachable NLRI
+	KIND_EOR                        // End-of-RIB marker
+)
+
+// String returns the kind name
+func (k UpdateKind) String() string {
+	switch k {
+	case KIND_EMPTY:
+		return "EMPTY"
+	case KIND_ANNOUNCE:
+		return "ANNOUNCE"
+	case KIND_WITHDRAW:
+		return "WITHDRAW"
+	case KIND_MIXED:
+		return "MIXED"
+	case KIND_EOR:
+		return "EOR"
+	default:
+		return "UpdateKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// Kind classifies u as an announcement, withdrawal, both, an End-of-RIB marker (see EoR),
+// or an UPDATE with no NLRI. MP-BGP NLRI other than IP prefixes (eg. Flowspec) count
+// if present. Attributes must already be parsed.
+func (u *Update) Kind() UpdateKind {
+	if u == nil || u.Msg.Upper != UPDATE {
+		return KIND_EMPTY
+	} else if u.EoR() != afi.AS_INVALID {
+		return KIND_EOR
+	}
+
+	reach := len(u.Reach) > 0 || mpHasNLRI(u.MP(attrs.ATTR_MP_REACH))
+	unreach := len(u.Unreach) > 0 || mpHasNLRI(u.MP(attrs.ATTR_MP_UNREACH))
+
+	switch {
+	case reach && unreach:
+		return KIND_MIXED
+	case reach:
+		return KIND_ANNOUNCE
+	case unreach:
+		return KIND_WITHDRAW
+	default:
+		return KIND_EMPTY
+	}
+}
+
+// mpHasNLRI returns true iff mp carries at least one NLRI, of any kind
+func mpHasNLRI(mp *attrs.MP) bool {
+	if mp == nil {
+		return false
+	}
+	switch v := mp.Value.(type) {
+	case *attrs.MPPrefixes:
+		return len(v.Prefixes) > 0
+	case *attrs.MPFlowspec:
+		return len(v.Rules) > 0 || len(mp.Data) > 0
+	case *attrs.MPEvpn:
+		return len(v.Routes) > 0 || len(mp.Data) > 0
+	case *attrs.MPBgpLS:
+		return len(v.NLRI) > 0 || len(mp.Data) > 0
+	default:
+		return len(mp.Data) > 0
+	}
+}
+
 // HasReach returns true iff u announces reachable NLRI (for any address family AF).
 func (u *Update) HasReach() bool {
 	if u == nil || u.Msg.Upper != UPDATE {
@@ -335,6 +845,111 @@ func (u *Update) AsPath() *attrs.Aspath {
 	}
 }
 
+// AS4Mismatch returns true iff u comes from a speaker without 4-byte ASN support,
+// ie. ATTR_ASPATH has AS_TRANS placeholders and ATTR_AS4PATH is present (rfc6793/4.2.3).
+func (u *Update) AS4Mismatch() bool {
+	if u == nil || u.Msg.Upper != UPDATE || !u.Attrs.Has(attrs.ATTR_AS4PATH) {
+		return false
+	}
+	ap := u.AsPath()
+	return ap != nil && ap.HasAsn(attrs.AS_TRANS, 0)
+}
+
+// FixAS4Path reconstructs the real ATTR_ASPATH from ATTR_AS4PATH iff u.AS4Mismatch(),
+// using attrs.MergeAS4Path. Returns true iff ATTR_ASPATH was replaced.
+// ATTR_AS4PATH is left intact, so that 2-byte ASN peers still get the same wire format.
+func (u *Update) FixAS4Path() bool {
+	if !u.AS4Mismatch() {
+		return false
+	}
+
+	ap := u.AsPath()
+	as4, _ := u.Attrs.Get(attrs.ATTR_AS4PATH).(*attrs.Aspath)
+	merged := attrs.MergeAS4Path(ap, as4)
+	if merged == ap {
+		return false
+	}
+
+	u.Attrs.Set(attrs.ATTR_ASPATH, merged)
+	u.Msg.Modified()
+	return true
+}
+
+// FixAS4Aggregator reconciles ATTR_AGGREGATOR with ATTR_AS4AGGREGATOR (rfc6793/4.2.3),
+// iff both are present and ATTR_AGGREGATOR has the AS_TRANS placeholder: the aggregator
+// is replaced with the 4-byte ASN and address from ATTR_AS4AGGREGATOR, which is dropped.
+// If ATTR_AGGREGATOR has a regular ASN, ATTR_AS4AGGREGATOR is dropped as stale.
+// Returns true iff u was modified.
+func (u *Update) FixAS4Aggregator() bool {
+	if u == nil || u.Msg.Upper != UPDATE {
+		return false
+	}
+
+	as4, ok := u.Attrs.Get(attrs.ATTR_AS4AGGREGATOR).(*attrs.Aggregator)
+	if !ok {
+		return false
+	}
+	if ag, ok := u.Attrs.Get(attrs.ATTR_AGGREGATOR).(*attrs.Aggregator); !ok {
+		return false // rfc6793/4.2.3: AS4_AGGREGATOR alone is ignored
+	} else if ag.ASN == attrs.AS_TRANS {
+		ag = u.Attrs.Own(attrs.ATTR_AGGREGATOR).(*attrs.Aggregator)
+		ag.ASN, ag.Addr = as4.ASN, as4.Addr
+	}
+
+	u.Attrs.Drop(attrs.ATTR_AS4AGGREGATOR)
+	u.Msg.Modified()
+	return true
+}
+
+// StripNextHop drops the legacy ATTR_NEXTHOP iff it is not authoritative,
+// ie. u has ATTR_MP_REACH and announces no IPv4 unicast prefixes in u.Reach.
+// Returns true iff the attribute was dropped. Call u.Msg.Modified() if needed.
+func (u *Update) StripNextHop() bool {
+	if u == nil || u.Msg.Upper != UPDATE {
+		return false
+	} else if len(u.Reach) > 0 || !u.Attrs.Has(attrs.ATTR_NEXTHOP) || u.MP(attrs.ATTR_MP_REACH) == nil {
+		return false
+	}
+
+	u.Attrs.Drop(attrs.ATTR_NEXTHOP)
+	return true
+}
+
+// SetNextHop rewrites the next-hop of reachable prefixes in u to addr, where the current
+// next-hop has the same IP version as addr: in ATTR_NEXTHOP (IPv4 only, iff u.Reach is non-empty)
+// and in ATTR_MP_REACH (eg. IPv6 unicast, or VPNv4 with the RD added on marshal).
+// For an IPv6 addr, ll sets the optional link-local next-hop (pass an invalid address to drop it).
+// Withdrawals are left untouched. Returns true iff u was modified; call u.Msg.Modified() if needed.
+func (u *Update) SetNextHop(addr, ll netip.Addr) (modified bool) {
+	if u == nil || u.Msg.Upper != UPDATE || !addr.IsValid() {
+		return false
+	}
+
+	// IPv4 unicast
+	if addr.Is4() && len(u.Reach) > 0 {
+		u.Attrs.Use(attrs.ATTR_NEXTHOP).(*attrs.IP).Addr = addr
+		modified = true
+	}
+
+	// MP-BGP
+	if u.MP(attrs.ATTR_MP_REACH).Prefixes() == nil {
+		return modified
+	}
+	mp := u.Attrs.Own(attrs.ATTR_MP_REACH).(*attrs.MP).Prefixes()
+	switch {
+	case !mp.NextHop.IsValid():
+		break
+	case mp.NextHop.Is4() && addr.Is4():
+		mp.NextHop = addr
+		modified = true
+	case mp.NextHop.Is6() && addr.Is6():
+		mp.NextHop = addr
+		mp.LinkLocal = ll
+		modified = true
+	}
+	return modified
+}
+
 // NextHop returns NEXT_HOP address, if possible.
 // Check nh.IsValid() before using the value.
 func (u *Update) NextHop() (nh netip.Addr) {
@@ -0,0 +1,927 @@
+package msg
+
+import (
+	"bytes"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/attrs"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/nlri"
+	"github.com/stretchr/testify/assert"
+)
+
+// newUpdate returns a new UPDATE message with given raw attributes and IPv4 NLRI
+func newUpdate(ats []byte, reach []byte) *Msg {
+	m := NewMsg()
+	m.Type = UPDATE
+	m.Data = append(m.Data, 0, 0) // no withdrawn
+	m.Data = msb.AppendUint16(m.Data, uint16(len(ats)))
+	m.Data = append(m.Data, ats...)
+	m.Data = append(m.Data, reach...)
+	return m
+}
+
+func TestUpdate_ParseWith(t *testing.T) {
+	var (
+		origin  = []byte{0x40, 0x01, 0x01, 0x00}
+		origin2 = []byte{0x40, 0x01, 0x01, 0x02}
+		unknown = []byte{0xc0, 0xc8, 0x01, 0xff}
+		badpath = []byte{0x40, 0x02, 0x08, 0x02, 0x01, 0xfd, 0xe8, 0x07, 0x01, 0x00, 0x01}
+		canon   = []byte{0x10, 0x0a, 0x01}             // 10.1.0.0/16
+		noncan  = []byte{0x0f, 0x0a, 0x01}             // 10.1.0.0/15
+		two     = []byte{0x08, 0x0a, 0x10, 0x0a, 0x01} // 10.0.0.0/8 10.1.0.0/16
+	)
+	cat := func(bufs ...[]byte) (ret []byte) {
+		for _, b := range bufs {
+			ret = append(ret, b...)
+		}
+		return
+	}
+
+	tests := []struct {
+		name    string
+		ats     []byte
+		reach   []byte
+		pol     *ParsePolicy
+		wantErr error
+		check   func(assert *assert.Assertions, u *Update)
+	}{
+		{"strict ok", cat(origin, unknown), canon, nil, nil, func(assert *assert.Assertions, u *Update) {
+			assert.True(u.Attrs.Has(200))
+			assert.Equal("10.1.0.0/16", u.Reach[0].String())
+		}},
+		{"strict dupe", cat(origin, origin2), nil, nil, ErrAttrDupe, nil},
+		{"dupe first", cat(origin, origin2), nil, &ParsePolicy{AttrDupe: DUPE_FIRST}, nil, func(assert *assert.Assertions, u *Update) {
+			assert.EqualValues(0, u.Attrs.Get(attrs.ATTR_ORIGIN).(*attrs.Origin).Origin)
+		}},
+		{"dupe last", cat(origin, origin2), nil, &ParsePolicy{AttrDupe: DUPE_LAST}, nil, func(assert *assert.Assertions, u *Update) {
+			assert.EqualValues(2, u.Attrs.Get(attrs.ATTR_ORIGIN).(*attrs.Origin).Origin)
+		}},
+		{"unknown drop", cat(origin, unknown), nil, &ParsePolicy{AttrUnknown: UNKNOWN_DROP}, nil, func(assert *assert.Assertions, u *Update) {
+			assert.False(u.Attrs.Has(200))
+			assert.Equal(1, u.Attrs.Len())
+		}},
+		{"unknown error", cat(origin, unknown), nil, &ParsePolicy{AttrUnknown: UNKNOWN_ERROR}, ErrAttrCode, nil},
+		{"strict aspath", badpath, nil, nil, attrs.ErrSegType, nil},
+		{"lenient aspath", badpath, nil, &LenientPolicy, nil, func(assert *assert.Assertions, u *Update) {
+			assert.Equal([]uint32{65000}, u.AsPath().Segments[0].List)
+		}},
+		{"non-canonical mask", origin, noncan, nil, nil, func(assert *assert.Assertions, u *Update) {
+			assert.Equal("10.0.0.0/15", u.Reach[0].String())
+		}},
+		{"non-canonical error", origin, noncan, &ParsePolicy{NonCanonical: PREFIX_ERROR}, nlri.ErrNonCanonical, nil},
+		{"max nlri ok", origin, two, &ParsePolicy{MaxNLRI: 2}, nil, nil},
+		{"max nlri", origin, two, &ParsePolicy{MaxNLRI: 1}, ErrMaxNLRI, nil},
+	}
+
+	var cps caps.Caps
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			u := &newUpdate(tt.ats, tt.reach).Update
+
+			err := u.ParseWith(cps, tt.pol)
+			if err == nil {
+				err = u.ParseAttrsWith(cps, tt.pol)
+			}
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(err, tt.wantErr)
+				return
+			}
+			assert.NoError(err)
+			if tt.check != nil {
+				tt.check(assert, u)
+			}
+		})
+	}
+}
+
+func TestUpdate_Withdraw(t *testing.T) {
+	assert := assert.New(t)
+	var cps caps.Caps
+
+	m := NewMsg()
+	p := nlri.FromPrefix(netip.MustParsePrefix("2001:db8::/32"))
+	m.Update.Withdraw(afi.AS_IPV6_UNICAST, p)
+	assert.NoError(m.Marshal(cps))
+	assert.Equal([]byte{
+		0x00, 0x00, // withdrawn routes
+		0x00, 0x0b, // attributes
+		0x80, 0x0f, 0x08, 0x00, 0x02, 0x01, 0x20, 0x20, 0x01, 0x0d, 0xb8, // MP_UNREACH
+	}, m.Data)
+
+	// parse it back
+	m2 := NewMsg()
+	m2.Type = UPDATE
+	m2.Data = m.Data
+	assert.NoError(m2.Update.Parse(cps))
+	assert.NoError(m2.Update.ParseAttrs(cps))
+	assert.Equal(1, m2.Update.Attrs.Len())
+	assert.False(m2.Update.HasReach())
+	assert.Equal([]nlri.NLRI{p}, m2.Update.GetUnreach(nil))
+
+	// IPv4 unicast
+	p = nlri.FromPrefix(netip.MustParsePrefix("10.0.0.0/8"))
+	m.Update.Withdraw(afi.AS_IPV4_UNICAST, p)
+	assert.NoError(m.Marshal(cps))
+	assert.Equal([]byte{0x00, 0x02, 0x08, 0x0a, 0x00, 0x00}, m.Data)
+}
+
+func TestUpdate_Clone(t *testing.T) {
+	assert := assert.New(t)
+	var cps caps.Caps
+
+	// a parsed UPDATE, from the wire
+	src := NewMsg()
+	assert.NoError(src.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"reach": [ "10.1.0.0/16" ],
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"ASPATH": { "flags": "T", "value": [ 65001, 65002 ] },
+			"NEXTHOP": { "flags": "T", "value": "192.0.2.1" },
+			"MP_REACH": { "flags": "O", "value": {
+				"af": "IPV6/UNICAST", "nexthop": "2001:db8::1", "prefixes": [ "2001:db8:1::/48" ] } }
+		} } ]`)))
+	assert.NoError(src.Marshal(cps))
+	m := NewMsg()
+	m.Type = UPDATE
+	m.Data = bytes.Clone(src.Data)
+	assert.NoError(m.Parse(cps))
+	want := m.String()
+
+	// modify the clone
+	m2 := m.Clone()
+	assert.Equal(want, m2.String())
+	u2 := &m2.Update
+	u2.Attrs.Get(attrs.ATTR_ASPATH).(*attrs.Aspath).Prepend(65000, 2)
+	u2.MP(attrs.ATTR_MP_REACH).Prefixes().Prefixes[0] = nlri.FromPrefix(netip.MustParsePrefix("2001:db8:2::/48"))
+	u2.Reach[0] = nlri.FromPrefix(netip.MustParsePrefix("10.2.0.0/16"))
+	m2.Modified()
+	assert.NoError(m2.Marshal(cps))
+	assert.Contains(m2.String(), `[65000,65000,65001,65002]`)
+	assert.Contains(m2.String(), `2001:db8:2::/48`)
+
+	// the original is not affected, including after buffer re-use
+	for i := range m2.Data {
+		m2.Data[i] = 0xff
+	}
+	assert.Equal(want, m.String())
+	if ap, ok := m.Update.Attrs.Get(attrs.ATTR_ASPATH).(*attrs.Aspath); assert.True(ok) {
+		assert.Equal(2, ap.Len())
+	}
+
+	// Update.Clone keeps the parent
+	u3 := m.Update.Clone()
+	assert.Same(m, u3.Msg)
+	u3.Attrs.Get(attrs.ATTR_ASPATH).(*attrs.Aspath).Prepend(65000, 1)
+	assert.Equal(want, m.String())
+
+	// other message types
+	n := NewNotify(NOTIFY_CEASE, CEASE_ADMIN_SHUTDOWN, nil)
+	assert.NoError(n.Notify.SetShutdownMessage("bye"))
+	assert.NoError(n.Marshal(cps))
+	n2 := n.Clone()
+	assert.Equal(n.String(), n2.String())
+	assert.Equal(n.Data, n2.Data)
+	n2.Data[0] = 0
+	assert.Equal(NOTIFY_CEASE, n.Data[0])
+}
+
+func TestUpdate_StripNextHop(t *testing.T) {
+	assert := assert.New(t)
+	var cps caps.Caps
+
+	m := NewMsg().Use(UPDATE)
+	u := &m.Update
+	nh := u.Attrs.Use(attrs.ATTR_NEXTHOP).(*attrs.IP)
+	nh.Addr = netip.MustParseAddr("192.0.2.1")
+	assert.False(u.StripNextHop(), "no MP_REACH")
+
+	mp := u.Attrs.Use(attrs.ATTR_MP_REACH).(*attrs.MP)
+	mp.AS = afi.AS_IPV6_UNICAST
+	mp.Value = attrs.NewMPValue(mp)
+	pfx := mp.Prefixes()
+	pfx.NextHop = netip.MustParseAddr("2001:db8::1")
+	pfx.Prefixes = append(pfx.Prefixes, nlri.FromPrefix(netip.MustParsePrefix("2001:db8:1::/48")))
+
+	// MP next-hop wins
+	assert.Equal(pfx.NextHop, u.NextHop())
+
+	// legacy is needed for IPv4 unicast
+	u.Reach = append(u.Reach, nlri.FromPrefix(netip.MustParsePrefix("10.0.0.0/8")))
+	assert.False(u.StripNextHop())
+	u.Reach = u.Reach[:0]
+
+	// legacy can be dropped
+	assert.True(u.StripNextHop())
+	assert.False(u.Attrs.Has(attrs.ATTR_NEXTHOP))
+	assert.Equal(pfx.NextHop, u.NextHop())
+	assert.NoError(m.Marshal(cps))
+}
+
+func TestUpdate_SetNextHop(t *testing.T) {
+	nh4, nh6, ll := netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("fe80::1")
+	tests := []struct {
+		name string
+		in   string
+		addr netip.Addr
+		ll   netip.Addr
+		out  string
+		nhl  int // expected MP next-hop length on the wire, if any
+	}{
+		{
+			"ipv4 unicast",
+			`{"reach":["10.0.0.0/8"],"attrs":{"NEXTHOP":"198.51.100.1"}}`,
+			nh4, netip.Addr{},
+			`{"reach":["10.0.0.0/8"],"attrs":{"NEXTHOP":{"flags":"T","value":"192.0.2.1"}}}`,
+			0,
+		},
+		{
+			"ipv6 with link-local",
+			`{"attrs":{"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::ff","prefixes":["2001:db8:1::/48"]}}}}`,
+			nh6, ll,
+			`{"attrs":{"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::1","link-local":"fe80::1","prefixes":["2001:db8:1::/48"]}}}}`,
+			32,
+		},
+		{
+			"vpnv4",
+			`{"attrs":{"MP_REACH":{"flags":"O","value":{"af":"IPV4/MPLS_VPN","nexthop":"198.51.100.1","prefixes":["label 100 65000:100:10.1.1.0/24"]}}}}`,
+			nh4, netip.Addr{},
+			`{"attrs":{"MP_REACH":{"flags":"O","value":{"af":"IPV4/MPLS_VPN","nexthop":"192.0.2.1","prefixes":["label 100 rd 65000:100 10.1.1.0/24"]}}}}`,
+			8 + 4,
+		},
+		{
+			"ipv6 untouched by ipv4 next-hop",
+			`{"attrs":{"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::ff","prefixes":["2001:db8:1::/48"]}}}}`,
+			nh4, netip.Addr{},
+			`{"attrs":{"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::ff","prefixes":["2001:db8:1::/48"]}}}}`,
+			16,
+		},
+		{
+			"withdraw untouched",
+			`{"unreach":["10.0.0.0/8"]}`,
+			nh4, netip.Addr{},
+			`{"unreach":["10.0.0.0/8"],"attrs":{}}`,
+			0,
+		},
+	}
+
+	var cps caps.Caps
+	for _, tt := range tests {
+		for _, wire := range []bool{false, true} {
+			name := tt.name
+			if wire {
+				name += " from wire"
+			}
+			t.Run(name, func(t *testing.T) {
+				testSetNextHop(t, cps, tt.in, wire, tt.addr, tt.ll, tt.out, tt.nhl)
+			})
+		}
+	}
+}
+
+// testSetNextHop runs Update.SetNextHop on UPDATE in, parsed from wire if requested,
+// and checks the result after a wire round-trip
+func testSetNextHop(t *testing.T, cps caps.Caps, in string, wire bool, addr, ll netip.Addr, out string, nhl int) {
+	m := NewMsg().Use(UPDATE)
+	if err := m.Update.FromJSON([]byte(in)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+
+	// start from a message parsed from the wire, referencing its buffer?
+	if wire {
+		if err := m.Marshal(cps); err != nil {
+			t.Fatalf("Marshal error = %v", err)
+		}
+		var buf bytes.Buffer
+		if _, err := m.WriteToCaps(&buf, cps); err != nil {
+			t.Fatalf("WriteTo error = %v", err)
+		}
+		m = NewMsg()
+		if _, err := m.FromBytes(buf.Bytes()); err != nil {
+			t.Fatalf("FromBytes error = %v", err)
+		}
+		if err := m.Parse(cps); err != nil {
+			t.Fatalf("Parse error = %v", err)
+		}
+	}
+
+	if m.Update.SetNextHop(addr, ll) {
+		m.Modified()
+	}
+	if err := m.Marshal(cps); err != nil {
+		t.Fatalf("Marshal error = %v", err)
+	}
+
+	// parse back from wire
+	var buf bytes.Buffer
+	if _, err := m.WriteToCaps(&buf, cps); err != nil {
+		t.Fatalf("WriteTo error = %v", err)
+	}
+	m2 := NewMsg()
+	if _, err := m2.FromBytes(buf.Bytes()); err != nil {
+		t.Fatalf("FromBytes error = %v", err)
+	}
+	if err := m2.Parse(cps); err != nil {
+		t.Fatalf("Parse error = %v", err)
+	}
+	assert.Equal(t, out, m2.Update.String())
+	if nhl > 0 {
+		assert.Len(t, m2.Update.MP(attrs.ATTR_MP_REACH).NH, nhl)
+	}
+}
+
+func TestUpdate_Normalize(t *testing.T) {
+	assert := assert.New(t)
+	var cps caps.Caps
+
+	m1 := NewMsg()
+	assert.NoError(m1.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"reach": [ "10.2.0.0/16", "10.1.0.0/16", "10.2.0.0/16" ],
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"ASPATH": { "flags": "T", "value": [ 65001 ] },
+			"NEXTHOP": { "flags": "T", "value": "192.0.2.1" },
+			"COMMUNITY": { "flags": "OT", "value": [ "65001:2", "65001:1", "65001:2" ] },
+			"LARGE_COMMUNITY": { "flags": "OT", "value": [ "65001:1:2", "65001:1:1" ] },
+			"MP_REACH": { "flags": "O", "value": {
+				"af": "IPV6/UNICAST", "nexthop": "2001:db8::1",
+				"prefixes": [ "2001:db8:2::/48", "2001:db8:1::/48" ] } }
+		} } ]`)))
+
+	m2 := NewMsg()
+	assert.NoError(m2.FromJSON([]byte(`[ "R", 2, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"reach": [ "10.1.0.0/16", "10.2.0.0/16" ],
+		"attrs": {
+			"MP_REACH": { "flags": "O", "value": {
+				"af": "IPV6/UNICAST", "nexthop": "2001:db8::1",
+				"prefixes": [ "2001:db8:1::/48", "2001:db8:2::/48" ] } },
+			"LARGE_COMMUNITY": { "flags": "OT", "value": [ "65001:1:1", "65001:1:2" ] },
+			"COMMUNITY": { "flags": "OT", "value": [ "65001:1", "65001:2" ] },
+			"NEXTHOP": { "flags": "T", "value": "192.0.2.1" },
+			"ASPATH": { "flags": "T", "value": [ 65001 ] },
+			"ORIGIN": { "flags": "T", "value": "IGP" }
+		} } ]`)))
+
+	// the raw bytes differ before normalization
+	assert.NoError(m1.Marshal(cps))
+	assert.NoError(m2.Marshal(cps))
+	assert.NotEqual(m1.Data, m2.Data)
+
+	assert.NoError(m1.Update.Normalize(cps))
+	assert.NoError(m2.Update.Normalize(cps))
+	assert.Equal(m1.Data, m2.Data)
+	assert.Len(m1.Update.Reach, 2)
+}
+
+func TestUpdate_SplitMP(t *testing.T) {
+	assert := assert.New(t)
+	var cps caps.Caps
+
+	m1 := NewMsg()
+	assert.NoError(m1.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"reach": [ "10.1.0.0/16" ],
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"ASPATH": { "flags": "T", "value": [ 65001 ] },
+			"NEXTHOP": { "flags": "T", "value": "192.0.2.1" },
+			"COMMUNITY": { "flags": "OT", "value": [ "65001:1" ] },
+			"MP_REACH": { "flags": "O", "value": {
+				"af": "IPV6/UNICAST", "nexthop": "2001:db8::1",
+				"prefixes": [ "2001:db8:1::/48" ] } }
+		} } ]`)))
+	assert.Equal([]afi.AS{afi.AS_IPV4_UNICAST, afi.AS_IPV6_UNICAST}, m1.Update.Families())
+
+	m2 := NewMsg()
+	assert.NoError(m1.Update.SplitMP(&m2.Update, cps))
+	assert.Equal([]afi.AS{afi.AS_IPV4_UNICAST}, m1.Update.Families())
+	assert.Equal([]afi.AS{afi.AS_IPV6_UNICAST}, m2.Update.Families())
+	assert.Equal(m1.Dir, m2.Dir)
+	assert.Equal(m1.Time, m2.Time)
+
+	// IPv4 part keeps NEXT_HOP, MP part does not
+	assert.True(m1.Update.Attrs.Has(attrs.ATTR_NEXTHOP))
+	assert.False(m2.Update.Attrs.Has(attrs.ATTR_NEXTHOP))
+	assert.False(m1.Update.Attrs.Has(attrs.ATTR_MP_REACH))
+	assert.Empty(m2.Update.Reach)
+
+	// common attributes are copies
+	for _, ac := range []attrs.Code{attrs.ATTR_ORIGIN, attrs.ATTR_ASPATH, attrs.ATTR_COMMUNITY} {
+		assert.True(m2.Update.Attrs.Has(ac), ac)
+		assert.NotSame(m1.Update.Attrs.Get(ac), m2.Update.Attrs.Get(ac), ac)
+	}
+
+	// both survive a round-trip through the wire format
+	for _, m := range []*Msg{m1, m2} {
+		assert.NoError(m.Marshal(cps))
+		m3 := NewMsg()
+		m3.Type = UPDATE
+		m3.Data = m.Data
+		assert.NoError(m3.Parse(cps))
+		assert.NoError(m3.Update.ParseAttrs(cps))
+		assert.Equal(m.Update.Families(), m3.Update.Families())
+	}
+	assert.Equal(netip.MustParseAddr("2001:db8::1"), m2.Update.NextHop())
+}
+
+func TestUpdate_TreatAsWithdraw(t *testing.T) {
+	assert := assert.New(t)
+	var cps caps.Caps
+
+	m := NewMsg()
+	assert.NoError(m.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"reach": [ "10.1.0.0/16" ],
+		"unreach": [ "10.2.0.0/16" ],
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"ASPATH": { "flags": "T", "value": [ 65001 ] },
+			"NEXTHOP": { "flags": "T", "value": "192.0.2.1" },
+			"MP_REACH": { "flags": "O", "value": {
+				"af": "IPV6/UNICAST", "nexthop": "2001:db8::1",
+				"prefixes": [ "2001:db8:1::/48" ] } }
+		} } ]`)))
+
+	u := &m.Update
+	assert.True(u.TreatAsWithdraw())
+	assert.False(u.HasReach())
+	assert.Equal(1, u.Attrs.Len())
+	assert.Equal([]nlri.NLRI{
+		nlri.FromPrefix(netip.MustParsePrefix("10.2.0.0/16")),
+		nlri.FromPrefix(netip.MustParsePrefix("10.1.0.0/16")),
+		nlri.FromPrefix(netip.MustParsePrefix("2001:db8:1::/48")),
+	}, u.GetUnreach(nil))
+	assert.NoError(m.Marshal(cps))
+
+	// MP_REACH and MP_UNREACH for different AFs
+	m2 := NewMsg()
+	assert.NoError(m2.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"attrs": {
+			"MP_REACH": { "flags": "O", "value": {
+				"af": "IPV6/UNICAST", "nexthop": "2001:db8::1",
+				"prefixes": [ "2001:db8:1::/48" ] } },
+			"MP_UNREACH": { "flags": "O", "value": {
+				"af": "IPV4/MULTICAST", "prefixes": [ "10.1.0.0/16" ] } }
+		} } ]`)))
+	assert.False(m2.Update.TreatAsWithdraw())
+
+	// MP_REACH and MP_UNREACH for the same AF are merged
+	m3 := NewMsg()
+	assert.NoError(m3.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"MP_REACH": { "flags": "O", "value": {
+				"af": "IPV6/UNICAST", "nexthop": "2001:db8::1",
+				"prefixes": [ "2001:db8:1::/48" ] } },
+			"MP_UNREACH": { "flags": "O", "value": {
+				"af": "IPV6/UNICAST", "prefixes": [ "2001:db8:2::/48" ] } }
+		} } ]`)))
+	assert.True(m3.Update.TreatAsWithdraw())
+	assert.Equal(KIND_WITHDRAW, m3.Update.Kind())
+	assert.NoError(m3.Marshal(cps))
+
+	// must survive the wire format
+	m4 := NewMsg()
+	m4.Type = UPDATE
+	m4.Data = m3.Data
+	assert.NoError(m4.Parse(cps))
+	assert.NoError(m4.Update.ParseAttrs(cps))
+	assert.Equal([]nlri.NLRI{
+		nlri.FromPrefix(netip.MustParsePrefix("2001:db8:2::/48")),
+		nlri.FromPrefix(netip.MustParsePrefix("2001:db8:1::/48")),
+	}, m4.Update.GetUnreach(nil))
+	assert.Equal(1, m4.Update.Attrs.Len())
+
+	// non-prefix NLRI can't be withdrawn this way
+	m5 := NewMsg()
+	assert.NoError(m5.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"attrs": {
+			"MP_REACH": { "flags": "O", "value": {
+				"af": "L2VPN/VPLS", "nh": "0xc0000201", "data": "0x001122334455" } }
+		} } ]`)))
+	assert.False(m5.Update.TreatAsWithdraw())
+	assert.Equal(KIND_ANNOUNCE, m5.Update.Kind())
+}
+
+func TestUpdate_FixAS4Path(t *testing.T) {
+	assert := assert.New(t)
+	var cps caps.Caps // no AS4
+
+	m := NewMsg()
+	assert.NoError(m.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"reach": [ "10.1.0.0/16" ],
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"ASPATH": { "flags": "T", "value": [ 100, 23456, 200, 23456 ] },
+			"NEXTHOP": { "flags": "T", "value": "192.0.2.1" },
+			"AS4PATH": { "flags": "OT", "value": [ 70000, 200, 80000 ] }
+		} } ]`)))
+	assert.NoError(m.Marshal(cps))
+	orig := append([]byte(nil), m.Data...)
+
+	u := &m.Update
+	assert.True(u.AS4Mismatch())
+	assert.True(u.FixAS4Path())
+	assert.False(u.AS4Mismatch())
+	assert.Equal([]uint32{100, 70000, 200, 80000}, u.AsPath().Segments[0].List)
+	assert.Equal(uint32(80000), u.AsPath().Origin())
+
+	// 2-byte peers get the same wire format
+	assert.NoError(m.Marshal(cps))
+	assert.Equal(orig, m.Data)
+
+	// AS4_PATH longer than AS_PATH: ignore
+	m2 := NewMsg()
+	assert.NoError(m2.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"attrs": {
+			"ASPATH": { "flags": "T", "value": [ 23456 ] },
+			"AS4PATH": { "flags": "OT", "value": [ 70000, 80000 ] }
+		} } ]`)))
+	assert.True(m2.Update.AS4Mismatch())
+	assert.False(m2.Update.FixAS4Path())
+}
+
+func TestUpdate_FixAS4Aggregator(t *testing.T) {
+	assert := assert.New(t)
+	var cps caps.Caps // no AS4
+
+	m := NewMsg()
+	assert.NoError(m.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"reach": [ "10.1.0.0/16" ],
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"ASPATH": { "flags": "T", "value": [ 100, 23456 ] },
+			"NEXTHOP": { "flags": "T", "value": "192.0.2.1" },
+			"AGGREGATOR": { "flags": "OT", "value": { "asn": 23456, "addr": "192.0.2.9" } },
+			"AS4AGGREGATOR": { "flags": "OT", "value": { "asn": 4200000001, "addr": "192.0.2.10" } }
+		} } ]`)))
+	assert.NoError(m.Marshal(cps))
+
+	// parse back from wire
+	m2 := NewMsg()
+	m2.Type = UPDATE
+	m2.Data = m.Data
+	assert.NoError(m2.Parse(cps))
+
+	u := &m2.Update
+	assert.True(u.FixAS4Aggregator())
+	assert.False(u.Attrs.Has(attrs.ATTR_AS4AGGREGATOR))
+	ag := u.Attrs.Get(attrs.ATTR_AGGREGATOR).(*attrs.Aggregator)
+	assert.Equal(uint32(4200000001), ag.ASN)
+	assert.Equal(netip.MustParseAddr("192.0.2.10"), ag.Addr)
+	assert.False(u.FixAS4Aggregator())
+
+	// 2-byte peers never see the 4-byte ASN truncated
+	assert.NoError(m2.Marshal(cps))
+	m4 := NewMsg()
+	m4.Type = UPDATE
+	m4.Data = m2.Data
+	assert.NoError(m4.Parse(cps))
+	assert.Equal(uint32(attrs.AS_TRANS), m4.Update.Attrs.Get(attrs.ATTR_AGGREGATOR).(*attrs.Aggregator).ASN)
+
+	// regular ASN in AGGREGATOR: AS4_AGGREGATOR is stale
+	m3 := NewMsg()
+	assert.NoError(m3.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"attrs": {
+			"AGGREGATOR": { "flags": "OT", "value": { "asn": 65001, "addr": "192.0.2.9" } },
+			"AS4AGGREGATOR": { "flags": "OT", "value": { "asn": 4200000001, "addr": "192.0.2.10" } }
+		} } ]`)))
+	assert.True(m3.Update.FixAS4Aggregator())
+	assert.False(m3.Update.Attrs.Has(attrs.ATTR_AS4AGGREGATOR))
+	assert.Equal(uint32(65001), m3.Update.Attrs.Get(attrs.ATTR_AGGREGATOR).(*attrs.Aggregator).ASN)
+}
+
+func TestUpdate_AddReachAF(t *testing.T) {
+	assert := assert.New(t)
+	var cps caps.Caps
+
+	var (
+		p4  = nlri.FromPrefix(netip.MustParsePrefix("10.1.0.0/16"))
+		p6  = nlri.FromPrefix(netip.MustParsePrefix("2001:db8:1::/48"))
+		p6b = nlri.FromPrefix(netip.MustParsePrefix("2001:db8:2::/48"))
+		nh4 = netip.MustParseAddr("192.0.2.1")
+		nh6 = netip.MustParseAddr("2001:db8::1")
+	)
+
+	m := NewMsg()
+	u := &m.Update
+	assert.NoError(u.AddReachAF(afi.AS_IPV4_UNICAST, nh4, p4))
+	assert.NoError(u.AddReachAF(afi.AS_IPV6_UNICAST, nh6, p6))
+	assert.NoError(u.AddReachAF(afi.AS_IPV6_UNICAST, netip.Addr{}, p6b))
+	assert.NoError(u.AddUnreachAF(afi.AS_IPV6_UNICAST, p6b))
+
+	// errors
+	assert.Error(u.AddReachAF(afi.AS_IPV4_UNICAST, nh6, p4))
+	assert.ErrorIs(u.AddReachAF(afi.AS_IPV4_FLOWSPEC, nh4), ErrValue)
+	assert.ErrorIs(u.AddUnreachAF(afi.AS_IPV4_FLOWSPEC), ErrValue)
+	assert.ErrorIs(u.AddReachAF(afi.AS_IPV4_UNICAST, nh4, p6), ErrValue)
+	assert.ErrorIs(u.AddReachAF(afi.AS_IPV4_VPN, nh4, p6), ErrValue)
+	assert.ErrorIs(u.AddReachAF(afi.AS_IPV4_MPLS, nh4, p4, p6), ErrValue)
+	assert.ErrorIs(u.AddReachAF(afi.AS_IPV6_UNICAST, nh6, p4), ErrValue)
+	assert.ErrorIs(u.AddReachAF(afi.AS_IPV6_UNICAST, nh4, p6), ErrValue)
+	assert.ErrorIs(u.AddUnreachAF(afi.AS_IPV6_UNICAST, p4), ErrValue)
+	assert.ErrorIs(u.AddUnreachAF(afi.AS_IPV4_UNICAST, nlri.NLRI{}), ErrValue)
+	p4long := p4
+	p4long.Labels = []uint32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10} // over 255 bits
+	assert.ErrorIs(u.AddReachAF(afi.AS_IPV4_VPN, nh4, p4long), ErrValue)
+
+	// check
+	assert.Equal([]nlri.NLRI{p4, p6, p6b}, u.GetReach(nil))
+	assert.Equal([]nlri.NLRI{p6b}, u.GetUnreach(nil))
+	assert.Equal(nh6, u.NextHop())
+	assert.Equal([]afi.AS{afi.AS_IPV4_UNICAST, afi.AS_IPV6_UNICAST}, u.Families())
+
+	// round-trip
+	assert.NoError(m.Marshal(cps))
+	m2 := NewMsg()
+	m2.Type = UPDATE
+	m2.Data = m.Data
+	assert.NoError(m2.Parse(cps))
+	assert.Equal([]nlri.NLRI{p4, p6, p6b}, m2.Update.GetReach(nil))
+	assert.Equal([]nlri.NLRI{p6b}, m2.Update.GetUnreach(nil))
+
+	// unsupported AF
+	m3 := NewMsg()
+	assert.ErrorIs(m3.Update.AddReachAF(afi.AS_IPV4_FLOWSPEC, nh4, p4), ErrUnsupported)
+	assert.False(m3.Update.Attrs.Has(attrs.ATTR_MP_REACH))
+
+	// IPv6 next-hop for IPv4 prefixes is fine in MP_REACH, rfc8950
+	m4 := NewMsg()
+	assert.NoError(m4.Update.AddReachAF(afi.AS_IPV4_MPLS, nh6, p4))
+	assert.Equal(nh6, m4.Update.NextHop())
+}
+
+func TestUpdate_Kind(t *testing.T) {
+	var cps caps.Caps
+	tests := []struct {
+		name string
+		json string
+		kind UpdateKind
+		eor  afi.AS
+	}{
+		{"ipv4 eor", `{}`, KIND_EOR, afi.AS_IPV4_UNICAST},
+		{"ipv6 eor", `{"attrs":{"MP_UNREACH":{"flags":"O","value":{"af":"IPV6/UNICAST","prefixes":[]}}}}`,
+			KIND_EOR, afi.AS_IPV6_UNICAST},
+		{"flowspec eor", `{"attrs":{"MP_UNREACH":{"flags":"O","value":{"af":"IPV4/FLOWSPEC","rules":[]}}}}`,
+			KIND_EOR, afi.AS_IPV4_FLOWSPEC},
+		{"empty", `{"attrs":{"ORIGIN":"IGP"}}`, KIND_EMPTY, afi.AS_INVALID},
+		{"empty mp unreach with attrs", `{"attrs":{"ORIGIN":"IGP","MP_UNREACH":{"flags":"O","value":{"af":"IPV6/UNICAST","prefixes":[]}}}}`,
+			KIND_EMPTY, afi.AS_INVALID},
+		{"announce", `{"reach":["10.0.0.0/8"],"attrs":{"ORIGIN":"IGP","NEXTHOP":"192.0.2.1"}}`, KIND_ANNOUNCE, afi.AS_INVALID},
+		{"announce mp", `{"attrs":{"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::1","prefixes":["2001:db8::/32"]}}}}`,
+			KIND_ANNOUNCE, afi.AS_INVALID},
+		{"withdraw", `{"unreach":["10.0.0.0/8"]}`, KIND_WITHDRAW, afi.AS_INVALID},
+		{"withdraw mp", `{"attrs":{"MP_UNREACH":{"flags":"O","value":{"af":"IPV6/UNICAST","prefixes":["2001:db8::/32"]}}}}`,
+			KIND_WITHDRAW, afi.AS_INVALID},
+		{"announce unknown af", `{"attrs":{"MP_REACH":{"flags":"O","value":{"af":"L2VPN/VPLS","nh":"0xc0000201","data":"0x001122334455"}}}}`,
+			KIND_ANNOUNCE, afi.AS_INVALID},
+		{"empty unknown af", `{"attrs":{"ORIGIN":"IGP","MP_REACH":{"flags":"O","value":{"af":"L2VPN/VPLS","nh":"0xc0000201","data":""}}}}`,
+			KIND_EMPTY, afi.AS_INVALID},
+		{"withdraw unknown af", `{"attrs":{"ORIGIN":"IGP","MP_UNREACH":{"flags":"O","value":{"af":"L2VPN/VPLS","data":"0x001122334455"}}}}`,
+			KIND_WITHDRAW, afi.AS_INVALID},
+		{"mixed", `{"reach":["10.0.0.0/8"],"attrs":{"NEXTHOP":"192.0.2.1","MP_UNREACH":{"flags":"O","value":{"af":"IPV6/UNICAST","prefixes":["2001:db8::/32"]}}}}`,
+			KIND_MIXED, afi.AS_INVALID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMsg()
+			m.Use(UPDATE)
+			if err := m.Update.FromJSON([]byte(tt.json)); err != nil {
+				t.Fatalf("FromJSON error = %v", err)
+			}
+
+			// round-trip through wire format
+			if err := m.Marshal(cps); err != nil {
+				t.Fatalf("Marshal error = %v", err)
+			}
+			m2 := NewMsg()
+			m2.Type = UPDATE
+			m2.Data = m.Data
+			if err := m2.Parse(cps); err != nil {
+				t.Fatalf("Parse error = %v", err)
+			}
+
+			for _, u := range []*Update{&m.Update, &m2.Update} {
+				if got := u.Kind(); got != tt.kind {
+					t.Errorf("Kind = %s, want %s", got, tt.kind)
+				}
+				if got := u.EoR(); got != tt.eor {
+					t.Errorf("EoR = %x, want %x", got, tt.eor)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdate_Intern(t *testing.T) {
+	var cps caps.Caps
+	pol := StrictPolicy
+	pol.Intern = NewInterner()
+
+	parse := func(js string) *Msg {
+		m := NewMsg()
+		m.Use(UPDATE)
+		if err := m.Update.FromJSON([]byte(js)); err != nil {
+			t.Fatalf("FromJSON error = %v", err)
+		}
+		if err := m.Marshal(cps); err != nil {
+			t.Fatalf("Marshal error = %v", err)
+		}
+		m2 := NewMsg()
+		m2.Type = UPDATE
+		m2.Data = m.Data
+		if err := m2.Update.ParseWith(cps, &pol); err != nil {
+			t.Fatalf("ParseWith error = %v", err)
+		}
+		if err := m2.Update.ParseAttrsWith(cps, &pol); err != nil {
+			t.Fatalf("ParseAttrsWith error = %v", err)
+		}
+		return m2
+	}
+
+	m1 := parse(`{"attrs":{"ORIGIN":"IGP","ASPATH":[1,2],"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::1","prefixes":["2001:db8:1::/48"]}}}}`)
+	m2 := parse(`{"attrs":{"ORIGIN":"IGP","ASPATH":[1,2],"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::1","prefixes":["2001:db8:2::/48"]}}}}`)
+	m3 := parse(`{"attrs":{"ORIGIN":"IGP","ASPATH":[1,3],"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::1","prefixes":["2001:db8:3::/48"]}}}}`)
+
+	if got := pol.Intern.Len(); got != 2 {
+		t.Errorf("Interner.Len = %d, want 2", got)
+	}
+	if m1.Update.AsPath() != m2.Update.AsPath() {
+		t.Errorf("identical AS_PATHs not shared")
+	}
+	if m1.Update.AsPath() == m3.Update.AsPath() {
+		t.Errorf("different AS_PATHs shared")
+	}
+	if m1.Update.MP(attrs.ATTR_MP_REACH) == m2.Update.MP(attrs.ATTR_MP_REACH) {
+		t.Errorf("MP_REACH shared")
+	}
+
+	// copy on write
+	m2.Update.Attrs.Use(attrs.ATTR_ASPATH).(*attrs.Aspath).Segments[0].List[0] = 100
+	if got := m1.Update.AsPath().String(); got != "[1,2]" {
+		t.Errorf("m1 AS_PATH = %s, want [1,2]", got)
+	}
+	if got := m2.Update.AsPath().String(); got != "[100,2]" {
+		t.Errorf("m2 AS_PATH = %s, want [100,2]", got)
+	}
+
+	// mp prefixes stay per message
+	if got := m2.Update.MP(attrs.ATTR_MP_REACH).Prefixes().Prefixes[0].String(); got != "2001:db8:2::/48" {
+		t.Errorf("m2 MP_REACH prefix = %s", got)
+	}
+}
+
+func TestInterner_Max(t *testing.T) {
+	in := NewInterner()
+	in.Max = 4
+	fp := func(i int) []byte { return []byte{byte(i)} }
+
+	// fill the current generation (2), then rotate
+	sets := make([]*attrs.Attrs, 6)
+	for i := range sets {
+		sets[i] = &attrs.Attrs{}
+	}
+	for i := range 3 {
+		if got := in.put(fp(i), sets[i]); got != sets[i] {
+			t.Errorf("put(%d) returned another set", i)
+		}
+	}
+	if got := in.Len(); got != 3 {
+		t.Errorf("Len = %d, want 3", got)
+	}
+
+	// 0 is in the old generation: moves to the current one
+	if got := in.get(fp(0)); got != sets[0] {
+		t.Errorf("get(0) = %p, want %p", got, sets[0])
+	}
+
+	// another rotation drops 1, the only one left in the old generation
+	in.put(fp(3), sets[3])
+	if got := in.Len(); got != 3 {
+		t.Errorf("Len = %d, want 3", got)
+	}
+	if got := in.get(fp(1)); got != nil {
+		t.Errorf("get(1) = %p, want nil", got)
+	}
+	for _, i := range []int{0, 2, 3} {
+		if got := in.get(fp(i)); got != sets[i] {
+			t.Errorf("get(%d) = %p, want %p", i, got, sets[i])
+		}
+	}
+
+	// never grows beyond Max
+	for i := range 100 {
+		in.put(fp(10+i), &attrs.Attrs{})
+		if got := in.Len(); got > in.Max {
+			t.Fatalf("Len = %d > Max = %d", got, in.Max)
+		}
+	}
+
+	in.Reset()
+	if got := in.Len(); got != 0 {
+		t.Errorf("Len after Reset = %d, want 0", got)
+	}
+}
+
+func TestInterner_AS4(t *testing.T) {
+	var cps2, cps4 caps.Caps
+	cps4.Use(caps.CAP_AS4)
+
+	// the same bytes are a different AS_PATH with AS4
+	raw := []byte{0x40, 0x02, 0x06, 0x02, 0x02, 0x00, 0x01, 0x00, 0x02}
+	in := NewInterner()
+	fp2, fp4 := in.fingerprint(raw, cps2), in.fingerprint(raw, cps4)
+	if fp2 == nil || fp4 == nil {
+		t.Fatalf("fingerprint = nil")
+	}
+	if string(fp2) == string(fp4) {
+		t.Errorf("fingerprints with and without AS4 are equal")
+	}
+}
+
+func TestUpdate_AddPath(t *testing.T) {
+	// L sends ADD_PATH for IPv4 (send only) and IPv6 (send+receive)
+	openL := NewMsg().Use(OPEN)
+	if err := openL.Open.FromJSON([]byte(`{"asn":65001,"caps":{"ADDPATH":["IPV4/UNICAST/SEND","IPV6/UNICAST/BIDIR"]}}`)); err != nil {
+		t.Fatalf("openL FromJSON error = %v", err)
+	}
+
+	// R sends ADD_PATH for IPv4 (receive only) and IPv6 (send+receive)
+	openR := NewMsg().Use(OPEN)
+	if err := openR.Open.FromJSON([]byte(`{"asn":65002,"caps":{"ADDPATH":["IPV4/UNICAST/RECEIVE","IPV6/UNICAST/BIDIR"]}}`)); err != nil {
+		t.Fatalf("openR FromJSON error = %v", err)
+	}
+
+	// negotiate from the L point of view
+	var cps caps.Caps
+	lcap := openL.Open.Caps.Get(caps.CAP_ADDPATH)
+	rcap := openR.Open.Caps.Get(caps.CAP_ADDPATH)
+	cps.Set(caps.CAP_ADDPATH, lcap.Intersect(rcap))
+
+	if !cps.AddPathEnabled(afi.AS_IPV4_UNICAST, dir.DIR_R) || cps.AddPathEnabled(afi.AS_IPV4_UNICAST, dir.DIR_L) {
+		t.Fatalf("IPv4 ADD_PATH should be enabled in DIR_R only")
+	}
+
+	js := `{"reach":["#1#10.0.0.0/8","#2#10.0.0.0/8"],"attrs":{"ORIGIN":"IGP","ASPATH":[65001],"NEXTHOP":"192.0.2.1",` +
+		`"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::1","prefixes":["#3#2001:db8::/32"]}}}}`
+
+	// L sending to R: path ids on the wire for both AFs
+	m := NewMsg()
+	m.Dir = dir.DIR_R
+	m.Use(UPDATE)
+	if err := m.Update.FromJSON([]byte(js)); err != nil {
+		t.Fatalf("FromJSON error = %v", err)
+	}
+	if err := m.Marshal(cps); err != nil {
+		t.Fatalf("Marshal error = %v", err)
+	}
+	reach := []byte{0, 0, 0, 1, 8, 10, 0, 0, 0, 2, 8, 10}
+	if got := m.Data[len(m.Data)-len(reach):]; !bytes.Equal(got, reach) {
+		t.Errorf("Reach on the wire = %x, want %x", got, reach)
+	}
+
+	m2 := NewMsg()
+	m2.Dir = dir.DIR_R
+	m2.Type = UPDATE
+	m2.Data = m.Data
+	if err := m2.Parse(cps); err != nil {
+		t.Fatalf("Parse error = %v", err)
+	}
+	if got := string(m2.Update.ToJSON(nil)); !strings.Contains(got, `"reach":["#1#10.0.0.0/8","#2#10.0.0.0/8"]`) ||
+		!strings.Contains(got, `"prefixes":["#3#2001:db8::/32"]`) {
+		t.Errorf("round-trip JSON = %s", got)
+	}
+	if id, ok := m2.Update.Reach[1].PathID(); !ok || id != 2 {
+		t.Errorf("Reach[1].PathID() = %d, %v, want 2, true", id, ok)
+	}
+
+	// R sending to L: no path ids for IPv4, but still for IPv6
+	m.Dir = dir.DIR_L
+	m.Modified()
+	if err := m.Marshal(cps); err != nil {
+		t.Fatalf("Marshal error = %v", err)
+	}
+	reach = []byte{8, 10, 8, 10}
+	if got := m.Data[len(m.Data)-len(reach):]; !bytes.Equal(got, reach) {
+		t.Errorf("Reach on the wire = %x, want %x", got, reach)
+	}
+
+	m3 := NewMsg()
+	m3.Dir = dir.DIR_L
+	m3.Type = UPDATE
+	m3.Data = m.Data
+	if err := m3.Parse(cps); err != nil {
+		t.Fatalf("Parse error = %v", err)
+	}
+	if _, ok := m3.Update.Reach[0].PathID(); ok {
+		t.Errorf("Reach[0] has a path id in DIR_L")
+	}
+	if got := string(m3.Update.ToJSON(nil)); !strings.Contains(got, `"prefixes":["#3#2001:db8::/32"]`) {
+		t.Errorf("round-trip JSON = %s", got)
+	}
+}
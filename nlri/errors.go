@@ -5,4 +5,6 @@ import "errors"
 var (
 	ErrLength = errors.New("invalid length")
 	ErrValue  = errors.New("invalid value")
+
+	ErrNonCanonical = errors.New("non-canonical prefix")
 )
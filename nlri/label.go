@@ -0,0 +1,171 @@
+package nlri
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// MPLS label values, as 20-bit numbers
+const (
+	LABEL_MAX      = 0xfffff // max label value
+	LABEL_WITHDRAW = 0x80000 // the rfc3107 withdraw marker (0x800000 on the wire)
+)
+
+// RD represents a VPN Route Distinguisher, rfc4364/4.2
+type RD uint64
+
+// RD types
+const (
+	RD_AS2  = 0 // 2-byte ASN + 4-byte value
+	RD_IPV4 = 1 // 4-byte IPv4 address + 2-byte value
+	RD_AS4  = 2 // 4-byte ASN + 2-byte value
+)
+
+// ReadLabel reads a 3-byte MPLS label entry from src,
+// returning the 20-bit label value and the bottom-of-stack bit.
+func ReadLabel(src []byte) (label uint32, bos bool, err error) {
+	if len(src) < 3 {
+		return 0, false, ErrLength
+	}
+	v := uint32(src[0])<<16 | uint32(src[1])<<8 | uint32(src[2])
+	return v >> 4, v&1 != 0, nil
+}
+
+// AppendLabel appends a 3-byte MPLS label entry to dst,
+// with the bottom-of-stack bit set iff bos is true.
+func AppendLabel(dst []byte, label uint32, bos bool) []byte {
+	v := (label & LABEL_MAX) << 4
+	if bos {
+		v |= 1
+	}
+	return append(dst, byte(v>>16), byte(v>>8), byte(v))
+}
+
+// ReadLabels reads an MPLS label stack from src into dst, until the bottom-of-stack bit
+// or the withdraw marker (stored as LABEL_WITHDRAW). Returns the number of bytes read.
+func ReadLabels(dst []uint32, src []byte) ([]uint32, int, error) {
+	for n := 0; ; n += 3 {
+		label, bos, err := ReadLabel(src[n:])
+		if err != nil {
+			return dst, n, err
+		}
+		dst = append(dst, label)
+		if bos || (label == LABEL_WITHDRAW && src[n+2] == 0) {
+			return dst, n + 3, nil
+		}
+	}
+}
+
+// AppendLabels appends an MPLS label stack to dst, with the bottom-of-stack bit
+// set on the last label. LABEL_WITHDRAW is written as the withdraw marker.
+func AppendLabels(dst []byte, labels []uint32) []byte {
+	for i, label := range labels {
+		if label == LABEL_WITHDRAW {
+			dst = append(dst, 0x80, 0x00, 0x00)
+		} else {
+			dst = AppendLabel(dst, label, i == len(labels)-1)
+		}
+	}
+	return dst
+}
+
+// ReadRD reads an 8-byte Route Distinguisher from src
+func ReadRD(src []byte) (RD, error) {
+	if len(src) < 8 {
+		return 0, ErrLength
+	}
+	return RD(msb.Uint64(src)), nil
+}
+
+// Marshal appends the wire representation of rd to dst
+func (rd RD) Marshal(dst []byte) []byte {
+	return msb.AppendUint64(dst, uint64(rd))
+}
+
+// Type returns the RD type
+func (rd RD) Type() uint16 {
+	return uint16(rd >> 48)
+}
+
+// String returns rd as text, eg. "65000:100" or "192.0.2.1:100"
+func (rd RD) String() string {
+	return string(rd.AppendTo(nil))
+}
+
+// AppendTo appends rd text representation to dst
+func (rd RD) AppendTo(dst []byte) []byte {
+	switch rd.Type() {
+	case RD_AS2:
+		dst = strconv.AppendUint(dst, uint64(rd>>32&0xffff), 10)
+		dst = append(dst, ':')
+		return strconv.AppendUint(dst, uint64(rd&0xffffffff), 10)
+	case RD_IPV4:
+		v := uint32(rd >> 16)
+		dst = netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}).AppendTo(dst)
+		dst = append(dst, ':')
+		return strconv.AppendUint(dst, uint64(rd&0xffff), 10)
+	case RD_AS4:
+		asn := uint64(rd >> 16 & 0xffffffff)
+		dst = strconv.AppendUint(dst, asn, 10)
+		if asn <= 0xffff {
+			dst = append(dst, 'L')
+		}
+		dst = append(dst, ':')
+		return strconv.AppendUint(dst, uint64(rd&0xffff), 10)
+	default:
+		dst = append(dst, `0x`...)
+		return strconv.AppendUint(dst, uint64(rd), 16)
+	}
+}
+
+// ParseRD parses text representation of a Route Distinguisher in s.
+// 4-byte ASNs need the "L" suffix unless larger than 65535, eg. "65000L:100".
+func ParseRD(s string) (RD, error) {
+	if hex, ok := strings.CutPrefix(s, "0x"); ok {
+		v, err := strconv.ParseUint(hex, 16, 64)
+		return RD(v), err
+	}
+
+	admin, val, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, ErrValue
+	}
+
+	// IPv4 address?
+	if addr, err := netip.ParseAddr(admin); err == nil {
+		if !addr.Is4() {
+			return 0, ErrValue
+		}
+		v, err := strconv.ParseUint(val, 10, 16)
+		if err != nil {
+			return 0, err
+		}
+		a := addr.As4()
+		ip := uint64(a[0])<<24 | uint64(a[1])<<16 | uint64(a[2])<<8 | uint64(a[3])
+		return RD(RD_IPV4<<48 | ip<<16 | v), nil
+	}
+
+	// ASN
+	as4 := false
+	if a, ok := strings.CutSuffix(admin, "L"); ok {
+		admin, as4 = a, true
+	}
+	asn, err := strconv.ParseUint(admin, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	if as4 || asn > 0xffff {
+		v, err := strconv.ParseUint(val, 10, 16)
+		if err != nil {
+			return 0, err
+		}
+		return RD(RD_AS4<<48 | asn<<16 | v), nil
+	} else {
+		v, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			return 0, err
+		}
+		return RD(RD_AS2<<48 | asn<<32 | v), nil
+	}
+}
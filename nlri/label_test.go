@@ -0,0 +1,74 @@
+package nlri
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    []byte
+		labels []uint32
+		n      int
+		err    error
+	}{
+		{"single BoS", []byte{0x00, 0x06, 0x41}, []uint32{100}, 3, nil},
+		{"stack", []byte{0x00, 0x06, 0x40, 0x00, 0x0c, 0x81, 0xff}, []uint32{100, 200}, 6, nil},
+		{"max", []byte{0xff, 0xff, 0xf1}, []uint32{LABEL_MAX}, 3, nil},
+		{"withdraw", []byte{0x80, 0x00, 0x00, 0x0a}, []uint32{LABEL_WITHDRAW}, 3, nil},
+		{"no BoS", []byte{0x00, 0x06, 0x40, 0x00}, []uint32{100}, 3, ErrLength},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			labels, n, err := ReadLabels(nil, tt.raw)
+			assert.Equal(tt.n, n)
+			if tt.err != nil {
+				assert.ErrorIs(err, tt.err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.labels, labels)
+			assert.Equal(tt.raw[:n], AppendLabels(nil, labels))
+		})
+	}
+
+	label, bos, err := ReadLabel([]byte{0x00, 0x06, 0x40})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 100, label)
+	assert.False(t, bos)
+}
+
+func TestRD(t *testing.T) {
+	tests := []struct {
+		str string
+		raw []byte
+	}{
+		{"65000:100", []byte{0, 0, 0xfd, 0xe8, 0, 0, 0, 100}},
+		{"192.0.2.1:100", []byte{0, 1, 192, 0, 2, 1, 0, 100}},
+		{"4200000000:100", []byte{0, 2, 0xfa, 0x56, 0xea, 0x00, 0, 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.str, func(t *testing.T) {
+			assert := assert.New(t)
+			rd, err := ReadRD(tt.raw)
+			assert.NoError(err)
+			assert.Equal(tt.str, rd.String())
+			assert.Equal(tt.raw, rd.Marshal(nil))
+
+			rd2, err := ParseRD(tt.str)
+			assert.NoError(err)
+			assert.Equal(rd, rd2)
+		})
+	}
+
+	// AS4 with small ASN needs the L suffix
+	rd, err := ParseRD("65000L:100")
+	assert.NoError(t, err)
+	assert.EqualValues(t, RD_AS4, rd.Type())
+	assert.Equal(t, "65000L:100", rd.String())
+}
@@ -1,7 +1,9 @@
 package nlri
 
 import (
+	"cmp"
 	"net/netip"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -15,12 +17,15 @@ import (
 var msb = binary.Msb
 
 // NLRI is Network Layer Reachability Information (RFC4271),
-// extended to support ADD_PATH (RFC7911).
+// extended to support ADD_PATH (RFC7911), labeled unicast (RFC8277) and MPLS VPNs (RFC4364).
 type NLRI struct {
 	netip.Prefix // the IP prefix
 
 	Options Options // controls optional features
 	Val     uint32  // additional NLRI value, eg. the ADD_PATH Path Identifier
+
+	RD     RD       // Route Distinguisher, only for VPN address families
+	Labels []uint32 // MPLS label stack, only for labeled address families
 }
 
 type Options = byte
@@ -36,6 +41,86 @@ func FromPrefix(p netip.Prefix) NLRI {
 	return NLRI{Prefix: p}
 }
 
+// HasLabels returns true iff prefixes in address family as carry an MPLS label stack,
+// ie. for labeled unicast (rfc8277) and VPN address families
+func HasLabels(as afi.AS) bool {
+	return as.Safi() == afi.SAFI_MPLS || as.Safi() == afi.SAFI_MPLS_VPN
+}
+
+// HasRD returns true iff prefixes in address family as carry a Route Distinguisher
+func HasRD(as afi.AS) bool {
+	return as.Safi() == afi.SAFI_MPLS_VPN
+}
+
+// PathID returns the ADD_PATH Path Identifier of p, and true iff present (rfc7911/3)
+func (p *NLRI) PathID() (id uint32, ok bool) {
+	if p.Options == OPT_ADDPATH {
+		return p.Val, true
+	}
+	return 0, false
+}
+
+// SetPathID sets the ADD_PATH Path Identifier of p to id
+func (p *NLRI) SetPathID(id uint32) {
+	p.Options = OPT_ADDPATH
+	p.Val = id
+}
+
+// Key returns a compact, deterministic key for p in address family as,
+// suitable for use in maps. The key includes the ADD_PATH Path Identifier,
+// iff present, and the Route Distinguisher, iff as is a VPN address family.
+// The MPLS labels are not part of the key. Keys of IPv4 and IPv6 prefixes never collide.
+func (p *NLRI) Key(as afi.AS) string {
+	var buf [4 + 1 + 4 + 8 + 1 + 16]byte
+
+	key := msb.AppendUint32(buf[:0], uint32(as))
+	if p.Options == OPT_ADDPATH {
+		key = append(key, OPT_ADDPATH)
+		key = msb.AppendUint32(key, p.Val)
+	} else {
+		key = append(key, 0)
+	}
+	if HasRD(as) {
+		key = p.RD.Marshal(key)
+	}
+
+	key = append(key, byte(p.Bits()))
+	key = append(key, p.Addr().AsSlice()...)
+	return string(key)
+}
+
+// Sort sorts prefixes in src by Route Distinguisher, address, prefix length, and
+// ADD_PATH Path Identifier, masking host bits and dropping duplicates. Returns the updated src slice.
+func Sort(src []NLRI) []NLRI {
+	for i := range src {
+		src[i].Prefix = src[i].Masked()
+	}
+	slices.SortFunc(src, compare)
+	return slices.CompactFunc(src, func(a, b NLRI) bool {
+		return compare(a, b) == 0
+	})
+}
+
+// compare compares a and b for Sort
+func compare(a, b NLRI) int {
+	if c := cmp.Compare(a.RD, b.RD); c != 0 {
+		return c
+	} else if c := a.Addr().Compare(b.Addr()); c != 0 {
+		return c
+	} else if c := cmp.Compare(a.Bits(), b.Bits()); c != 0 {
+		return c
+	}
+
+	var aval, bval int64 = -1, -1
+	if a.Options == OPT_ADDPATH {
+		aval = int64(a.Val)
+	}
+	if b.Options == OPT_ADDPATH {
+		bval = int64(b.Val)
+	}
+	return cmp.Compare(aval, bval)
+}
+
 // FindParent returns the first index i into parents
 // where parents[i] fully covers p, or -1 if not found.
 func (p *NLRI) FindParent(parents []NLRI) int {
@@ -68,52 +153,139 @@ func ToJSON(dst []byte, src []NLRI) []byte {
 		if i > 0 {
 			dst = append(dst, ',')
 		}
-		if p.Options == OPT_ADDPATH {
-			dst = append(dst, `"#`...)
-			dst = json.Uint32(dst, p.Val)
-			dst = append(dst, '#')
-		} else {
-			dst = append(dst, '"')
-		}
-		dst = p.Prefix.AppendTo(dst)
+		dst = append(dst, '"')
+		dst = p.appendTo(dst)
 		dst = append(dst, '"')
 	}
 	return append(dst, ']')
 }
 
-// FromJSON parses JSON representation of prefixes in src into dst
-func FromJSON(src []byte, dst []NLRI) ([]NLRI, error) {
-	err := json.ArrayEach(src, func(key int, buf []byte, typ json.Type) error {
-		var (
-			nlri NLRI
-			err  error
-			s    = json.S(buf)
-		)
+// appendTo appends text representation of p to dst, in the form of
+// "[#path-id#][label L1[,L2...] ][rd RD ]prefix", eg. "label 100 rd 65000:1 10.0.0.0/8".
+// The RD is skipped if zero.
+func (p *NLRI) appendTo(dst []byte) []byte {
+	if p.Options == OPT_ADDPATH {
+		dst = append(dst, '#')
+		dst = json.Uint32(dst, p.Val)
+		dst = append(dst, '#')
+	}
+	if len(p.Labels) > 0 {
+		dst = append(dst, `label `...)
+		for i, l := range p.Labels {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = json.Uint32(dst, l)
+		}
+		dst = append(dst, ' ')
+	}
+	if p.RD != 0 {
+		dst = append(dst, `rd `...)
+		dst = p.RD.AppendTo(dst)
+		dst = append(dst, ' ')
+	}
+	return p.Prefix.AppendTo(dst)
+}
+
+// parse parses text representation of p in s, as written by appendTo.
+// For IPv4 prefixes, it also accepts the "RD:prefix" form, eg. "65000:1:10.0.0.0/8",
+// which is ambiguous for IPv6 (eg. "100:1:2001:db8::/32" is a valid IPv6 prefix).
+func (p *NLRI) parse(s string) (err error) {
+	if len(s) == 0 {
+		return ErrValue
+	}
 
-		if len(s) == 0 {
-			return json.ErrValue
+	// starts with #? treat as add-path path identifier
+	if s[0] == '#' {
+		before, after, found := strings.Cut(s[1:], "#")
+		if !found || len(before) == 0 || len(after) == 0 {
+			return ErrValue
 		}
+		val, err := strconv.ParseUint(before, 10, 32)
+		if err != nil {
+			return err
+		}
+		p.Options = OPT_ADDPATH
+		p.Val = uint32(val)
+		s = after
+	}
 
-		// starts with #? treat as add-path path identifier
-		if s[0] == '#' {
-			before, after, found := strings.Cut(s[1:], "#")
-			if !found || len(before) == 0 || len(after) == 0 {
-				return json.ErrValue
-			}
-			val, err := strconv.ParseUint(before, 10, 32)
+	// MPLS labels?
+	if after, ok := strings.CutPrefix(s, "label "); ok {
+		list, after, found := strings.Cut(after, " ")
+		if !found {
+			return ErrValue
+		}
+		for _, v := range strings.Split(list, ",") {
+			l, err := strconv.ParseUint(v, 10, 32)
 			if err != nil {
 				return err
+			} else if l > LABEL_MAX {
+				return ErrValue
 			}
-			nlri.Options = OPT_ADDPATH
-			nlri.Val = uint32(val)
-			s = after
+			p.Labels = append(p.Labels, uint32(l))
 		}
+		s = after
+	}
 
-		nlri.Prefix, err = netip.ParsePrefix(s)
-		if err != nil {
+	// Route Distinguisher?
+	if after, ok := strings.CutPrefix(s, "rd "); ok {
+		rd, after, found := strings.Cut(after, " ")
+		if !found {
+			return ErrValue
+		}
+		if p.RD, err = ParseRD(rd); err != nil {
 			return err
 		}
+		p.Prefix, err = netip.ParsePrefix(after)
+		return err
+	}
+
+	// plain prefix?
+	p.Prefix, err = netip.ParsePrefix(s)
+	if err == nil {
+		return nil
+	}
+
+	// RD:prefix, only for IPv4 (no colon in the prefix)
+	i := strings.LastIndexByte(s, ':')
+	if i <= 0 {
+		return err
+	}
+	pfx, err := netip.ParsePrefix(s[i+1:])
+	if err != nil {
+		return err
+	} else if !pfx.Addr().Is4() {
+		return ErrValue
+	}
+	if p.RD, err = ParseRD(s[:i]); err != nil {
+		return err
+	}
+	p.Prefix = pfx
+	return nil
+}
 
+// FromString parses text representation of an NLRI in s, as written by ToString,
+// eg. "10.0.0.0/24", "rd 65000:100 10.0.0.0/24", or "label 24000 10.0.0.0/24".
+// For IPv4, it also accepts the "RD:prefix" form, eg. "65000:100:10.0.0.0/24".
+func FromString(s string) (p NLRI, err error) {
+	err = p.parse(s)
+	return p, err
+}
+
+// ToString returns text representation of p, which can be parsed back by FromString.
+// The RD is written as "rd RD prefix", which is unambiguous also for IPv6 prefixes.
+func (p *NLRI) ToString() string {
+	return string(p.appendTo(nil))
+}
+
+// FromJSON parses JSON representation of prefixes in src into dst
+func FromJSON(src []byte, dst []NLRI) ([]NLRI, error) {
+	err := json.ArrayEach(src, func(key int, buf []byte, typ json.Type) error {
+		var nlri NLRI
+		if err := nlri.parse(json.S(buf)); err != nil {
+			return err
+		}
 		dst = append(dst, nlri)
 		return nil
 	})
@@ -122,8 +294,16 @@ func FromJSON(src []byte, dst []NLRI) ([]NLRI, error) {
 
 // Unmarshal unmarshals src into prefix p
 func (p *NLRI) Unmarshal(src []byte, ipv6, addpath bool) (n int, err error) {
+	return p.unmarshal(src, ipv6, addpath, false, false)
+}
+
+// unmarshal unmarshals src into prefix p, optionally preceded by
+// an MPLS label stack (iff labels is true) and a Route Distinguisher (iff rd is true)
+func (p *NLRI) unmarshal(src []byte, ipv6, addpath, labels, rd bool) (n int, err error) {
 	// reset options, just in case
 	p.Options = 0
+	p.RD = 0
+	p.Labels = nil
 
 	// parse ADD_PATH Path Identifier?
 	if addpath {
@@ -137,10 +317,40 @@ func (p *NLRI) Unmarshal(src []byte, ipv6, addpath bool) (n int, err error) {
 	}
 
 	// prefix length in bits
+	if len(src) < 1 {
+		return n, ErrLength
+	}
 	l := int(src[0])
 	src = src[1:]
 	n++
-	if l > 128 || (!ipv6 && l > 32) {
+
+	// MPLS labels? rfc8277/2
+	if labels {
+		k := 0
+		p.Labels, k, err = ReadLabels(p.Labels, src[:min(len(src), (l+7)/8)])
+		if err != nil {
+			return n, err
+		}
+		src = src[k:]
+		n += k
+		l -= 8 * k
+	}
+
+	// Route Distinguisher? rfc4364/4.3.4
+	if rd {
+		if l < 64 {
+			return n, ErrLength
+		}
+		p.RD, err = ReadRD(src)
+		if err != nil {
+			return n, err
+		}
+		src = src[8:]
+		n += 8
+		l -= 64
+	}
+
+	if l < 0 || l > 128 || (!ipv6 && l > 32) {
 		return n, ErrValue
 	}
 
@@ -169,6 +379,8 @@ func Unmarshal(dst []NLRI, src []byte, as afi.AS, cps caps.Caps, dir dir.Dir) ([
 	var (
 		ipv6    = as.IsIPv6()
 		addpath = cps.AddPathEnabled(as, dir)
+		labels  = HasLabels(as)
+		rd      = HasRD(as)
 	)
 
 	for len(src) > 0 {
@@ -180,7 +392,7 @@ func Unmarshal(dst []NLRI, src []byte, as afi.AS, cps caps.Caps, dir dir.Dir) ([
 		}
 		p := &dst[l]
 
-		n, err := p.Unmarshal(src, ipv6, addpath)
+		n, err := p.unmarshal(src, ipv6, addpath, labels, rd)
 		if err != nil {
 			return dst, ErrLength
 		}
@@ -191,8 +403,100 @@ func Unmarshal(dst []NLRI, src []byte, as afi.AS, cps caps.Caps, dir dir.Dir) ([
 	return dst, nil
 }
 
+// CheckCanonical returns ErrNonCanonical iff any of the IP prefixes in src
+// has host bits set beyond its prefix length, or ErrLength on parse error.
+func CheckCanonical(src []byte, as afi.AS, cps caps.Caps, dir dir.Dir) error {
+	var (
+		addpath = cps.AddPathEnabled(as, dir)
+		labels  = HasLabels(as)
+		rd      = HasRD(as)
+		tmp     [4]uint32
+	)
+	for len(src) > 0 {
+		if addpath {
+			if len(src) < 5 {
+				return ErrLength
+			}
+			src = src[4:]
+		}
+
+		// prefix length in bits
+		l := int(src[0])
+		src = src[1:]
+
+		// skip the labels and RD
+		if labels {
+			_, k, err := ReadLabels(tmp[:0], src[:min(len(src), (l+7)/8)])
+			if err != nil {
+				return ErrLength
+			}
+			src = src[k:]
+			l -= 8 * k
+		}
+		if rd {
+			if l < 64 || len(src) < 8 {
+				return ErrLength
+			}
+			src = src[8:]
+			l -= 64
+		}
+		if l < 0 {
+			return ErrLength
+		}
+
+		// prefix length in bytes
+		b := l / 8
+		if l%8 != 0 {
+			b++
+		}
+		if len(src) < b {
+			return ErrLength
+		}
+
+		// any bits set after l in the last byte?
+		if l%8 != 0 && src[b-1]&(0xff>>(l%8)) != 0 {
+			return ErrNonCanonical
+		}
+
+		src = src[b:]
+	}
+	return nil
+}
+
 // Marshal marshals prefix p to dst
 func (p *NLRI) Marshal(dst []byte, addpath bool) []byte {
+	return p.marshal(dst, addpath, false, false)
+}
+
+// BitLen returns the value of the NLRI length field for p in address family as,
+// ie. the total number of bits in the prefix, its MPLS label stack, and its RD.
+// Prefixes with BitLen over 255 can't be encoded, eg. due to too many labels.
+func (p *NLRI) BitLen(as afi.AS) int {
+	return p.bitLen(HasLabels(as), HasRD(as))
+}
+
+// bitLen returns the total length of p in bits, see BitLen
+func (p *NLRI) bitLen(labels, rd bool) int {
+	tl := p.Bits()
+	if labels {
+		tl += 24 * max(1, len(p.Labels))
+	}
+	if rd {
+		tl += 64
+	}
+	return tl
+}
+
+// marshal marshals prefix p to dst, optionally preceded by
+// an MPLS label stack (iff labels is true) and a Route Distinguisher (iff rd is true).
+// If p has no labels, the rfc8277 withdraw marker is used instead.
+// Skips p if its total length does not fit in the length field.
+func (p *NLRI) marshal(dst []byte, addpath, labels, rd bool) []byte {
+	tl := p.bitLen(labels, rd)
+	if tl > 255 {
+		return dst // can't be encoded
+	}
+
 	if addpath {
 		if p.Options == OPT_ADDPATH {
 			dst = msb.AppendUint32(dst, p.Val)
@@ -206,20 +510,34 @@ func (p *NLRI) Marshal(dst []byte, addpath bool) []byte {
 	if l%8 != 0 {
 		b++
 	}
-	dst = append(dst, byte(l))
+	dst = append(dst, byte(tl))
+
+	if labels {
+		if len(p.Labels) > 0 {
+			dst = AppendLabels(dst, p.Labels)
+		} else {
+			dst = AppendLabels(dst, []uint32{LABEL_WITHDRAW})
+		}
+	}
+	if rd {
+		dst = p.RD.Marshal(dst)
+	}
 
 	return append(dst, p.Addr().AsSlice()[:b]...)
 }
 
-// Marshal marshals prefixes in src to dst
+// Marshal marshals prefixes in src to dst, skipping prefixes not in as
+// or too long to encode (see BitLen).
 func Marshal(dst []byte, src []NLRI, as afi.AS, cps caps.Caps, dir dir.Dir) []byte {
 	var (
 		ipv6    = as.IsIPv6()
 		addpath = cps.AddPathEnabled(as, dir)
+		labels  = HasLabels(as)
+		rd      = HasRD(as)
 	)
 	for _, p := range src {
 		if p.Addr().Is6() == ipv6 {
-			dst = p.Marshal(dst, addpath)
+			dst = p.marshal(dst, addpath, labels, rd)
 		}
 	}
 	return dst
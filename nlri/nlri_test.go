@@ -0,0 +1,142 @@
+package nlri
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNLRI_Key(t *testing.T) {
+	assert := assert.New(t)
+
+	v4 := FromPrefix(netip.MustParsePrefix("0.0.0.0/0"))
+	v6 := FromPrefix(netip.MustParsePrefix("::/0"))
+	assert.NotEqual(v4.Key(afi.AS_IPV4_UNICAST), v6.Key(afi.AS_IPV4_UNICAST), "v4 vs v6 same AF")
+	assert.NotEqual(v4.Key(afi.AS_IPV4_UNICAST), v6.Key(afi.AS_IPV6_UNICAST), "v4 vs v6")
+
+	v4 = FromPrefix(netip.MustParsePrefix("10.0.0.0/8"))
+	v6 = FromPrefix(netip.MustParsePrefix("::a00:0/8"))
+	assert.NotEqual(v4.Key(afi.AS_IPV4_UNICAST), v6.Key(afi.AS_IPV4_UNICAST), "v4 vs v6 same bytes")
+
+	// address family matters
+	assert.NotEqual(v4.Key(afi.AS_IPV4_UNICAST), v4.Key(afi.AS_IPV4_MULTICAST))
+
+	// deterministic
+	v4b := FromPrefix(netip.MustParsePrefix("10.0.0.0/8"))
+	assert.Equal(v4.Key(afi.AS_IPV4_UNICAST), v4b.Key(afi.AS_IPV4_UNICAST))
+
+	// path-id
+	ap1 := NLRI{Prefix: v4.Prefix, Options: OPT_ADDPATH, Val: 1}
+	ap2 := NLRI{Prefix: v4.Prefix, Options: OPT_ADDPATH, Val: 2}
+	ap0 := NLRI{Prefix: v4.Prefix, Options: OPT_ADDPATH, Val: 0}
+	assert.NotEqual(ap1.Key(afi.AS_IPV4_UNICAST), ap2.Key(afi.AS_IPV4_UNICAST))
+	assert.NotEqual(ap0.Key(afi.AS_IPV4_UNICAST), v4.Key(afi.AS_IPV4_UNICAST))
+
+	// user value is not part of the key
+	uv := NLRI{Prefix: v4.Prefix, Options: OPT_VALUE, Val: 123}
+	assert.Equal(v4.Key(afi.AS_IPV4_UNICAST), uv.Key(afi.AS_IPV4_UNICAST))
+
+	// RD is part of the VPN key, labels are not
+	rd1 := NLRI{Prefix: v4.Prefix, RD: RD_AS2<<48 | 65000<<32 | 1, Labels: []uint32{100}}
+	rd2 := NLRI{Prefix: v4.Prefix, RD: RD_AS2<<48 | 65000<<32 | 2, Labels: []uint32{100}}
+	rd1b := NLRI{Prefix: v4.Prefix, RD: rd1.RD, Labels: []uint32{200}}
+	assert.NotEqual(rd1.Key(afi.AS_IPV4_VPN), rd2.Key(afi.AS_IPV4_VPN))
+	assert.Equal(rd1.Key(afi.AS_IPV4_VPN), rd1b.Key(afi.AS_IPV4_VPN))
+}
+
+func TestNLRI_FromString(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		str    string
+		rd     RD
+		labels []uint32
+		pfx    string
+	}{
+		{"10.0.0.0/24", 0, nil, "10.0.0.0/24"},
+		{"rd 65000:100 10.0.0.0/24", RD_AS2<<48 | 65000<<32 | 100, nil, "10.0.0.0/24"},
+		{"rd 192.0.2.1:7 2001:db8::/32", RD_IPV4<<48 | 0xc0000201<<16 | 7, nil, "2001:db8::/32"},
+		{"label 24000 10.0.0.0/24", 0, []uint32{24000}, "10.0.0.0/24"},
+		{"label 100,200 rd 65000:100 10.0.0.0/24", RD_AS2<<48 | 65000<<32 | 100, []uint32{100, 200}, "10.0.0.0/24"},
+		{"label 100 rd 100:1 2001:db8::/32", RD_AS2<<48 | 100<<32 | 1, []uint32{100}, "2001:db8::/32"},
+		{"rd 65000L:1 2001:db8:1::/48", RD_AS4<<48 | 65000<<16 | 1, nil, "2001:db8:1::/48"},
+	}
+	for _, tt := range tests {
+		p, err := FromString(tt.str)
+		if !assert.NoError(err, tt.str) {
+			continue
+		}
+		assert.Equal(tt.rd, p.RD, tt.str)
+		assert.Equal(tt.labels, p.Labels, tt.str)
+		assert.Equal(tt.pfx, p.Prefix.String(), tt.str)
+		assert.Equal(tt.str, p.ToString())
+	}
+
+	// ToString -> FromString round-trip, eg. VPNv6 with a small RD
+	for _, p := range []NLRI{
+		{Prefix: netip.MustParsePrefix("2001:db8::/32"), RD: RD_AS2<<48 | 100<<32 | 1},
+		{Prefix: netip.MustParsePrefix("2001:db8::/32"), RD: RD_AS2<<48 | 100<<32 | 1, Labels: []uint32{16, 17}},
+		{Prefix: netip.MustParsePrefix("::/0"), RD: RD_IPV4<<48 | 0xc0000201<<16 | 7},
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), RD: RD_AS4<<48 | 4200000000<<16 | 1, Options: OPT_ADDPATH, Val: 5},
+	} {
+		s := p.ToString()
+		p2, err := FromString(s)
+		if assert.NoError(err, s) {
+			assert.Equal(p, p2, s)
+		}
+	}
+
+	// the legacy RD:prefix form, only for IPv4
+	p, err := FromString("65000:100:10.0.0.0/24")
+	if assert.NoError(err) {
+		assert.Equal(RD(RD_AS2<<48|65000<<32|100), p.RD)
+		assert.Equal("rd 65000:100 10.0.0.0/24", p.ToString())
+	}
+	p, err = FromString("100:1:2001:db8::/32") // a plain IPv6 prefix
+	if assert.NoError(err) {
+		assert.Zero(p.RD)
+	}
+
+	// malformed
+	for _, s := range []string{
+		"",
+		"65000:abc:10.0.0.0/24",
+		"65000:5000000000:10.0.0.0/24",
+		"300.0.0.1:1:10.0.0.0/24",
+		"label 24000",
+		"label 2000000 10.0.0.0/24",
+		"rd 65000:100",
+		"rd 65000:abc 2001:db8::/32",
+		"192.0.2.1:5:2001:db8::/32",
+	} {
+		_, err := FromString(s)
+		assert.Error(err, s)
+	}
+}
+
+func TestNLRI_MarshalTooLong(t *testing.T) {
+	assert := assert.New(t)
+	var cps caps.Caps
+
+	// VPNv4 /32: 32 + 64 bits, plus 24 bits per label
+	ok := NLRI{Prefix: netip.MustParsePrefix("10.0.0.1/32"), RD: RD(RD_AS2<<48 | 65000<<32 | 1), Labels: []uint32{1, 2, 3, 4, 5, 6}}
+	long := NLRI{Prefix: netip.MustParsePrefix("10.0.0.2/32"), RD: ok.RD, Labels: []uint32{1, 2, 3, 4, 5, 6, 7}}
+	assert.Equal(240, ok.BitLen(afi.AS_IPV4_VPN))
+	assert.Equal(264, long.BitLen(afi.AS_IPV4_VPN))
+	assert.Equal(32, long.BitLen(afi.AS_IPV4_UNICAST))
+
+	// the prefix over 255 bits must be skipped, not wrapped
+	buf := Marshal(nil, []NLRI{ok, long}, afi.AS_IPV4_VPN, cps, dir.DIR_L)
+	assert.Len(buf, 1+30)
+	assert.EqualValues(240, buf[0])
+
+	got, err := Unmarshal(nil, buf, afi.AS_IPV4_VPN, cps, dir.DIR_L)
+	if assert.NoError(err) && assert.Len(got, 1) {
+		assert.Equal(ok.Prefix, got[0].Prefix)
+		assert.Equal(ok.Labels, got[0].Labels)
+	}
+}
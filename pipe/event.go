@@ -20,12 +20,15 @@ var (
 	// pipe is about to stop
 	EVENT_STOP = "bgpfix/pipe.STOP"
 
-	// could not parse the message before its callback
+	// could not parse the message before its callback (value: offending bytes, if known)
 	EVENT_PARSE = "bgpfix/pipe.PARSE"
 
 	// valid OPEN with a bigger message timestamp (seconds) made it to output
 	EVENT_OPEN = "bgpfix/pipe.OPEN"
 
+	// capabilities negotiated after OPENs made it to both sides (value: *caps.Caps)
+	EVENT_NEGOTIATED = "bgpfix/pipe.NEGOTIATED"
+
 	// KEEPALIVE with a bigger message timestamp (seconds) made it to output
 	EVENT_ALIVE = "bgpfix/pipe.ALIVE"
 
@@ -40,6 +43,12 @@ var (
 
 	// End-of-RIB for all AFs in Caps made it to ouput in given direction
 	EVENT_EOR = "bgpfix/pipe.EOR"
+
+	// no messages in given direction for Options.IdleTimeout
+	EVENT_IDLE = "bgpfix/pipe.IDLE"
+
+	// no messages in given direction for the HoldTimer hold time (value: last message time)
+	EVENT_HOLD_EXPIRED = "bgpfix/pipe.HOLD_EXPIRED"
 )
 
 // Event represents an arbitrary event for a BGP pipe.
@@ -179,13 +188,19 @@ func (p *Pipe) Event(et string, args ...any) *Event {
 	return ev
 }
 
-// sendEvent sends ev with given ctx; if noblock is true, it never blocks on full channel
+// sendEvent sends ev with given ctx; if noblock is true, it never blocks on full channel.
+// Returns false if not sent, eg. if the pipe is stopping.
 func (p *Pipe) sendEvent(ev *Event, ctx context.Context, noblock bool) (sent bool) {
-	defer func() { recover() }() // in case of closed p.events
-
 	ev.Pipe = p
 	ev.Time = time.Now().UTC()
 
+	// NB: Stop() closes p.evch only with p.evmu locked
+	p.evmu.RLock()
+	defer p.evmu.RUnlock()
+	if p.evdone {
+		return false
+	}
+
 	var ctxchan <-chan struct{}
 	if ctx != nil {
 		ctxchan = ctx.Done()
@@ -195,6 +210,8 @@ func (p *Pipe) sendEvent(ev *Event, ctx context.Context, noblock bool) (sent boo
 		select {
 		case <-ctxchan:
 			return false
+		case <-p.evstop:
+			return false
 		case p.evch <- ev:
 			return true
 		default:
@@ -204,6 +221,8 @@ func (p *Pipe) sendEvent(ev *Event, ctx context.Context, noblock bool) (sent boo
 		select {
 		case <-ctxchan:
 			return false
+		case <-p.evstop:
+			return false
 		case p.evch <- ev:
 			return true
 		}
@@ -0,0 +1,97 @@
+package pipe
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+)
+
+// HoldTimer maintains BGP session liveness in one direction of a Pipe,
+// eg. when proxying a session whose endpoints may go silent.
+type HoldTimer struct {
+	Pipe  *Pipe         // parent pipe
+	Dir   dir.Dir       // direction of injected KEEPALIVEs
+	Hold  time.Duration // hold time
+	Input *Input        // input for injected KEEPALIVEs
+
+	Sent    atomic.Uint64 // number of KEEPALIVEs injected
+	Expired atomic.Uint64 // number of times the hold timer expired
+
+	out   *Line // the line in Dir
+	in    *Line // the line in the opposite direction
+	sent  int64 // UNIX timestamp (ns) of the last injected KEEPALIVE (or start)
+	start int64 // UNIX timestamp (ns) when the timer started
+	fired int64 // the inbound LastMsg value when last expired
+}
+
+// HoldTimer adds a hold timer to p, active once the session is established:
+// it sends a KEEPALIVE in direction d every hold/3 of no other messages in d,
+// and emits EVENT_HOLD_EXPIRED if no message arrives in the opposite direction
+// for longer than hold. The hold time is corrected to at least 3 seconds.
+//
+// Must be called before p.Start().
+func (p *Pipe) HoldTimer(d dir.Dir, hold time.Duration) *HoldTimer {
+	ht := &HoldTimer{
+		Pipe:  p,
+		Dir:   d,
+		Hold:  max(hold, 3*time.Second),
+		Input: p.Options.AddInput(d),
+		out:   p.LineFor(d),
+		in:    p.LineFor(d.Flip()),
+	}
+	p.Options.OnEstablished(ht.onEstablished)
+	return ht
+}
+
+// onEstablished starts the timer
+func (ht *HoldTimer) onEstablished(ev *Event) bool {
+	go ht.run()
+	return false // unregister the handler
+}
+
+// run checks the timer periodically, until the pipe or the line is done.
+func (ht *HoldTimer) run() {
+	ticker := time.NewTicker(max(ht.Hold/30, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	ht.reset(time.Now())
+	for {
+		select {
+		case <-ht.Pipe.ctx.Done():
+			return
+		case <-ht.out.done:
+			return
+		case now := <-ticker.C:
+			ht.tick(now)
+		}
+	}
+}
+
+// reset restarts the timer at now
+func (ht *HoldTimer) reset(now time.Time) {
+	ht.start = now.UnixNano()
+	ht.sent = ht.start
+	ht.fired = 0
+}
+
+// tick checks the timer at now
+func (ht *HoldTimer) tick(now time.Time) {
+	ts := now.UnixNano()
+
+	// nothing sent in Dir for hold/3?
+	if last := max(ht.out.LastMsg.Load(), ht.sent); ts-last >= int64(ht.Hold/3) {
+		ht.sent = ts
+		if ht.Input.WriteMsg(ht.Pipe.GetMsg().Use(msg.KEEPALIVE)) == nil {
+			ht.Sent.Add(1)
+		}
+	}
+
+	// nothing received for hold?
+	if last := max(ht.in.LastMsg.Load(), ht.start); last != ht.fired && ts-last > int64(ht.Hold) {
+		ht.fired = last
+		ht.Expired.Add(1)
+		ht.Pipe.Event(EVENT_HOLD_EXPIRED, ht.in.Dir, time.Unix(0, last))
+	}
+}
@@ -0,0 +1,79 @@
+package pipe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoldTimer(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewPipe(context.Background())
+	ht := p.HoldTimer(dir.DIR_R, 3*time.Second)
+	expired := make(chan *Event, 10)
+	p.Options.OnEvent(func(ev *Event) bool {
+		expired <- ev
+		return true
+	}, EVENT_HOLD_EXPIRED)
+	p.Start()
+	defer p.Stop()
+
+	// fake clock: drive ht.tick directly, the session is never established
+	base := time.Now()
+	at := func(d time.Duration) time.Time { return base.Add(d) }
+	ht.reset(base)
+
+	// wants exactly n KEEPALIVEs on output after tick at d
+	tick := func(d time.Duration, n int) {
+		t.Helper()
+		ht.tick(at(d))
+		for range n {
+			select {
+			case m := <-p.R.Out:
+				assert.Equal(msg.KEEPALIVE, m.Type)
+			case <-time.After(time.Second):
+				t.Fatalf("tick %s: KEEPALIVE not sent", d)
+			}
+		}
+		select {
+		case m := <-p.R.Out:
+			t.Fatalf("tick %s: unexpected %s", d, m.Type)
+		default:
+		}
+	}
+
+	// cadence: every hold/3
+	tick(500*time.Millisecond, 0)
+	tick(1000*time.Millisecond, 1)
+	tick(1500*time.Millisecond, 0)
+	tick(2000*time.Millisecond, 1)
+
+	// inbound message: no expiry
+	p.L.LastMsg.Store(at(2500 * time.Millisecond).UnixNano())
+	tick(3200*time.Millisecond, 1)
+	tick(5000*time.Millisecond, 1)
+	assert.Len(expired, 0)
+
+	// no inbound message for more than hold: expires once
+	tick(6000*time.Millisecond, 1)
+	tick(6500*time.Millisecond, 0)
+	select {
+	case ev := <-expired:
+		assert.Equal(dir.DIR_L, ev.Dir)
+	case <-time.After(time.Second):
+		t.Fatalf("EVENT_HOLD_EXPIRED not emitted")
+	}
+	assert.EqualValues(1, ht.Expired.Load())
+
+	// outbound message resets the keepalive timer
+	p.R.LastMsg.Store(at(6900 * time.Millisecond).UnixNano())
+	tick(7500*time.Millisecond, 0)
+	tick(7900*time.Millisecond, 1)
+	assert.EqualValues(6, ht.Sent.Load())
+	assert.EqualValues(1, ht.Expired.Load())
+}
@@ -1,14 +1,12 @@
 package pipe
 
 import (
+	"context"
 	"io"
-	"maps"
 	"slices"
 	"time"
 
 	"github.com/bgpfix/bgpfix/afi"
-	"github.com/bgpfix/bgpfix/attrs"
-	"github.com/bgpfix/bgpfix/caps"
 	"github.com/bgpfix/bgpfix/dir"
 	"github.com/bgpfix/bgpfix/msg"
 )
@@ -46,16 +44,40 @@ func (in *Input) attach(p *Pipe, l *Line) {
 	in.Dir = l.Dir
 	in.done = make(chan struct{})
 
-	// copy relevant callbacks to cbs
-	var cbs []*Callback
-	for _, cb := range p.Options.Callbacks {
+	// reference in in.cbs[type]
+	for _, cb := range in.callbacks(p.Options.Callbacks, l.Dir) {
+		// all types?
+		if len(cb.Types) == 0 {
+			for i := range in.cbs {
+				in.cbs[i] = append(in.cbs[i], cb)
+			}
+			continue
+		}
+
+		// only select types
+		types := slices.Clone(cb.Types)
+		slices.Sort(types)
+		for _, t := range slices.Compact(types) {
+			if t < msg.Type(len(in.cbs)) {
+				in.cbs[t] = append(in.cbs[t], cb)
+			} else {
+				in.cbs[0] = append(in.cbs[0], cb)
+			}
+		}
+	}
+}
+
+// callbacks returns the callbacks in src that apply to this input in direction d,
+// sorted in the order of execution
+func (in *Input) callbacks(src []*Callback, d dir.Dir) (cbs []*Callback) {
+	for _, cb := range src {
 		// nil?
 		if cb == nil || cb.Func == nil {
 			continue
 		}
 
 		// direction match?
-		if cb.Dir != 0 && cb.Dir&l.Dir == 0 {
+		if cb.Dir != 0 && cb.Dir&d == 0 {
 			continue
 		}
 
@@ -91,27 +113,7 @@ func (in *Input) attach(p *Pipe, l *Line) {
 		}
 	})
 
-	// reference in in.cbs[type]
-	for _, cb := range cbs {
-		// all types?
-		if len(cb.Types) == 0 {
-			for i := range in.cbs {
-				in.cbs[i] = append(in.cbs[i], cb)
-			}
-			continue
-		}
-
-		// only select types
-		types := slices.Clone(cb.Types)
-		slices.Sort(types)
-		for _, t := range slices.Compact(types) {
-			if t < msg.Type(len(in.cbs)) {
-				in.cbs[t] = append(in.cbs[t], cb)
-			} else {
-				in.cbs[0] = append(in.cbs[0], cb)
-			}
-		}
-	}
+	return cbs
 }
 
 // prepare prepares metadata and context of m for processing in this Input.
@@ -202,6 +204,7 @@ input:
 		}
 
 		// m updates the UNIX timestamp for its type?
+		l.LastMsg.Store(time.Now().UnixNano())
 		t := m.Time.Unix()
 		switch m.Type {
 		case msg.OPEN:
@@ -231,31 +234,24 @@ input:
 			// an End-of-RIB marker?
 			if m.Len() < 32 && m.Parse(p.Caps) == nil {
 				// get Address Family
-				as := afi.AS_IPV4_UNICAST
-				if m.Len() == msg.HEADLEN+msg.UPDATE_MINLEN {
-					// must be IPv4 unicast
-				} else if a := m.Update.Attrs; a.Len() != 1 {
-					break // must have 1 attribute
-				} else if unreach, ok := a.Get(attrs.ATTR_MP_UNREACH).(*attrs.MP); !ok {
-					break // must ATTR_MP_UNREACH
-				} else {
-					as = unreach.AS
+				as := m.Update.EoR()
+				if as == afi.AS_INVALID {
+					break // not an EoR
 				}
 
 				// already seen?
 				if _, loaded := l.EoR.LoadOrStore(as, t); loaded {
 					break
 				} else { // it's new, announce
+					l.eorNotify()
 					p.Event(EVENT_EOR_AF, m.Dir, as.Afi(), as.Safi())
 				}
 
 				// tick afi off our todo list
 				if eor_todo == nil {
 					eor_todo = make(map[afi.AS]bool)
-					if c, ok := p.Caps.Get(caps.CAP_MP).(*caps.MP); ok {
-						maps.Copy(eor_todo, c.Proto)
-					} else {
-						eor_todo[afi.AS_IPV4_UNICAST] = true
+					for _, as := range p.EoRFamilies() {
+						eor_todo[as] = true
 					}
 				} else if len(eor_todo) == 0 {
 					break // already seen all required AFs
@@ -272,7 +268,12 @@ input:
 		// output closed?
 		if closed {
 			p.PutMsg(m) // drop on the floor
-		} else if l.WriteOutput(m) != nil {
+			continue
+		}
+
+		// copy to taps, write to output
+		l.tap(m)
+		if l.WriteOutput(m) != nil {
 			closed = true // start dropping from now on
 		}
 	}
@@ -307,10 +308,22 @@ func (in *Input) Write(src []byte) (int, error) {
 	return in.WriteFunc(src, nil)
 }
 
+// WriteCtx is the same as Input.Write(), but aborts a blocking write to in.In
+// when ctx is done, returning ctx.Err(). The unconsumed remainder of src, including
+// the message being written, is buffered for a re-try with Write(nil) or WriteCtx(ctx, nil).
+func (in *Input) WriteCtx(ctx context.Context, src []byte) (int, error) {
+	return in.writeFunc(ctx, src, nil)
+}
+
 // WriteFunc is the same as Input.Write(), but takes an optional callback function
 // to be called just before the message is accepted for processing. If the callback
 // returns false, the message is silently dropped instead.
 func (in *Input) WriteFunc(src []byte, cb CallbackFunc) (int, error) {
+	return in.writeFunc(nil, src, cb)
+}
+
+// writeFunc implements Write, WriteCtx and WriteFunc. ctx may be nil.
+func (in *Input) writeFunc(ctx context.Context, src []byte, cb CallbackFunc) (int, error) {
 	var (
 		p   = in.Pipe
 		now = time.Now().UTC()
@@ -336,6 +349,7 @@ func (in *Input) WriteFunc(src []byte, cb CallbackFunc) (int, error) {
 	for len(raw) > 0 {
 		// grab memory and parse raw[:off]
 		m := p.GetMsg()
+		todo := raw
 		off, err := m.FromBytes(raw)
 		switch err {
 		case nil:
@@ -362,7 +376,13 @@ func (in *Input) WriteFunc(src []byte, cb CallbackFunc) (int, error) {
 
 		// send
 		m.CopyData()
-		if err := in.WriteMsg(m); err != nil {
+		if ctx == nil {
+			err = in.WriteMsg(m)
+		} else if err = in.WriteMsgCtx(ctx, m); err != nil && err == ctx.Err() {
+			p.PutMsg(m)
+			raw = todo // buffer m for re-try
+		}
+		if err != nil {
 			return len(src), err
 		}
 	}
@@ -388,3 +408,27 @@ func (in *Input) WriteMsg(m *msg.Msg) (write_error error) {
 
 	return nil
 }
+
+// WriteMsgCtx is the same as WriteMsg, but aborts if ctx is done before m is written,
+// returning ctx.Err(). In such case, the caller still owns m, which is left untouched.
+// Unlike WriteMsg, the message metadata (including the sequence number) is assigned
+// by the input processor, ie. only after m was successfully written.
+func (in *Input) WriteMsgCtx(ctx context.Context, m *msg.Msg) (write_error error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// safe write to in.In
+	defer func() {
+		if recover() != nil {
+			write_error = ErrInClosed
+			in.Pipe.PutMsg(m)
+		}
+	}()
+	select {
+	case in.In <- m:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
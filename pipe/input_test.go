@@ -0,0 +1,103 @@
+package pipe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInput_WriteCtx(t *testing.T) {
+	assert := assert.New(t)
+
+	// block processing of the first message until released
+	release := make(chan struct{})
+	p := NewPipe(context.Background())
+	p.Options.OnMsg(func(m *msg.Msg) bool {
+		<-release
+		return true
+	}, dir.DIR_L)
+	in := p.Options.AddInput(dir.DIR_L, &Input{In: make(chan *msg.Msg)})
+	p.Start()
+	defer p.Stop()
+
+	// 3x KEEPALIVE on the wire
+	ka := []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x00, 0x13, 0x04,
+	}
+	var wire []byte
+	for range 3 {
+		wire = append(wire, ka...)
+	}
+
+	// in.In is never drained: must return promptly after cancel
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	n, err := in.WriteCtx(ctx, wire)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Equal(len(wire), n)
+	assert.Less(time.Since(start), time.Second)
+
+	// already cancelled
+	n, err = in.WriteCtx(ctx, nil)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Equal(0, n)
+
+	// release and re-try with the buffered remainder
+	close(release)
+	_, err = in.WriteCtx(context.Background(), nil)
+	assert.NoError(err)
+	for i := range 3 {
+		select {
+		case m := <-p.L.Out:
+			assert.Equal(msg.KEEPALIVE, m.Type)
+			assert.EqualValues(i+1, m.Seq) // no gaps after cancel
+		case <-time.After(time.Second):
+			t.Fatalf("KEEPALIVE %d not forwarded", i)
+		}
+	}
+}
+
+func TestInput_WriteMsgCtx(t *testing.T) {
+	assert := assert.New(t)
+
+	// block processing of the first message until released
+	release := make(chan struct{})
+	p := NewPipe(context.Background())
+	p.Options.OnMsg(func(m *msg.Msg) bool {
+		<-release
+		return true
+	}, dir.DIR_L)
+	in := p.Options.AddInput(dir.DIR_L, &Input{In: make(chan *msg.Msg)})
+	p.Start()
+	defer p.Stop()
+	assert.NoError(in.WriteMsg(p.GetMsg().Use(msg.KEEPALIVE)))
+
+	// in.In is blocked: cancelled before written
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	m := p.GetMsg().Use(msg.KEEPALIVE)
+	assert.ErrorIs(in.WriteMsgCtx(ctx, m), context.DeadlineExceeded)
+
+	// m must be left as before
+	assert.Zero(m.Seq)
+	assert.True(m.Time.IsZero())
+	assert.Nil(MsgContext(m).Input)
+
+	// re-try: no sequence number was taken
+	close(release)
+	assert.NoError(in.WriteMsg(m))
+	for i := range 2 {
+		select {
+		case m := <-p.L.Out:
+			assert.EqualValues(i+1, m.Seq)
+		case <-time.After(time.Second):
+			t.Fatalf("KEEPALIVE %d not forwarded", i)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package pipe
 import (
 	"bytes"
 	"io"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/bgpfix/bgpfix/afi"
 	"github.com/bgpfix/bgpfix/dir"
@@ -32,16 +34,29 @@ type Line struct {
 	// UNIX timestamp (seconds) of the last UPDATE message
 	LastUpdate atomic.Int64
 
+	// UNIX timestamp (nanoseconds, wall clock) of the last message of any type
+	LastMsg atomic.Int64
+
 	// the OPEN message that updated LastOpen
 	Open atomic.Pointer[msg.Open]
 
 	// UNIX timestamp (seconds) of the first EoR for given AF
 	EoR *xsync.MapOf[afi.AS, int64]
 
-	inputs []*Input      // all input processors, [0] is the default .Input
-	seq    atomic.Int64  // last seq number assigned
-	obuf   bytes.Buffer  // output buffer
-	done   chan struct{} // closed when done
+	// closed and replaced whenever a new AF is added to EoR
+	eorch atomic.Pointer[chan struct{}]
+
+	inputs []*Input                // all input processors, [0] is the default .Input
+	taps   atomic.Pointer[[]*Tap]  // message taps
+	last   atomic.Pointer[msg.Msg] // if set, the last message to write before closing Out
+	seq    atomic.Int64            // last seq number assigned
+	obuf   bytes.Buffer            // output buffer
+	done   chan struct{}           // closed when done
+
+	outmu   sync.RWMutex  // guards Out against close while writing
+	outstop chan struct{} // closed when Out is about to close
+	outonce sync.Once     // closes outstop
+	outdone bool          // true iff Out closed
 }
 
 // attach line inputs
@@ -49,6 +64,8 @@ func (l *Line) attach() {
 	p := l.Pipe
 	l.done = make(chan struct{})
 	l.EoR = xsync.NewMapOf[afi.AS, int64]()
+	eorch := make(chan struct{})
+	l.eorch.Store(&eorch)
 
 	// the default input
 	l.Input.attach(p, l)
@@ -69,16 +86,87 @@ func (l *Line) start() {
 		go in.process()
 	}
 
+	// watch for idle?
+	if l.Pipe.Options.IdleTimeout > 0 {
+		go l.idleWatch()
+	}
+
 	// close line output when all processors finish
 	go func() {
 		for _, in := range l.inputs {
 			in.Wait()
 		}
+		if m := l.last.Swap(nil); m != nil {
+			l.writeLast(m)
+		}
 		l.CloseOutput()
 		close(l.done)
 	}()
 }
 
+// writeLast writes m to l.Out as the very last message, after all inputs are done
+func (l *Line) writeLast(m *msg.Msg) {
+	p := l.Pipe
+	m.Seq = l.seq.Add(1)
+	m.Time = time.Now().UTC()
+	l.tap(m)
+
+	l.outmu.RLock()
+	defer l.outmu.RUnlock()
+	if l.outdone {
+		p.PutMsg(m) // l.Out already closed
+		return
+	}
+
+	select {
+	case l.Out <- m:
+	case <-l.outstop:
+		p.PutMsg(m)
+	case <-p.ctx.Done():
+		p.PutMsg(m)
+	}
+}
+
+// eorNotify wakes up all WaitEoR() calls after a new AF was added to l.EoR
+func (l *Line) eorNotify() {
+	eorch := make(chan struct{})
+	close(*l.eorch.Swap(&eorch))
+}
+
+// idleWatch emits EVENT_IDLE (and closes l if enabled) whenever no message
+// is seen for Options.IdleTimeout, until l is done.
+func (l *Line) idleWatch() {
+	var (
+		p       = l.Pipe
+		timeout = p.Options.IdleTimeout
+		ticker  = time.NewTicker(max(timeout/10, 10*time.Millisecond))
+		fired   int64 // the LastMsg value when last fired
+	)
+	defer ticker.Stop()
+
+	l.LastMsg.CompareAndSwap(0, time.Now().UnixNano())
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-p.ctx.Done():
+			return
+		case now := <-ticker.C:
+			last := l.LastMsg.Load()
+			if last == fired || now.Sub(time.Unix(0, last)) < timeout {
+				continue
+			}
+
+			fired = last
+			p.Event(EVENT_IDLE, l.Dir, time.Unix(0, last))
+			if p.Options.IdleClose {
+				l.Close()
+				return
+			}
+		}
+	}
+}
+
 // Wait blocks until all processing is done (returns true),
 // or aborts if the Pipe context is cancelled (returns false).
 func (l *Line) Wait() bool {
@@ -97,21 +185,79 @@ func (l *Line) Close() {
 	}
 }
 
-// CloseOutput safely closes the output channel.
+// CloseOutput safely closes the output channel, unblocking pending WriteOutput calls.
 func (l *Line) CloseOutput() {
-	defer func() { recover() }()
-	close(l.Out)
+	l.outonce.Do(func() { close(l.outstop) })
+
+	l.outmu.Lock()
+	defer l.outmu.Unlock()
+	if !l.outdone {
+		l.outdone = true
+		close(l.Out)
+	}
+}
+
+// WriteOutput safely sends m to l.Out, returning ErrOutClosed if closed.
+// On error, m is put back in the pool.
+func (l *Line) WriteOutput(m *msg.Msg) error {
+	l.outmu.RLock()
+	defer l.outmu.RUnlock()
+	if l.outdone {
+		l.Pipe.PutMsg(m)
+		return ErrOutClosed
+	}
+
+	select {
+	case l.Out <- m:
+		return nil
+	case <-l.outstop:
+		l.Pipe.PutMsg(m)
+		return ErrOutClosed
+	}
 }
 
-// WriteOutput safely sends m to l.Out, avoiding a panic if closed.
-func (l *Line) WriteOutput(m *msg.Msg) (write_error error) {
-	defer func() {
-		if recover() != nil {
-			write_error = ErrOutClosed
-			l.Pipe.PutMsg(m)
+// SendNotificationAndClose closes all inputs of l, waits until the messages already
+// queued in the inputs are processed, and writes a NOTIFICATION message with given
+// error code, subcode, and data to l.Out (bypassing the callbacks) as the very last
+// message before l.Out is closed. Then, it blocks until the reader of l.Out takes
+// the NOTIFICATION (which does not mean it was already sent to the peer, eg. it can
+// still be buffered in Line.Read). Returns ErrStopped if the Pipe stops before that,
+// or ErrOutClosed if l was already stopped.
+func (l *Line) SendNotificationAndClose(code, subcode byte, data []byte) error {
+	p := l.Pipe
+
+	// prepare the message, to be sent after the inputs finish
+	m := p.GetMsg()
+	m.Dir = l.Dir
+	m.Type = msg.NOTIFY
+	m.Data = append([]byte{code, subcode}, data...)
+	if old := l.last.Swap(m); old != nil {
+		p.PutMsg(old)
+	}
+
+	// stop reading new messages, wait for the inputs to finish
+	l.Close()
+	if !l.Wait() {
+		return ErrStopped
+	}
+
+	// not taken? l was already done
+	if m := l.last.Swap(nil); m != nil {
+		p.PutMsg(m)
+		return ErrOutClosed
+	}
+
+	// wait for the reader
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for len(l.Out) > 0 {
+		select {
+		case <-p.ctx.Done():
+			return ErrStopped
+		case <-ticker.C:
 		}
-	}()
-	l.Out <- m
+	}
+
 	return nil
 }
 
@@ -142,7 +288,7 @@ func (l *Line) Read(dst []byte) (int, error) {
 		}
 
 		// write m.Data to buf
-		_, err = m.WriteTo(buf)
+		_, err = m.WriteToCaps(buf, p.Caps)
 		p.PutMsg(m)
 
 		// what's next?
@@ -177,7 +323,7 @@ func (l *Line) WriteTo(w io.Writer) (int64, error) {
 		}
 
 		// write m.Data to w
-		k, err = m.WriteTo(w)
+		k, err = m.WriteToCaps(w, p.Caps)
 		p.PutMsg(m)
 		n += k
 
@@ -0,0 +1,109 @@
+package pipe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLine_SendNotificationAndClose(t *testing.T) {
+	assert := assert.New(t)
+
+	// block processing of UPDATEs until released
+	release := make(chan struct{})
+	p := NewPipe(context.Background())
+	p.Options.OnMsg(func(m *msg.Msg) bool {
+		<-release
+		return true
+	}, dir.DIR_L, msg.UPDATE)
+	p.Start()
+	defer p.Stop()
+
+	// queue 2 UPDATEs
+	for range 2 {
+		m := p.GetMsg().Use(msg.UPDATE)
+		assert.NoError(p.L.WriteMsg(m))
+	}
+
+	// send NOTIFICATION while the UPDATEs are still queued
+	errch := make(chan error, 1)
+	go func() { errch <- p.L.SendNotificationAndClose(6, 2, nil) }()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	// the NOTIFICATION must be the last message
+	var got []msg.Type
+	var last *msg.Msg
+	for m := range p.L.Out {
+		got = append(got, m.Type)
+		last = m
+	}
+	assert.Equal([]msg.Type{msg.UPDATE, msg.UPDATE, msg.NOTIFY}, got)
+	if assert.NotNil(last) {
+		assert.Equal([]byte{6, 2}, last.Data)
+		assert.EqualValues(3, last.Seq)
+	}
+
+	select {
+	case err := <-errch:
+		assert.NoError(err)
+	case <-time.After(time.Second):
+		t.Fatal("SendNotificationAndClose did not return")
+	}
+
+	// already stopped
+	assert.ErrorIs(p.L.SendNotificationAndClose(6, 2, nil), ErrOutClosed)
+}
+
+func TestLine_Idle(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewPipe(context.Background())
+	p.Options.IdleTimeout = 100 * time.Millisecond
+	idle := make(chan *Event, 10)
+	p.Options.OnEvent(func(ev *Event) bool {
+		if ev.Dir == dir.DIR_L {
+			idle <- ev
+		}
+		return true
+	}, EVENT_IDLE)
+	p.Start()
+	defer p.Stop()
+
+	go func() {
+		for range p.L.Out {
+		}
+	}()
+
+	// traffic flows: no EVENT_IDLE
+	for range 15 {
+		assert.NoError(p.L.WriteMsg(msg.NewMsg().Use(msg.KEEPALIVE)))
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.Len(idle, 0)
+
+	// traffic stops: EVENT_IDLE exactly once
+	select {
+	case ev := <-idle:
+		if vals, _ := ev.Value.([]any); assert.Len(vals, 1) {
+			last, _ := vals[0].(time.Time)
+			assert.WithinDuration(time.Now(), last, 300*time.Millisecond)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("EVENT_IDLE not received")
+	}
+	time.Sleep(300 * time.Millisecond)
+	assert.Len(idle, 0)
+
+	// traffic again, then idle again: fires again
+	assert.NoError(p.L.WriteMsg(msg.NewMsg().Use(msg.KEEPALIVE)))
+	select {
+	case <-idle:
+	case <-time.After(time.Second):
+		t.Fatal("EVENT_IDLE not received after new traffic")
+	}
+}
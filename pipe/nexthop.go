@@ -0,0 +1,22 @@
+package pipe
+
+import (
+	"net/netip"
+
+	"github.com/bgpfix/bgpfix/msg"
+)
+
+// SetNextHop returns a CallbackFunc that rewrites the next-hop of reachable prefixes
+// in UPDATE messages to addr, eg. for an inline next-hop-self proxy.
+// See msg.Update.SetNextHop for details; ll is the optional IPv6 link-local next-hop.
+//
+// Withdrawals and non-UPDATE messages are left untouched. Use with Options.OnMsg,
+// eg. p.Options.OnMsg(pipe.SetNextHop(nh4, netip.Addr{}), dir.DIR_L, msg.UPDATE).
+func SetNextHop(addr, ll netip.Addr) CallbackFunc {
+	return func(m *msg.Msg) bool {
+		if m.Update.SetNextHop(addr, ll) {
+			m.Modified()
+		}
+		return true
+	}
+}
@@ -0,0 +1,30 @@
+package pipe
+
+import (
+	"bytes"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/msg"
+)
+
+// RewriteOpen returns a CallbackFunc that lets rewrite modify the capabilities
+// of OPEN messages, eg. to strip capabilities a legacy peer cannot handle.
+// The message is marked as modified (and re-marshaled on output) iff the capabilities changed.
+// Since callbacks run before the pipe records OPENs, Line.Open will reflect the changes.
+//
+// Use with Options.OnMsg, eg. p.Options.OnMsg(pipe.RewriteOpen(f), dir.DIR_R, msg.OPEN).
+func RewriteOpen(rewrite func(cps *caps.Caps)) CallbackFunc {
+	return func(m *msg.Msg) bool {
+		if m.Upper != msg.OPEN {
+			return true
+		}
+
+		o := &m.Open
+		before := o.Caps.ToJSON(nil)
+		rewrite(&o.Caps)
+		if !bytes.Equal(before, o.Caps.ToJSON(nil)) {
+			m.Modified()
+		}
+		return true
+	}
+}
@@ -0,0 +1,57 @@
+package pipe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteOpen(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewPipe(context.Background())
+	p.Options.OnMsg(RewriteOpen(func(cps *caps.Caps) {
+		cps.Drop(caps.CAP_ROUTE_REFRESH)
+	}), dir.DIR_R, msg.OPEN)
+	p.Start()
+	defer p.Stop()
+
+	// an OPEN from the wire, with ROUTE_REFRESH
+	src := msg.NewMsg()
+	assert.NoError(src.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "OPEN", {
+		"bgp": 4, "asn": 65001, "id": "192.0.2.1", "hold": 90, "caps": { "MP": [ "IPV4/UNICAST" ] } } ]`)))
+	src.Open.Caps.Use(caps.CAP_ROUTE_REFRESH)
+	assert.NoError(src.Marshal(p.Caps))
+	m := p.GetMsg()
+	m.Type = msg.OPEN
+	m.Data = src.Data
+	assert.NoError(m.Parse(p.Caps))
+	assert.True(m.Open.HasCap(caps.CAP_ROUTE_REFRESH))
+	assert.NoError(p.R.WriteMsg(m))
+
+	var out *msg.Msg
+	select {
+	case out = <-p.R.Out:
+	case <-time.After(time.Second):
+		t.Fatalf("OPEN not forwarded")
+	}
+
+	// parse the forwarded OPEN back from the wire
+	assert.NoError(out.Marshal(p.Caps))
+	m2 := msg.NewMsg()
+	m2.Type = msg.OPEN
+	m2.Data = out.Data
+	assert.NoError(m2.Parse(p.Caps))
+	assert.False(m2.Open.HasCap(caps.CAP_ROUTE_REFRESH))
+	assert.True(m2.Open.HasCap(caps.CAP_MP))
+
+	// recorded as the direction's Open
+	if o := p.R.Open.Load(); assert.NotNil(o) {
+		assert.False(o.HasCap(caps.CAP_ROUTE_REFRESH))
+	}
+}
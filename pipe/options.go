@@ -6,7 +6,9 @@ import (
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/bgpfix/bgpfix/afi"
 	"github.com/bgpfix/bgpfix/dir"
 	"github.com/bgpfix/bgpfix/msg"
 	"github.com/rs/zerolog"
@@ -26,6 +28,14 @@ type Options struct {
 
 	Caps bool // overwrite pipe.Caps with the capabilities negotiated in OPEN messages?
 
+	IdleTimeout time.Duration // if non-zero, emit EVENT_IDLE for a Line with no messages for this long
+	IdleClose   bool          // if true, close the Line inputs on EVENT_IDLE
+
+	FixAS4Path  bool             // if true, reconstruct AS_PATH and AGGREGATOR from AS4_* in parsed UPDATEs, see msg.Update.FixAS4Path
+	ParsePolicy *msg.ParsePolicy // if non-nil, controls how UPDATEs are parsed, eg. &msg.LenientPolicy (default: msg.StrictPolicy)
+
+	EoRFamilies []afi.AS // if non-empty, overrides Caps.Families() as the AFs required for EVENT_EOR
+
 	Callbacks []*Callback // message callbacks
 	Handlers  []*Handler  // event handlers
 	Inputs    []*Input    // input processors
@@ -264,6 +274,11 @@ func (o *Options) OnEstablished(hdf HandlerFunc) *Handler {
 	return o.OnEvent(hdf, EVENT_ESTABLISHED)
 }
 
+// OnNegotiated request hdf to be called when BGP capabilities are negotiated (see Pipe.Negotiated).
+func (o *Options) OnNegotiated(hdf HandlerFunc) *Handler {
+	return o.OnEvent(hdf, EVENT_NEGOTIATED)
+}
+
 // OnParseError request hdf to be called on BGP message parse error.
 func (o *Options) OnParseError(hdf HandlerFunc) *Handler {
 	return o.OnEvent(hdf, EVENT_PARSE)
@@ -3,10 +3,15 @@ package pipe
 
 import (
 	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/bgpfix/bgpfix/afi"
 	"github.com/bgpfix/bgpfix/caps"
 	"github.com/bgpfix/bgpfix/dir"
 	"github.com/bgpfix/bgpfix/msg"
@@ -40,10 +45,15 @@ type Pipe struct {
 	wgstart sync.WaitGroup // 1 before start, 0 after start
 
 	evch   chan *Event           // pipe event input
+	evmu   sync.RWMutex          // guards evch against close while sending
+	evstop chan struct{}         // closed when evch is about to close
+	evdone bool                  // true iff evch closed
 	evwg   sync.WaitGroup        // event handler routine
 	events map[string][]*Handler // maps events to their handlers
 
 	msgpool *sync.Pool // pool for new messages
+
+	negotiated atomic.Pointer[caps.Caps] // capabilities negotiated in OPENs, if known
 }
 
 // NewPipe returns a new pipe, which can be configured through its Options.
@@ -63,7 +73,8 @@ func NewPipe(ctx context.Context) *Pipe {
 		Input: Input{
 			In: make(chan *msg.Msg, 10),
 		},
-		Out: make(chan *msg.Msg, 10),
+		Out:     make(chan *msg.Msg, 10),
+		outstop: make(chan struct{}),
 	}
 
 	p.L = &Line{
@@ -72,16 +83,21 @@ func NewPipe(ctx context.Context) *Pipe {
 		Input: Input{
 			In: make(chan *msg.Msg, 10),
 		},
-		Out: make(chan *msg.Msg, 10),
+		Out:     make(chan *msg.Msg, 10),
+		outstop: make(chan struct{}),
 	}
 
 	// NB: add internal handlers
 	p.events = map[string][]*Handler{
+		EVENT_OPEN: {&Handler{
+			Func: p.checkNegotiated,
+		}},
 		EVENT_ALIVE: {&Handler{
 			Func: p.checkEstablished,
 		}},
 	}
 	p.evch = make(chan *Event, 10)
+	p.evstop = make(chan struct{})
 
 	p.wgstart.Add(1)
 	return p
@@ -127,26 +143,9 @@ func (p *Pipe) checkEstablished(ev *Event) bool {
 		return true // strange, but keep trying
 	}
 
-	// find out common caps?
+	// overwrite p.Caps with common caps?
 	if p.Options.Caps {
-		// collect common caps into common
-		var common caps.Caps
-		ropen.Caps.Each(func(i int, cc caps.Code, rcap caps.Cap) {
-			// support on both ends?
-			lcap := lopen.Caps.Get(cc)
-			if lcap == nil {
-				return
-			}
-
-			// needs an intersection?
-			if icap := rcap.Intersect(lcap); icap != nil {
-				common.Set(cc, icap) // use the new intersection value
-			} else {
-				common.Set(cc, rcap) // just reference the received
-			}
-		})
-
-		// overwrite p.Caps
+		common := negotiate(lopen, ropen)
 		p.Caps.Clear()
 		p.Caps.SetFrom(common)
 		p.Info().Bytes("caps", p.Caps.ToJSON(nil)).Msg("negotiated session capabilities")
@@ -159,6 +158,84 @@ func (p *Pipe) checkEstablished(ev *Event) bool {
 	return false
 }
 
+// checkNegotiated is called whenever either direction gets a new OPEN message.
+// Once OPENs in both directions are known, it computes the negotiated capabilities
+// (see Negotiated) and emits EVENT_NEGOTIATED, each time an OPEN changes.
+func (p *Pipe) checkNegotiated(ev *Event) bool {
+	ropen, lopen := p.R.Open.Load(), p.L.Open.Load()
+	if ropen == nil || lopen == nil {
+		return true // not yet, keep trying
+	}
+
+	common := negotiate(lopen, ropen)
+	p.negotiated.Store(&common)
+	p.Event(EVENT_NEGOTIATED, ev.Dir, &common)
+	return true
+}
+
+// negotiate returns the capabilities common to lopen and ropen,
+// ie. the capabilities of the BGP session after exchanging these OPENs.
+func negotiate(lopen, ropen *msg.Open) caps.Caps {
+	return ropen.Caps.Intersect(lopen.Caps)
+}
+
+// Negotiated returns the capabilities negotiated in the last OPEN messages
+// seen in both directions, eg. common AFI/SAFIs, AS4 if both sides sent it,
+// and the resulting ADD-PATH modes. It is valid regardless of Options.Caps.
+// Returns an empty (invalid) Caps if OPENs in both directions are not known yet.
+func (p *Pipe) Negotiated() caps.Caps {
+	if cps := p.negotiated.Load(); cps != nil {
+		return *cps
+	}
+	return caps.Caps{}
+}
+
+// EoRFamilies returns the address families required for End-of-RIB:
+// Options.EoRFamilies if set, or the families of Negotiated() capabilities if known,
+// or the families in p.Caps otherwise (IPv4 unicast by default).
+func (p *Pipe) EoRFamilies() []afi.AS {
+	if len(p.Options.EoRFamilies) > 0 {
+		return p.Options.EoRFamilies
+	} else if cps := p.Negotiated(); cps.Valid() {
+		return cps.Families()
+	} else {
+		return p.Caps.Families()
+	}
+}
+
+// WaitEoR blocks until End-of-RIB markers for all EoRFamilies() have been seen
+// in messages flowing in direction d, eg. to detect when the initial table transfer
+// in that direction has finished. Must be called after p.Start().
+// Returns nil on success, or an error if ctx is done or the pipe stops first.
+func (p *Pipe) WaitEoR(d dir.Dir, ctx context.Context) error {
+	l := p.LineFor(d)
+	for {
+		eorch := *l.eorch.Load() // NB: before checking l.EoR
+
+		// seen all?
+		done := true
+		for _, as := range p.EoRFamilies() {
+			if _, ok := l.EoR.Load(as); !ok {
+				done = false
+				break
+			}
+		}
+		if done {
+			return nil
+		}
+
+		// wait for next EoR
+		select {
+		case <-eorch:
+			continue
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-p.ctx.Done():
+			return ErrStopped
+		}
+	}
+}
+
 // Start starts the Pipe in background and returns.
 func (p *Pipe) Start() {
 	if p.started.Swap(true) || p.stopped.Load() {
@@ -204,7 +281,11 @@ func (p *Pipe) Stop() {
 	}
 
 	// publish the event (ignore the global context)
-	go p.sendEvent(&Event{Type: EVENT_STOP}, nil, false)
+	stopsent := make(chan struct{})
+	go func() {
+		p.sendEvent(&Event{Type: EVENT_STOP}, nil, false)
+		close(stopsent)
+	}()
 
 	// close all inputs (if not done already)
 	p.L.Close()
@@ -221,7 +302,12 @@ func (p *Pipe) Stop() {
 	p.R.Wait()
 
 	// stop the event handler and wait for it to finish
+	<-stopsent
+	close(p.evstop) // unblock senders waiting on full evch
+	p.evmu.Lock()
+	p.evdone = true
 	close(p.evch)
+	p.evmu.Unlock()
 	p.evwg.Wait()
 }
 
@@ -243,6 +329,11 @@ func (p *Pipe) Stopped() bool {
 	return p.stopped.Load()
 }
 
+// Context returns the pipe context, which is cancelled when the pipe stops.
+func (p *Pipe) Context() context.Context {
+	return p.ctx
+}
+
 // GetMsg returns empty msg from pool, or a new msg object
 func (p *Pipe) GetMsg() (m *msg.Msg) {
 	if m, ok := p.msgpool.Get().(*msg.Msg); ok {
@@ -273,12 +364,26 @@ func (p *Pipe) PutMsg(m *msg.Msg) {
 	p.msgpool.Put(m)
 }
 
-// ParseMsg parses given message m (if needed), in the context of this Pipe.
-// In case of error, it emits EVENT_PARSE before returning.
+// ParseMsg parses given message m (if needed), in the context of this Pipe,
+// using Options.ParsePolicy for UPDATEs. In case of error, it emits EVENT_PARSE
+// before returning, with the offending bytes as the event value (if known,
+// see msg.ParseError).
 func (p *Pipe) ParseMsg(m *msg.Msg) error {
-	err := m.Parse(p.Caps)
+	err := m.ParseWith(p.Caps, p.Options.ParsePolicy)
 	if err != nil {
-		p.Event(EVENT_PARSE, m.Dir, m, err)
+		var pe *msg.ParseError
+		if errors.As(err, &pe) {
+			p.Event(EVENT_PARSE, m.Dir, m, err, pe.Data)
+		} else {
+			p.Event(EVENT_PARSE, m.Dir, m, err)
+		}
+	} else if p.Options.FixAS4Path && m.Type == msg.UPDATE {
+		if m.Update.FixAS4Path() {
+			p.Debug().Stringer("dir", m.Dir).Int64("seq", m.Seq).Msg("reconstructed AS_PATH from AS4_PATH")
+		}
+		if m.Update.FixAS4Aggregator() {
+			p.Debug().Stringer("dir", m.Dir).Int64("seq", m.Seq).Msg("reconciled AGGREGATOR with AS4_AGGREGATOR")
+		}
 	}
 	return err
 }
@@ -292,3 +397,27 @@ func (p *Pipe) LineFor(dst dir.Dir) *Line {
 		return p.R
 	}
 }
+
+// CallbackOrder returns the names of callbacks in the order they run for messages
+// of type typ processed by the default Input of the line for direction d (see LineFor),
+// for diagnostics. Callbacks with no Name are reported by their function name.
+// Dropped callbacks are skipped; callbacks disabled using Enabled are included.
+func (p *Pipe) CallbackOrder(d dir.Dir, typ msg.Type) (names []string) {
+	l := p.LineFor(d)
+	for _, cb := range l.Input.callbacks(p.Options.Callbacks, l.Dir) {
+		if cb.Dropped {
+			continue
+		} else if len(cb.Types) > 0 && !slices.Contains(cb.Types, typ) {
+			continue
+		}
+
+		if cb.Name != "" {
+			names = append(names, cb.Name)
+		} else if f := runtime.FuncForPC(reflect.ValueOf(cb.Func).Pointer()); f != nil {
+			names = append(names, f.Name())
+		} else {
+			names = append(names, "?")
+		}
+	}
+	return names
+}
@@ -0,0 +1,318 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/msg/msgtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipe_CallbackOrder(t *testing.T) {
+	assert := assert.New(t)
+	pass := func(m *msg.Msg) bool { return true }
+
+	p := NewPipe(context.Background())
+	o := &p.Options
+	o.AddCallback(pass, &Callback{Name: "post", Post: true})
+	o.AddCallback(pass, &Callback{Name: "late", Order: 10})
+	o.AddCallback(pass, &Callback{Name: "early", Order: -10, Types: []msg.Type{msg.UPDATE}})
+	o.AddCallback(pass, &Callback{Name: "pre", Pre: true, Order: 100})
+	o.AddCallback(pass, &Callback{Name: "left", Dir: dir.DIR_L})
+	o.AddCallback(pass, &Callback{Name: "dropped", Dropped: true})
+	o.AddCallback(pass, &Callback{Order: 5})
+
+	// unnamed callbacks are reported by function name
+	order := func(d dir.Dir, typ msg.Type) []string {
+		names := p.CallbackOrder(d, typ)
+		for i, name := range names {
+			if strings.HasSuffix(name, "TestPipe_CallbackOrder.func1") {
+				names[i] = "unnamed"
+			}
+		}
+		return names
+	}
+	assert.Equal([]string{"pre", "early", "unnamed", "late", "post"}, order(dir.DIR_R, msg.UPDATE))
+
+	// other message type, other direction
+	assert.Equal([]string{"pre", "left", "unnamed", "late", "post"}, order(dir.DIR_L, msg.KEEPALIVE))
+
+	// reversed
+	p.R.Reverse = true
+	assert.Equal([]string{"pre", "late", "unnamed", "early", "post"}, order(dir.DIR_R, msg.UPDATE))
+}
+
+func TestPipe_ParseError(t *testing.T) {
+	assert := assert.New(t)
+
+	evch := make(chan *Event, 1)
+	p := NewPipe(context.Background())
+	p.Options.OnMsg(func(m *msg.Msg) bool { return true }, dir.DIR_L, msg.UPDATE)
+	p.Options.OnParseError(func(ev *Event) bool {
+		evch <- ev
+		return true
+	})
+	in := p.Options.AddInput(dir.DIR_L)
+	p.Start()
+	defer p.Stop()
+
+	// UPDATE with an AS_PATH segment announcing 3 ASNs, but carrying only 2
+	aspath := []byte{0x40, 0x02, 0x06, 0x02, 0x03, 0xfd, 0xe9, 0xfd, 0xea}
+	wire := []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x00, 0x28, 0x02, // length 40, UPDATE
+		0x00, 0x00, // no withdrawn routes
+		0x00, 0x0d, // attributes length
+		0x40, 0x01, 0x01, 0x00, // ORIGIN IGP
+	}
+	wire = append(wire, aspath...)
+	wire = append(wire, 0x18, 0x0a, 0x00, 0x00) // 10.0.0.0/24
+	_, err := in.Write(wire)
+	assert.NoError(err)
+
+	select {
+	case ev := <-evch:
+		assert.Equal(dir.DIR_L, ev.Dir)
+		var pe *msg.ParseError
+		if assert.True(errors.As(ev.Error, &pe)) {
+			assert.Equal(aspath, pe.Data)
+		}
+		if vals, ok := ev.Value.([]any); assert.True(ok) && assert.Len(vals, 1) {
+			assert.Equal(aspath, vals[0])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("EVENT_PARSE not received")
+	}
+}
+
+func TestPipe_ParsePolicy(t *testing.T) {
+	// UPDATE with a duplicate ORIGIN
+	wire := []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x00, 0x2d, 0x02, // length 45, UPDATE
+		0x00, 0x00, // no withdrawn routes
+		0x00, 0x12, // attributes length
+		0x40, 0x01, 0x01, 0x00, // ORIGIN IGP
+		0x40, 0x01, 0x01, 0x02, // ORIGIN INCOMPLETE
+		0x40, 0x02, 0x00, // empty AS_PATH
+		0x40, 0x03, 0x04, 0xc0, 0x00, 0x02, 0x01, // NEXTHOP 192.0.2.1
+		0x18, 0x0a, 0x00, 0x00, // 10.0.0.0/24
+	}
+
+	run := func(pol *msg.ParsePolicy) (parsed bool) {
+		p := NewPipe(context.Background())
+		p.Options.ParsePolicy = pol
+		p.Options.OnMsg(func(m *msg.Msg) bool {
+			parsed = m.Upper == msg.UPDATE
+			return true
+		}, dir.DIR_L, msg.UPDATE)
+		in := p.Options.AddInput(dir.DIR_L)
+		p.Start()
+		defer p.Stop()
+
+		_, err := in.Write(wire)
+		assert.NoError(t, err)
+		p.L.Close()
+		for range p.L.Out {
+		}
+		return parsed
+	}
+
+	assert.False(t, run(nil), "StrictPolicy")
+	assert.True(t, run(&msg.LenientPolicy), "LenientPolicy")
+}
+
+func TestPipe_Negotiated(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewPipe(context.Background())
+	evch := make(chan *Event, 10)
+	p.Options.OnEvent(func(ev *Event) bool {
+		evch <- ev
+		return true
+	}, EVENT_NEGOTIATED)
+	p.Start()
+	defer p.Stop()
+
+	open := func(js string) *msg.Msg {
+		m := msg.NewMsg()
+		assert.NoError(m.FromJSON([]byte(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "OPEN", ` + js + ` ]`)))
+		assert.NoError(m.Marshal(p.Caps))
+		return m
+	}
+
+	// no OPENs yet
+	cps := p.Negotiated()
+	assert.False(cps.Valid())
+
+	// only one OPEN: nothing to negotiate
+	assert.NoError(p.L.WriteMsg(open(`{ "bgp": 4, "asn": 65001, "id": "192.0.2.1", "hold": 90,
+		"caps": { "MP": [ "IPV4/UNICAST", "IPV6/UNICAST" ], "ROUTE_REFRESH": true, "AS4": 65001 } }`)))
+	<-p.L.Out
+	select {
+	case ev := <-evch:
+		t.Fatalf("unexpected EVENT_NEGOTIATED: %v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+	cps = p.Negotiated()
+	assert.False(cps.Valid())
+
+	// the other OPEN: negotiated once
+	assert.NoError(p.R.WriteMsg(open(`{ "bgp": 4, "asn": 65002, "id": "192.0.2.2", "hold": 90,
+		"caps": { "MP": [ "IPV4/UNICAST" ], "AS4": 65002 } }`)))
+	<-p.R.Out
+	select {
+	case ev := <-evch:
+		assert.Equal(dir.DIR_R, ev.Dir)
+		if vals, _ := ev.Value.([]any); assert.Len(vals, 1) {
+			cps, _ := vals[0].(*caps.Caps)
+			if assert.NotNil(cps) {
+				assert.Equal([]afi.AS{afi.AS_IPV4_UNICAST}, cps.Families())
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("EVENT_NEGOTIATED not received")
+	}
+	select {
+	case ev := <-evch:
+		t.Fatalf("EVENT_NEGOTIATED received twice: %v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// the intersection of both OPENs
+	cps = p.Negotiated()
+	assert.True(cps.Valid())
+	assert.Equal([]afi.AS{afi.AS_IPV4_UNICAST}, cps.Families())
+	assert.True(cps.Has(caps.CAP_AS4))
+	assert.False(cps.Has(caps.CAP_ROUTE_REFRESH))
+}
+
+func TestPipe_WaitEoR(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewPipe(context.Background())
+	p.Options.EoRFamilies = []afi.AS{afi.AS_IPV4_UNICAST, afi.AS_IPV6_UNICAST}
+	p.Start()
+	defer p.Stop()
+
+	go func() {
+		for range p.L.Out {
+		}
+	}()
+
+	errch := make(chan error, 1)
+	go func() {
+		errch <- p.WaitEoR(dir.DIR_L, context.Background())
+	}()
+
+	// IPv4 only: still waiting
+	assert.NoError(p.L.WriteMsg(msgtest.EoR(afi.AS_IPV4_UNICAST)))
+	assert.Eventually(func() bool {
+		_, ok := p.L.EoR.Load(afi.AS_IPV4_UNICAST)
+		return ok
+	}, time.Second, time.Millisecond)
+	select {
+	case err := <-errch:
+		t.Fatalf("WaitEoR returned early: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// IPv6 too: done
+	assert.NoError(p.L.WriteMsg(msgtest.EoR(afi.AS_IPV6_UNICAST)))
+	select {
+	case err := <-errch:
+		assert.NoError(err)
+	case <-time.After(time.Second):
+		t.Fatal("WaitEoR did not return")
+	}
+
+	// already seen: returns at once
+	assert.NoError(p.WaitEoR(dir.DIR_L, context.Background()))
+
+	// nothing in the other direction: honors ctx cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		errch <- p.WaitEoR(dir.DIR_R, ctx)
+	}()
+	select {
+	case err := <-errch:
+		t.Fatalf("WaitEoR returned early: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	select {
+	case err := <-errch:
+		assert.ErrorIs(err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("WaitEoR did not return after cancel")
+	}
+}
+
+func TestPipe_StopEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	// a slow handler, so that evch fills up
+	var handled atomic.Int32
+	p := NewPipe(context.Background())
+	p.Options.OnEvent(func(ev *Event) bool {
+		time.Sleep(time.Millisecond)
+		handled.Add(1)
+		return true
+	}, "test")
+	p.Start()
+
+	// emit events while stopping
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				p.Event("test")
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	p.Stop()
+	wg.Wait()
+
+	// no events after stop
+	n := handled.Load()
+	assert.Less(n, int32(1000))
+	ev := p.Event("test")
+	<-ev.done
+	assert.Equal(n, handled.Load())
+}
+
+func TestLine_CloseOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	// nobody reads L.Out
+	p := NewPipe(context.Background())
+	p.Start()
+	defer p.Stop()
+	for range cap(p.L.Out) + 1 {
+		assert.NoError(p.L.WriteMsg(p.GetMsg().Use(msg.KEEPALIVE)))
+	}
+
+	// the input is blocked writing to L.Out: must unblock on close
+	assert.Eventually(func() bool { return len(p.L.Out) == cap(p.L.Out) }, time.Second, time.Millisecond)
+	p.L.CloseOutput()
+	p.L.CloseOutput() // no-op
+	assert.ErrorIs(p.L.WriteOutput(p.GetMsg().Use(msg.KEEPALIVE)), ErrOutClosed)
+
+	n := 0
+	for range p.L.Out {
+		n++
+	}
+	assert.Equal(cap(p.L.Out), n)
+}
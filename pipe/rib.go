@@ -0,0 +1,187 @@
+package pipe
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/attrs"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/nlri"
+)
+
+// Rib is an in-memory table of IP prefixes announced in UPDATE messages, eg. an Adj-RIB-In,
+// keyed by address family, prefix, Route Distinguisher and ADD_PATH Path Identifier
+// (see nlri.NLRI.Key). It is thread-safe.
+type Rib struct {
+	mu sync.RWMutex
+	db map[netip.Prefix]map[string]*RibEntry // prefix -> NLRI key -> entry
+	n  int                                   // number of entries
+}
+
+// RibEntry is a single route stored in Rib. Must be treated as read-only.
+type RibEntry struct {
+	AS        afi.AS      // address family
+	NLRI      nlri.NLRI   // prefix, with optional RD and ADD_PATH Path Identifier
+	NextHop   netip.Addr  // next-hop, only for ATTR_MP_REACH (otherwise in Attrs)
+	LinkLocal netip.Addr  // IPv6 link-local next-hop, if present
+	Attrs     attrs.Attrs // path attributes, without ATTR_MP_*
+	Time      time.Time   // message timestamp
+
+	key string // NLRI key in AS
+}
+
+// NewRib returns a new, empty Rib.
+func NewRib() *Rib {
+	return &Rib{db: make(map[netip.Prefix]map[string]*RibEntry)}
+}
+
+// ribKey returns the masked prefix of p and its key in address family as
+func ribKey(as afi.AS, p nlri.NLRI) (netip.Prefix, string) {
+	p.Prefix = p.Masked()
+	return p.Prefix, p.Key(as)
+}
+
+// Feed applies reachable and unreachable prefixes in UPDATE m to r,
+// for IPv4 unicast and IP prefixes in ATTR_MP_*. The message must already be parsed.
+// Stored attributes are deep copies, so m can be re-used afterwards.
+// Always returns true, so it can be used as a CallbackFunc.
+func (r *Rib) Feed(m *msg.Msg) bool {
+	u := &m.Update
+	if m.Upper != msg.UPDATE {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// withdrawals
+	for i := range u.Unreach {
+		r.del(afi.AS_IPV4_UNICAST, &u.Unreach[i])
+	}
+	if mp := u.MP(attrs.ATTR_MP_UNREACH).Prefixes(); mp != nil {
+		for i := range mp.Prefixes {
+			r.del(mp.AS, &mp.Prefixes[i])
+		}
+	}
+
+	// announcements
+	mp := u.MP(attrs.ATTR_MP_REACH).Prefixes()
+	if len(u.Reach) == 0 && (mp == nil || len(mp.Prefixes) == 0) {
+		return true
+	}
+	ats := u.Attrs.CloneExcept(attrs.ATTR_MP_REACH, attrs.ATTR_MP_UNREACH)
+	for _, p := range u.Reach {
+		r.put(&RibEntry{
+			AS:    afi.AS_IPV4_UNICAST,
+			NLRI:  p,
+			Attrs: ats.Share(),
+			Time:  m.Time,
+		})
+	}
+	if mp != nil {
+		for _, p := range mp.Prefixes {
+			p.Labels = append([]uint32(nil), p.Labels...)
+			r.put(&RibEntry{
+				AS:        mp.AS,
+				NLRI:      p,
+				NextHop:   mp.NextHop,
+				LinkLocal: mp.LinkLocal,
+				Attrs:     ats.Share(),
+				Time:      m.Time,
+			})
+		}
+	}
+
+	return true
+}
+
+// put stores e in r, replacing the existing entry (if any)
+func (r *Rib) put(e *RibEntry) {
+	var pfx netip.Prefix
+	pfx, e.key = ribKey(e.AS, e.NLRI)
+
+	db := r.db[pfx]
+	if db == nil {
+		db = make(map[string]*RibEntry, 1)
+		r.db[pfx] = db
+	}
+	if _, ok := db[e.key]; !ok {
+		r.n++
+	}
+	db[e.key] = e
+}
+
+// del drops the entry for p in address family as from r, if present
+func (r *Rib) del(as afi.AS, p *nlri.NLRI) {
+	pfx, key := ribKey(as, *p)
+	db := r.db[pfx]
+	if _, ok := db[key]; !ok {
+		return
+	}
+
+	delete(db, key)
+	r.n--
+	if len(db) == 0 {
+		delete(r.db, pfx)
+	}
+}
+
+// Len returns the number of entries in r
+func (r *Rib) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.n
+}
+
+// Lookup returns all entries for exactly prefix p, in any address family,
+// Route Distinguisher, or ADD_PATH Path Identifier, sorted by their keys.
+// Returns nil if not found.
+func (r *Rib) Lookup(p netip.Prefix) []*RibEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	db := r.db[p.Masked()]
+	if len(db) == 0 {
+		return nil
+	}
+	list := make([]*RibEntry, 0, len(db))
+	for _, e := range db {
+		list = append(list, e)
+	}
+	slices.SortFunc(list, func(a, b *RibEntry) int {
+		return strings.Compare(a.key, b.key)
+	})
+	return list
+}
+
+// Dump calls write for each entry in r, as a new UPDATE message in direction d
+// announcing one prefix. The order is undefined. Stops on the first write error.
+// Must not call r.Feed from within write.
+func (r *Rib) Dump(d dir.Dir, write func(m *msg.Msg) error) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, db := range r.db {
+		for _, e := range db {
+			m := msg.NewMsg().Use(msg.UPDATE)
+			m.Dir = d
+			u := &m.Update
+			u.Attrs = e.Attrs.Share()
+			if err := u.AddReachAF(e.AS, e.NextHop, e.NLRI); err != nil {
+				return err
+			}
+			if e.LinkLocal.IsValid() {
+				u.MP(attrs.ATTR_MP_REACH).Prefixes().LinkLocal = e.LinkLocal
+			}
+			if err := write(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
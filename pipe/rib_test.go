@@ -0,0 +1,163 @@
+package pipe
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/attrs"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRib(t *testing.T) {
+	assert := assert.New(t)
+	rib := NewRib()
+
+	feed := func(s string) {
+		m := msg.NewMsg()
+		if !assert.NoError(m.FromJSON([]byte(s))) {
+			t.FailNow()
+		}
+		assert.True(rib.Feed(m))
+		m.Reset() // must not affect rib
+	}
+
+	// announce IPv4 and IPv6 prefixes
+	feed(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"reach": [ "10.1.0.0/16", "10.2.0.0/16" ],
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"ASPATH": { "flags": "T", "value": [ 65001, 65002 ] },
+			"NEXTHOP": { "flags": "T", "value": "192.0.2.1" }
+		} } ]`)
+	feed(`[ "R", 2, "2024-01-01T00:00:01.000", 0, "UPDATE", {
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"ASPATH": { "flags": "T", "value": [ 65001 ] },
+			"MP_REACH": { "flags": "O", "value": {
+				"af": "IPV6/UNICAST", "nexthop": "2001:db8::1", "link-local": "fe80::1",
+				"prefixes": [ "2001:db8:1::/48" ] } }
+		} } ]`)
+	assert.Equal(3, rib.Len())
+
+	// lookup
+	es := rib.Lookup(netip.MustParsePrefix("10.1.0.0/16"))
+	if assert.Len(es, 1) {
+		e := es[0]
+		assert.Equal(afi.AS_IPV4_UNICAST, e.AS)
+		if ap, ok := e.Attrs.Get(attrs.ATTR_ASPATH).(*attrs.Aspath); assert.True(ok) {
+			assert.Equal(`[65001,65002]`, string(ap.ToJSON(nil)))
+		}
+	}
+	es = rib.Lookup(netip.MustParsePrefix("2001:db8:1::/48"))
+	if assert.Len(es, 1) {
+		e := es[0]
+		assert.Equal(afi.AS_IPV6_UNICAST, e.AS)
+		assert.Equal("2001:db8::1", e.NextHop.String())
+		assert.Equal("fe80::1", e.LinkLocal.String())
+		assert.False(e.Attrs.Has(attrs.ATTR_MP_REACH))
+	}
+	assert.Nil(rib.Lookup(netip.MustParsePrefix("10.3.0.0/16")))
+
+	// replace
+	feed(`[ "R", 3, "2024-01-01T00:00:02.000", 0, "UPDATE", {
+		"reach": [ "10.1.0.0/16" ],
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"ASPATH": { "flags": "T", "value": [ 65003 ] },
+			"NEXTHOP": { "flags": "T", "value": "192.0.2.3" }
+		} } ]`)
+	assert.Equal(3, rib.Len())
+	if es = rib.Lookup(netip.MustParsePrefix("10.1.0.0/16")); assert.Len(es, 1) {
+		ap := es[0].Attrs.Get(attrs.ATTR_ASPATH).(*attrs.Aspath)
+		assert.Equal(`[65003]`, string(ap.ToJSON(nil)))
+	}
+
+	// dump and re-feed into another rib
+	rib2 := NewRib()
+	err := rib.Dump(dir.DIR_L, func(m *msg.Msg) error {
+		assert.Equal(dir.DIR_L, m.Dir)
+		assert.NoError(m.Marshal(caps.Caps{}))
+		m2 := msg.NewMsg()
+		assert.NoError(m2.FromJSON(m.GetJSON()))
+		rib2.Feed(m2)
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal(3, rib2.Len())
+	if es = rib2.Lookup(netip.MustParsePrefix("2001:db8:1::/48")); assert.Len(es, 1) {
+		assert.Equal("fe80::1", es[0].LinkLocal.String())
+	}
+
+	// withdraw everything
+	feed(`[ "R", 4, "2024-01-01T00:00:03.000", 0, "UPDATE", {
+		"unreach": [ "10.1.0.0/16", "10.2.0.0/16" ],
+		"attrs": {
+			"MP_UNREACH": { "flags": "O", "value": {
+				"af": "IPV6/UNICAST", "prefixes": [ "2001:db8:1::/48" ] } }
+		} } ]`)
+	assert.Equal(0, rib.Len())
+	assert.Nil(rib.Lookup(netip.MustParsePrefix("10.1.0.0/16")))
+	assert.Nil(rib.Lookup(netip.MustParsePrefix("2001:db8:1::/48")))
+}
+
+func TestRib_AddPath(t *testing.T) {
+	assert := assert.New(t)
+	rib := NewRib()
+
+	feed := func(s string) {
+		m := msg.NewMsg()
+		if !assert.NoError(m.FromJSON([]byte(s))) {
+			t.FailNow()
+		}
+		assert.True(rib.Feed(m))
+	}
+
+	// the same prefix via 3 paths, one without a Path ID
+	feed(`[ "R", 1, "2024-01-01T00:00:00.000", 0, "UPDATE", {
+		"reach": [ "#2#10.1.0.0/16", "#1#10.1.0.0/16", "10.1.0.0/16" ],
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"NEXTHOP": { "flags": "T", "value": "192.0.2.1" }
+		} } ]`)
+	assert.Equal(3, rib.Len())
+
+	// re-announce path 1: replaced
+	feed(`[ "R", 2, "2024-01-01T00:00:01.000", 0, "UPDATE", {
+		"reach": [ "#1#10.1.0.0/16" ],
+		"attrs": {
+			"ORIGIN": { "flags": "T", "value": "IGP" },
+			"NEXTHOP": { "flags": "T", "value": "192.0.2.2" }
+		} } ]`)
+	assert.Equal(3, rib.Len())
+
+	// sorted: no Path ID first
+	es := rib.Lookup(netip.MustParsePrefix("10.1.0.0/16"))
+	if assert.Len(es, 3) {
+		for i, want := range []uint32{0, 1, 2} {
+			id, ok := es[i].NLRI.PathID()
+			assert.Equal(want > 0, ok)
+			assert.Equal(want, id)
+		}
+		assert.Equal(1, es[1].Time.Second())
+		assert.Equal(0, es[2].Time.Second())
+	}
+
+	// withdraw path 2 and an unknown path 3
+	feed(`[ "R", 3, "2024-01-01T00:00:02.000", 0, "UPDATE", {
+		"unreach": [ "#2#10.1.0.0/16", "#3#10.1.0.0/16" ] } ]`)
+	assert.Equal(2, rib.Len())
+	if es = rib.Lookup(netip.MustParsePrefix("10.1.0.0/16")); assert.Len(es, 2) {
+		id, _ := es[1].NLRI.PathID()
+		assert.EqualValues(1, id)
+	}
+
+	// withdraw the rest
+	feed(`[ "R", 4, "2024-01-01T00:00:03.000", 0, "UPDATE", {
+		"unreach": [ "#1#10.1.0.0/16", "10.1.0.0/16" ] } ]`)
+	assert.Equal(0, rib.Len())
+	assert.Nil(rib.Lookup(netip.MustParsePrefix("10.1.0.0/16")))
+}
@@ -0,0 +1,96 @@
+package pipe
+
+import (
+	"bytes"
+	"slices"
+	"sync/atomic"
+
+	"github.com/bgpfix/bgpfix/msg"
+)
+
+// Tap receives copies of all messages written to a Line output,
+// after all callbacks, without affecting the message flow.
+// Use Line.Tap to get a new Tap.
+type Tap struct {
+	C          chan *msg.Msg // receives message copies (owned by the receiver)
+	DropOnFull bool          // if true, drop messages when C is full instead of blocking
+
+	Dropped atomic.Uint64 // number of messages dropped
+}
+
+// Tap attaches a new Tap to l, sending copies of the messages to ch.
+// If dropOnFull is true, never blocks the Line, dropping message copies
+// when ch is full (see Tap.Dropped). Otherwise, blocks the Line until ch
+// accepts the copy or the Pipe stops. The copies carry the message metadata,
+// type, and raw data (marshaled if needed), but not the upper layer.
+// The channel is never closed by l.
+func (l *Line) Tap(ch chan *msg.Msg, dropOnFull bool) *Tap {
+	t := &Tap{C: ch, DropOnFull: dropOnFull}
+	for {
+		old := l.taps.Load()
+		var taps []*Tap
+		if old != nil {
+			taps = slices.Clone(*old)
+		}
+		taps = append(taps, t)
+		if l.taps.CompareAndSwap(old, &taps) {
+			return t
+		}
+	}
+}
+
+// Untap detaches t from l.
+func (l *Line) Untap(t *Tap) {
+	for {
+		old := l.taps.Load()
+		if old == nil {
+			return
+		}
+		taps := slices.DeleteFunc(slices.Clone(*old), func(t2 *Tap) bool {
+			return t2 == t
+		})
+		if l.taps.CompareAndSwap(old, &taps) {
+			return
+		}
+	}
+}
+
+// tap sends copies of m to all taps in l, if any
+func (l *Line) tap(m *msg.Msg) {
+	taps := l.taps.Load()
+	if taps == nil || len(*taps) == 0 {
+		return
+	}
+
+	// make sure m.Data is valid
+	if m.Marshal(l.Pipe.Caps) != nil {
+		for _, t := range *taps {
+			t.Dropped.Add(1)
+		}
+		return
+	}
+
+	for _, t := range *taps {
+		c := msg.NewMsg()
+		c.Dir = m.Dir
+		c.Seq = m.Seq
+		c.Time = m.Time
+		c.Type = m.Type
+		c.Data = bytes.Clone(m.Data)
+
+		if !t.DropOnFull {
+			select {
+			case t.C <- c:
+			case <-l.Pipe.ctx.Done():
+				t.Dropped.Add(1) // pipe stopped
+			}
+			continue
+		}
+
+		select {
+		case t.C <- c:
+		default:
+			t.Dropped.Add(1)
+		}
+	}
+}
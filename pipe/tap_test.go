@@ -0,0 +1,81 @@
+package pipe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTap_DropOnFull(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewPipe(context.Background())
+	tap := p.L.Tap(make(chan *msg.Msg, 2), true)
+	p.Start()
+	defer p.Stop()
+
+	for range 5 {
+		assert.NoError(p.L.WriteMsg(p.GetMsg().Use(msg.KEEPALIVE)))
+	}
+	p.L.Close()
+	for range p.L.Out {
+	}
+
+	assert.Len(tap.C, 2)
+	assert.EqualValues(3, tap.Dropped.Load())
+	for i := range 2 {
+		c := <-tap.C
+		assert.Equal(msg.KEEPALIVE, c.Type)
+		assert.EqualValues(i+1, c.Seq)
+	}
+}
+
+func TestTap_Untap(t *testing.T) {
+	assert := assert.New(t)
+
+	p := NewPipe(context.Background())
+	tap := p.L.Tap(make(chan *msg.Msg, 10), false)
+	p.Start()
+	defer p.Stop()
+
+	assert.NoError(p.L.WriteMsg(p.GetMsg().Use(msg.KEEPALIVE)))
+	<-p.L.Out // tapped before written to Out
+
+	p.L.Untap(tap)
+	assert.NoError(p.L.WriteMsg(p.GetMsg().Use(msg.KEEPALIVE)))
+	p.L.Close()
+	for range p.L.Out {
+	}
+
+	assert.Len(tap.C, 1)
+	assert.Zero(tap.Dropped.Load())
+}
+
+func TestTap_BlockingStop(t *testing.T) {
+	assert := assert.New(t)
+
+	// nobody reads the tap
+	p := NewPipe(context.Background())
+	tap := p.L.Tap(make(chan *msg.Msg), false)
+	p.Start()
+
+	assert.NoError(p.L.WriteMsg(p.GetMsg().Use(msg.KEEPALIVE)))
+
+	// Stop must not hang on the blocked tap
+	done := make(chan struct{})
+	go func() {
+		p.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Stop blocked on a full tap")
+	}
+	assert.Eventually(func() bool {
+		return tap.Dropped.Load() == 1
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package transport
+
+import (
+	"net/netip"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// md5Control returns a net.Dialer.Control function that enables TCP-MD5 with key
+// for the remote address of the socket being connected.
+func md5Control(key string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		ap, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return err
+		}
+
+		// prepare the sockopt
+		var sig unix.TCPMD5Sig
+		addr := ap.Addr().Unmap()
+		if addr.Is4() {
+			sa := (*unix.RawSockaddrInet4)(unsafe.Pointer(&sig.Addr))
+			sa.Family = unix.AF_INET
+			sa.Addr = addr.As4()
+		} else {
+			sa := (*unix.RawSockaddrInet6)(unsafe.Pointer(&sig.Addr))
+			sa.Family = unix.AF_INET6
+			sa.Addr = addr.As16()
+		}
+		sig.Keylen = uint16(copy(sig.Key[:], key))
+
+		// set it on the socket
+		var serr error
+		err = c.Control(func(fd uintptr) {
+			serr = unix.SetsockoptTCPMD5Sig(int(fd), unix.IPPROTO_TCP, unix.TCP_MD5SIG, &sig)
+		})
+		if err != nil {
+			return err
+		}
+		return serr
+	}
+}
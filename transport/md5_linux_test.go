@@ -0,0 +1,72 @@
+//go:build linux
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// md5Listen listens on a local port, requiring TCP-MD5 with key from 127.0.0.1.
+// Skips the test if TCP-MD5 is not available.
+func md5Listen(t *testing.T, key string) net.Listener {
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			// NB: the key is for the peer, ie. 127.0.0.1
+			return md5Control(key)(network, "127.0.0.1:0", c)
+		},
+	}
+	ln, err := lc.Listen(context.Background(), "tcp4", "127.0.0.1:0")
+	if errors.Is(err, unix.EPERM) || errors.Is(err, unix.ENOPROTOOPT) || errors.Is(err, unix.EACCES) {
+		t.Skipf("TCP-MD5 not available: %v", err)
+	} else if err != nil {
+		t.Fatalf("Listen error = %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return ln
+}
+
+func TestDialMD5_Linux(t *testing.T) {
+	assert := assert.New(t)
+	ln := md5Listen(t, "secret")
+
+	// accept one connection and echo a line back
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4)
+		if n, err := conn.Read(buf); err == nil {
+			conn.Write(buf[:n])
+		}
+	}()
+
+	// the same key: works
+	conn, err := DialMD5(ln.Addr().String(), "secret")
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+	_, err = conn.Write([]byte("ping"))
+	assert.NoError(err)
+	buf := make([]byte, 4)
+	_, err = conn.Read(buf)
+	assert.NoError(err)
+	assert.Equal("ping", string(buf))
+
+	// a different key: the SYN is silently dropped
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = DialMD5Context(ctx, ln.Addr().String(), "wrong")
+	assert.Error(err)
+}
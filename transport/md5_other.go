@@ -0,0 +1,12 @@
+//go:build !linux
+
+package transport
+
+import "syscall"
+
+// md5Control returns a net.Dialer.Control function that always fails
+func md5Control(key string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return ErrUnsupported
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package transport
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialMD5_Unsupported(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen: %v", err)
+	}
+	defer ln.Close()
+
+	conn, err := DialMD5(ln.Addr().String(), "secret")
+	assert.Nil(t, conn)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
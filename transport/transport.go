@@ -0,0 +1,36 @@
+// Package transport provides helpers for establishing BGP transport connections,
+// eg. TCP with RFC2385 MD5 signatures, which the standard library does not expose.
+//
+// The returned connections are ordinary net.Conn values, to be used with
+// pipe.Input.Write and pipe.Line.Read, eg. via util.CopyThrough.
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+var (
+	ErrUnsupported = errors.New("not supported on this platform")
+	ErrKeyLength   = errors.New("invalid key length")
+)
+
+// MD5_MAXKEYLEN is the maximum length of a TCP-MD5 key
+const MD5_MAXKEYLEN = 80
+
+// DialMD5 connects to the TCP address addr, signing all segments
+// with TCP-MD5 (RFC2385) using key.
+func DialMD5(addr, key string) (net.Conn, error) {
+	return DialMD5Context(context.Background(), addr, key)
+}
+
+// DialMD5Context is like DialMD5, but uses given context ctx.
+func DialMD5Context(ctx context.Context, addr, key string) (net.Conn, error) {
+	if len(key) == 0 || len(key) > MD5_MAXKEYLEN {
+		return nil, ErrKeyLength
+	}
+
+	d := net.Dialer{Control: md5Control(key)}
+	return d.DialContext(ctx, "tcp", addr)
+}
@@ -0,0 +1,16 @@
+package transport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialMD5_KeyLength(t *testing.T) {
+	for _, key := range []string{"", strings.Repeat("k", MD5_MAXKEYLEN+1)} {
+		conn, err := DialMD5("127.0.0.1:179", key)
+		assert.Nil(t, conn)
+		assert.ErrorIs(t, err, ErrKeyLength, "key length %d", len(key))
+	}
+}
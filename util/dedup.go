@@ -0,0 +1,178 @@
+package util
+
+import (
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/attrs"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/nlri"
+	"github.com/bgpfix/bgpfix/pipe"
+)
+
+// Dedup drops UPDATEs that repeat what was already seen within a time window,
+// ie. re-advertisements of the same prefixes with identical attributes,
+// and repeated withdrawals of the same prefixes.
+//
+// Each prefix is tracked with the hash of the attributes it was last seen with
+// (or as withdrawn). An UPDATE is dropped iff all of its IP prefixes, both reachable
+// and unreachable (including MP-BGP), are unchanged since they last passed, less than
+// Window ago. UPDATEs with no IP prefixes (eg. End-of-RIB, Flowspec) always pass.
+// Prefixes not seen for longer than Window are forgotten, once per Window.
+type Dedup struct {
+	Pipe   *pipe.Pipe
+	Window time.Duration // dedup time window
+
+	Passed  atomic.Uint64 // number of UPDATEs passed
+	Dropped atomic.Uint64 // number of UPDATEs dropped as duplicates
+
+	mu    sync.Mutex
+	seed  maphash.Seed
+	db    map[string]dedupEntry // prefix key -> last state
+	buf   []byte                // buffer for attribute hashing
+	keys  []string              // prefix keys of the current message
+	swept int64                 // unix nano timestamp of the last expire()
+}
+
+// dedupEntry represents the last state of a prefix that passed Dedup
+type dedupEntry struct {
+	hash uint64 // attribute hash, or 0 if withdrawn
+	time int64  // unix nano timestamp
+}
+
+// NewDedup adds a callback to p that drops duplicate UPDATEs in direction d, seen within window.
+// Must be called before p.Start().
+func NewDedup(p *pipe.Pipe, d dir.Dir, window time.Duration) *Dedup {
+	dd := &Dedup{
+		Pipe:   p,
+		Window: window,
+		seed:   maphash.MakeSeed(),
+		db:     make(map[string]dedupEntry),
+	}
+	p.Options.OnMsg(dd.onUpdate, d, msg.UPDATE)
+	return dd
+}
+
+// onUpdate returns false iff UPDATE m is a duplicate
+func (dd *Dedup) onUpdate(m *msg.Msg) bool {
+	u := &m.Update
+
+	// MP-BGP other than IP prefixes?
+	for _, ac := range []attrs.Code{attrs.ATTR_MP_REACH, attrs.ATTR_MP_UNREACH} {
+		if mp := u.MP(ac); mp != nil && mp.Prefixes() == nil {
+			dd.Passed.Add(1)
+			return true
+		}
+	}
+
+	dd.mu.Lock()
+	defer dd.mu.Unlock()
+
+	now := m.Time.UnixNano()
+	if now-dd.swept >= int64(dd.Window) {
+		dd.expire(now)
+	}
+	hash := dd.hash(u)
+	dup := true
+
+	// check reachable prefixes
+	dd.keys = dd.keys[:0]
+	nreach := dd.collect(u.Reach, afi.AS_IPV4_UNICAST, u.MP(attrs.ATTR_MP_REACH).Prefixes())
+	for _, key := range dd.keys {
+		if e, ok := dd.db[key]; !ok || e.hash != hash || now-e.time >= int64(dd.Window) {
+			dup = false
+			break
+		}
+	}
+
+	// check unreachable prefixes
+	dd.collect(u.Unreach, afi.AS_IPV4_UNICAST, u.MP(attrs.ATTR_MP_UNREACH).Prefixes())
+	for _, key := range dd.keys[nreach:] {
+		if !dup {
+			break
+		} else if e, ok := dd.db[key]; !ok || e.hash != 0 || now-e.time >= int64(dd.Window) {
+			dup = false
+		}
+	}
+
+	// no prefixes, or a duplicate?
+	if len(dd.keys) == 0 {
+		dd.Passed.Add(1)
+		return true
+	} else if dup {
+		dd.Dropped.Add(1)
+		return false
+	}
+
+	// store the new state
+	for i, key := range dd.keys {
+		if i < nreach {
+			dd.db[key] = dedupEntry{hash, now}
+		} else {
+			dd.db[key] = dedupEntry{0, now}
+		}
+	}
+	dd.Passed.Add(1)
+	return true
+}
+
+// expire drops entries older than dd.Window at now from dd.db
+func (dd *Dedup) expire(now int64) {
+	for key, e := range dd.db {
+		if now-e.time >= int64(dd.Window) {
+			delete(dd.db, key)
+		}
+	}
+	dd.swept = now
+}
+
+// Len returns the number of prefixes currently tracked
+func (dd *Dedup) Len() int {
+	dd.mu.Lock()
+	defer dd.mu.Unlock()
+	return len(dd.db)
+}
+
+// collect appends to dd.keys the keys of prefixes in base (in address family as)
+// and in mp (if non-nil). Returns the new length of dd.keys.
+func (dd *Dedup) collect(base []nlri.NLRI, as afi.AS, mp *attrs.MPPrefixes) int {
+	for i := range base {
+		dd.keys = append(dd.keys, base[i].Key(as))
+	}
+	if mp != nil {
+		for i := range mp.Prefixes {
+			dd.keys = append(dd.keys, mp.Prefixes[i].Key(mp.AS))
+		}
+	}
+	return len(dd.keys)
+}
+
+// hash returns the hash of path attributes in u, including the MP-BGP next-hop,
+// but excluding the MP-BGP prefixes. Never returns 0.
+func (dd *Dedup) hash(u *msg.Update) uint64 {
+	var h maphash.Hash
+	h.SetSeed(dd.seed)
+
+	cps := dd.Pipe.Caps
+	u.Attrs.Each(func(i int, ac attrs.Code, at attrs.Attr) {
+		switch ac {
+		case attrs.ATTR_MP_UNREACH:
+			return
+		case attrs.ATTR_MP_REACH:
+			if mp := u.MP(ac).Prefixes(); mp != nil {
+				dd.buf = mp.AS.Marshal3(dd.buf[:0])
+				dd.buf = mp.NextHop.AppendTo(dd.buf)
+				dd.buf = mp.LinkLocal.AppendTo(dd.buf)
+			}
+		default:
+			dd.buf = at.Marshal(dd.buf[:0], cps, u.Msg.Dir)
+		}
+		h.Write(dd.buf)
+	})
+
+	return max(h.Sum64(), 1)
+}
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/msg/msgtest"
+	"github.com/bgpfix/bgpfix/pipe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedup(t *testing.T) {
+	assert := assert.New(t)
+	p := pipe.NewPipe(context.Background())
+	dd := NewDedup(p, dir.DIR_L, time.Minute)
+
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	pass := func(m *msg.Msg, after time.Duration) bool {
+		m.Time = t0.Add(after)
+		return dd.onUpdate(m)
+	}
+	announce := func(med string) *msg.Msg {
+		return msgtest.Update(`{"reach":["10.0.0.0/8"],"attrs":{"ORIGIN":"IGP","ASPATH":[65001],` +
+			`"NEXTHOP":"192.0.2.1","MED":` + med + `}}`)
+	}
+
+	// the same announce twice: the second is dropped
+	assert.True(pass(announce("10"), 0))
+	assert.False(pass(announce("10"), time.Second))
+
+	// changed MED passes, then is a duplicate itself
+	assert.True(pass(announce("20"), 2*time.Second))
+	assert.False(pass(announce("20"), 3*time.Second))
+
+	// the same announce again, but after the window
+	assert.True(pass(announce("20"), 2*time.Minute))
+
+	// withdrawals, also in MP-BGP
+	assert.True(pass(msgtest.Withdraw("10.0.0.0/8"), 2*time.Minute+time.Second))
+	assert.False(pass(msgtest.Withdraw("10.0.0.0/8"), 2*time.Minute+2*time.Second))
+	assert.True(pass(msgtest.Announce("2001:db8::1", []uint32{65001}, "2001:db8:1::/48"), 2*time.Minute))
+	assert.False(pass(msgtest.Announce("2001:db8::1", []uint32{65001}, "2001:db8:1::/48"), 2*time.Minute))
+	assert.True(pass(msgtest.Announce("2001:db8::2", []uint32{65001}, "2001:db8:1::/48"), 2*time.Minute))
+	assert.True(pass(msgtest.Withdraw("2001:db8:1::/48"), 2*time.Minute))
+	assert.False(pass(msgtest.Withdraw("2001:db8:1::/48"), 2*time.Minute))
+
+	// no prefixes
+	assert.True(pass(msgtest.Update(`{}`), 2*time.Minute))
+	assert.True(pass(msgtest.Update(`{}`), 2*time.Minute))
+
+	assert.EqualValues(9, dd.Passed.Load())
+	assert.EqualValues(5, dd.Dropped.Load())
+	assert.Equal(2, dd.Len())
+
+	// old prefixes are forgotten
+	assert.True(pass(msgtest.Withdraw("10.1.0.0/16"), 4*time.Minute))
+	assert.Equal(1, dd.Len())
+}
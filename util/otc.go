@@ -0,0 +1,184 @@
+package util
+
+import (
+	"sync/atomic"
+
+	"github.com/bgpfix/bgpfix/attrs"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/pipe"
+)
+
+var (
+	// route leak caught by OTC (value: the reason)
+	EVENT_OTC_LEAK = "bgpfix/util.OTC_LEAK"
+
+	// BGP Role of the neighbor does not match ours (value: our Role, neighbor Role)
+	EVENT_OTC_ROLE_MISMATCH = "bgpfix/util.OTC_ROLE_MISMATCH"
+)
+
+// OTC implements route leak prevention and detection of rfc9234/5 for one BGP session,
+// using the Only to Customer (OTC) attribute and the BGP Role of the local speaker.
+//
+// UPDATEs flowing in the Ingress direction are treated as received from the BGP neighbor,
+// and UPDATEs flowing in the opposite (egress) direction as sent to it. Use one OTC
+// per BGP session, eg. two for a pipe that connects two external BGP neighbors.
+//
+// Leaked routes are withdrawn (see msg.Update.TreatAsWithdraw), or dropped if not possible.
+// The ingress procedure needs the BGP Roles of both sides and the neighbor ASN.
+//
+// If the BGP Role of the neighbor does not match ours (rfc9234/4.2), OTC sends
+// a Role Mismatch NOTIFICATION to the neighbor and closes the session.
+type OTC struct {
+	Pipe    *pipe.Pipe
+	Ingress dir.Dir // direction of UPDATEs received from the neighbor
+
+	Role     *caps.Role // our BGP Role; if nil, CAP_ROLE from our last OPEN (sent in egress)
+	PeerRole *caps.Role // neighbor BGP Role; if nil, CAP_ROLE from its last OPEN (sent in Ingress)
+	LocalAS  uint32     // our ASN; if zero, taken from our last OPEN
+	RemoteAS uint32     // neighbor ASN; if zero, taken from its last OPEN (sent in Ingress)
+
+	Leaks   atomic.Uint64 // number of UPDATEs with leaked routes caught in Ingress
+	Blocked atomic.Uint64 // number of UPDATEs with OTC not propagated in egress
+	Marked  atomic.Uint64 // number of UPDATEs where OTC was added
+
+	mismatch atomic.Bool // Role Mismatch already handled?
+}
+
+// NewOTC adds OTC callbacks to p for the BGP neighbor that sends UPDATEs in direction ingress.
+// Must be called before p.Start().
+func NewOTC(p *pipe.Pipe, ingress dir.Dir) *OTC {
+	o := &OTC{Pipe: p, Ingress: ingress}
+	p.Options.OnMsg(o.onIngress, ingress, msg.UPDATE)
+	p.Options.OnMsg(o.onEgress, ingress.Flip(), msg.UPDATE)
+	p.Options.OnEvent(o.onOpen, pipe.EVENT_OPEN)
+	return o
+}
+
+// role returns our BGP Role, if known
+func (o *OTC) role() (role byte, ok bool) {
+	return o.roleFrom(o.Role, o.Ingress.Flip())
+}
+
+// peerRole returns the BGP Role of the neighbor, if known
+func (o *OTC) peerRole() (role byte, ok bool) {
+	return o.roleFrom(o.PeerRole, o.Ingress)
+}
+
+// roleFrom returns the BGP Role from ovr if non-nil, or from the last OPEN sent in direction d.
+func (o *OTC) roleFrom(ovr *caps.Role, d dir.Dir) (role byte, ok bool) {
+	if ovr != nil {
+		return ovr.Role, true
+	} else if op := o.Pipe.LineFor(d).Open.Load(); op == nil {
+		return 0, false
+	} else if c, ok := op.Caps.Get(caps.CAP_ROLE).(*caps.Role); ok {
+		return c.Role, true
+	} else {
+		return 0, false
+	}
+}
+
+// asn returns the ASN from ovr if non-zero, or from the last OPEN sent in direction d.
+func (o *OTC) asn(ovr uint32, d dir.Dir) uint32 {
+	if ovr != 0 {
+		return ovr
+	} else if op := o.Pipe.LineFor(d).Open.Load(); op != nil {
+		return uint32(op.GetASN())
+	} else {
+		return 0
+	}
+}
+
+// onOpen checks the BGP Roles of both sides after a new OPEN, rfc9234/4.2
+func (o *OTC) onOpen(ev *pipe.Event) bool {
+	role, ok := o.role()
+	if !ok {
+		return true
+	}
+	peer, ok := o.peerRole()
+	if !ok {
+		return true
+	}
+
+	want, valid := (&caps.Role{Role: role}).Peer()
+	if !valid || peer == want || !o.mismatch.CompareAndSwap(false, true) {
+		return true
+	}
+
+	o.Pipe.Event(EVENT_OTC_ROLE_MISMATCH, o.Ingress, role, peer)
+	go func() {
+		l := o.Pipe.LineFor(o.Ingress.Flip())
+		err := l.SendNotificationAndClose(msg.NOTIFY_OPEN, msg.OPEN_ROLE_MISMATCH, nil)
+		if err != nil {
+			o.Pipe.Warn().Err(err).Msg("OTC: could not send Role Mismatch")
+		}
+	}()
+
+	return true
+}
+
+// onIngress implements rfc9234/5 ingress procedure
+func (o *OTC) onIngress(m *msg.Msg) bool {
+	u := &m.Update
+	if !u.HasReach() {
+		return true
+	}
+
+	role, ok := o.role()
+	if !ok {
+		return true
+	} else if _, ok := o.peerRole(); !ok {
+		return true
+	}
+
+	remote := o.asn(o.RemoteAS, o.Ingress)
+	if remote == 0 {
+		return true
+	}
+
+	otc, _ := u.Attrs.Get(attrs.ATTR_OTC).(*attrs.Otc)
+	switch {
+	case otc != nil && (role == caps.ROLE_PROVIDER || role == caps.ROLE_RS):
+		return o.leak(m, "OTC from customer or RS-client")
+	case otc != nil && role == caps.ROLE_PEER && otc.ASN != remote:
+		return o.leak(m, "OTC from peer does not match its ASN")
+	case otc == nil && (role == caps.ROLE_CUSTOMER || role == caps.ROLE_RS_CLIENT || role == caps.ROLE_PEER):
+		u.Attrs.Use(attrs.ATTR_OTC).(*attrs.Otc).ASN = remote
+		m.Modified()
+		o.Marked.Add(1)
+	}
+
+	return true
+}
+
+// onEgress implements rfc9234/5 egress procedure
+func (o *OTC) onEgress(m *msg.Msg) bool {
+	u := &m.Update
+	role, ok := o.role()
+	if !ok || !u.HasReach() {
+		return true
+	}
+
+	otc := u.Attrs.Has(attrs.ATTR_OTC)
+	switch {
+	case otc && (role == caps.ROLE_CUSTOMER || role == caps.ROLE_PEER || role == caps.ROLE_RS_CLIENT):
+		o.Blocked.Add(1)
+		return u.TreatAsWithdraw()
+	case !otc && (role == caps.ROLE_PROVIDER || role == caps.ROLE_PEER || role == caps.ROLE_RS):
+		if local := o.asn(o.LocalAS, o.Ingress.Flip()); local != 0 {
+			u.Attrs.Use(attrs.ATTR_OTC).(*attrs.Otc).ASN = local
+			m.Modified()
+			o.Marked.Add(1)
+		}
+	}
+
+	return true
+}
+
+// leak handles a route leak in m
+func (o *OTC) leak(m *msg.Msg, reason string) bool {
+	o.Leaks.Add(1)
+	o.Pipe.Event(EVENT_OTC_LEAK, m.Dir, m, reason)
+	return m.Update.TreatAsWithdraw()
+}
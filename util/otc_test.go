@@ -0,0 +1,186 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/attrs"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/msg/msgtest"
+	"github.com/bgpfix/bgpfix/pipe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOTC(t *testing.T) {
+	assert := assert.New(t)
+
+	// we are the provider of a customer that sends UPDATEs in DIR_L
+	p := pipe.NewPipe(context.Background())
+	o := NewOTC(p, dir.DIR_L)
+	o.Role = &caps.Role{Role: caps.ROLE_PROVIDER}
+	o.PeerRole = &caps.Role{Role: caps.ROLE_CUSTOMER}
+	o.LocalAS, o.RemoteAS = 65001, 65002
+
+	evch := make(chan *pipe.Event, 1)
+	p.Options.OnEvent(func(ev *pipe.Event) bool {
+		evch <- ev
+		return true
+	}, EVENT_OTC_LEAK)
+	p.Start()
+	defer p.Stop()
+
+	update := func(otc string) *msg.Msg {
+		js := `{"reach":["10.0.0.0/8"],"attrs":{"ORIGIN":"IGP","ASPATH":[65002],"NEXTHOP":"192.0.2.2"`
+		if otc != "" {
+			js += `,"OTC":` + otc
+		}
+		m := msgtest.Update(js + `}}`)
+		m.Dir = dir.DIR_L
+		return m
+	}
+
+	// a customer route incorrectly carrying OTC toward us (provider): a leak
+	m := update("65003")
+	assert.True(o.onIngress(m))
+	assert.Empty(m.Update.Reach)
+	assert.Len(m.Update.Unreach, 1)
+	assert.False(m.Update.Attrs.Has(attrs.ATTR_OTC))
+	assert.EqualValues(1, o.Leaks.Load())
+	select {
+	case ev := <-evch:
+		assert.Equal(dir.DIR_L, ev.Dir)
+	case <-time.After(time.Second):
+		t.Fatal("EVENT_OTC_LEAK not received")
+	}
+
+	// a valid customer route passes untouched
+	m = update("")
+	assert.True(o.onIngress(m))
+	assert.Len(m.Update.Reach, 1)
+	assert.False(m.Update.Attrs.Has(attrs.ATTR_OTC))
+
+	// our routes toward the customer get OTC = our ASN
+	m = update("")
+	assert.True(o.onEgress(m))
+	if otc, ok := m.Update.Attrs.Get(attrs.ATTR_OTC).(*attrs.Otc); assert.True(ok) {
+		assert.EqualValues(65001, otc.ASN)
+	}
+	assert.EqualValues(1, o.Marked.Load())
+
+	// the other way around: we are the customer, sending a route with OTC to our provider
+	o.Role = &caps.Role{Role: caps.ROLE_CUSTOMER}
+	o.PeerRole = &caps.Role{Role: caps.ROLE_PROVIDER}
+	m = update("65003")
+	assert.True(o.onEgress(m))
+	assert.Empty(m.Update.Reach)
+	assert.EqualValues(1, o.Blocked.Load())
+
+	// ...and routes from our provider are marked with its ASN
+	m = update("")
+	assert.True(o.onIngress(m))
+	if otc, ok := m.Update.Attrs.Get(attrs.ATTR_OTC).(*attrs.Otc); assert.True(ok) {
+		assert.EqualValues(65002, otc.ASN)
+	}
+	assert.EqualValues(1, o.Leaks.Load())
+}
+
+func TestOTC_Unknown(t *testing.T) {
+	assert := assert.New(t)
+
+	p := pipe.NewPipe(context.Background())
+	o := NewOTC(p, dir.DIR_L)
+	o.Role = &caps.Role{Role: caps.ROLE_PEER}
+	p.Start()
+	defer p.Stop()
+
+	update := func() *msg.Msg {
+		m := msgtest.Update(`{"reach":["10.0.0.0/8"],"attrs":{"ORIGIN":"IGP","ASPATH":[65002],"NEXTHOP":"192.0.2.2","OTC":65003}}`)
+		m.Dir = dir.DIR_L
+		return m
+	}
+
+	// no Role of the neighbor: skip
+	o.RemoteAS = 65002
+	m := update()
+	assert.True(o.onIngress(m))
+	assert.Len(m.Update.Reach, 1)
+
+	// no ASN of the neighbor: skip, even though OTC != remote ASN
+	o.RemoteAS = 0
+	o.PeerRole = &caps.Role{Role: caps.ROLE_PEER}
+	m = update()
+	assert.True(o.onIngress(m))
+	assert.Len(m.Update.Reach, 1)
+	assert.EqualValues(0, o.Leaks.Load())
+
+	// both known: a leak
+	o.RemoteAS = 65002
+	m = update()
+	assert.True(o.onIngress(m))
+	assert.Empty(m.Update.Reach)
+	assert.EqualValues(1, o.Leaks.Load())
+}
+
+func TestOTC_RoleMismatch(t *testing.T) {
+	assert := assert.New(t)
+
+	// we are the provider, the neighbor sends OPENs and UPDATEs in DIR_L
+	p := pipe.NewPipe(context.Background())
+	o := NewOTC(p, dir.DIR_L)
+	o.Role = &caps.Role{Role: caps.ROLE_PROVIDER}
+
+	evch := make(chan *pipe.Event, 1)
+	p.Options.OnEvent(func(ev *pipe.Event) bool {
+		evch <- ev
+		return true
+	}, EVENT_OTC_ROLE_MISMATCH)
+	p.Start()
+	defer p.Stop()
+
+	// the neighbor claims to be a peer, not a customer
+	open := msgtest.Open(65002, 90, "192.0.2.2", &caps.Role{Role: caps.ROLE_PEER})
+	assert.NoError(p.L.WriteMsg(open))
+	<-p.L.Out
+
+	select {
+	case ev := <-evch:
+		assert.Equal([]any{caps.ROLE_PROVIDER, caps.ROLE_PEER}, ev.Value)
+	case <-time.After(time.Second):
+		t.Fatal("EVENT_OTC_ROLE_MISMATCH not received")
+	}
+
+	// the neighbor must get the NOTIFICATION, then R must close
+	select {
+	case m := <-p.R.Out:
+		assert.NoError(m.Parse(caps.Caps{}))
+		assert.Equal(msg.NOTIFY, m.Type)
+		assert.Equal(msg.NOTIFY_OPEN, m.Notify.Code)
+		assert.Equal(msg.OPEN_ROLE_MISMATCH, m.Notify.Subcode)
+	case <-time.After(time.Second):
+		t.Fatal("NOTIFICATION not received")
+	}
+	_, ok := <-p.R.Out
+	assert.False(ok)
+}
+
+func TestOTC_RoleMatch(t *testing.T) {
+	p := pipe.NewPipe(context.Background())
+	o := NewOTC(p, dir.DIR_L)
+	o.Role = &caps.Role{Role: caps.ROLE_PROVIDER}
+	p.Start()
+	defer p.Stop()
+
+	open := msgtest.Open(65002, 90, "192.0.2.2", &caps.Role{Role: caps.ROLE_CUSTOMER})
+	assert.NoError(t, p.L.WriteMsg(open))
+	<-p.L.Out
+
+	select {
+	case m := <-p.R.Out:
+		t.Fatalf("unexpected message: %s", m)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.False(t, o.mismatch.Load())
+}
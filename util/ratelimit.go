@@ -0,0 +1,93 @@
+package util
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/pipe"
+)
+
+// RateLimit enforces a token bucket rate limit on UPDATEs flowing in one direction,
+// eg. to protect a downstream BGP speaker from UPDATE bursts.
+//
+// UPDATEs over the limit are delayed until a token is available (blocking the Input
+// that processes them, but no longer than the pipe context), or dropped if Drop is set.
+type RateLimit struct {
+	Pipe  *pipe.Pipe
+	Rate  float64 // UPDATEs per second; <= 0 means no limit
+	Burst int     // bucket size, ie. max. number of UPDATEs sent back-to-back (min. 1)
+	Drop  bool    // if true, drop UPDATEs over the limit instead of delaying them
+
+	Delayed atomic.Uint64 // number of UPDATEs delayed
+	Dropped atomic.Uint64 // number of UPDATEs dropped
+
+	mu     sync.Mutex
+	tokens float64   // available tokens (negative when reserved in advance)
+	last   time.Time // last time tokens were updated
+}
+
+// NewRateLimit adds a callback to p that limits UPDATEs in direction d to perSecond,
+// with bursts of up to burst messages. Must be called before p.Start().
+func NewRateLimit(p *pipe.Pipe, d dir.Dir, perSecond float64, burst int) *RateLimit {
+	rl := &RateLimit{
+		Pipe:   p,
+		Rate:   perSecond,
+		Burst:  burst,
+		tokens: float64(max(burst, 1)),
+	}
+	p.Options.OnMsg(rl.onUpdate, d, msg.UPDATE)
+	return rl
+}
+
+// reserve takes a token from the bucket, returning how long to wait for it.
+// If drop is true and the token is not available now, it is not taken and ok is false.
+func (rl *RateLimit) reserve(drop bool) (wait time.Duration, ok bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	// refill the bucket
+	now := time.Now()
+	burst := float64(max(rl.Burst, 1))
+	if !rl.last.IsZero() {
+		rl.tokens = min(burst, rl.tokens+now.Sub(rl.last).Seconds()*rl.Rate)
+	}
+	rl.last = now
+
+	// take a token
+	if rl.tokens >= 1 {
+		rl.tokens--
+		return 0, true
+	} else if drop {
+		return 0, false
+	}
+	rl.tokens--
+	return time.Duration(-rl.tokens / rl.Rate * float64(time.Second)), true
+}
+
+// onUpdate delays or drops UPDATE m if over the rate limit
+func (rl *RateLimit) onUpdate(m *msg.Msg) bool {
+	if rl.Rate <= 0 {
+		return true
+	}
+
+	wait, ok := rl.reserve(rl.Drop)
+	if !ok {
+		rl.Dropped.Add(1)
+		return false
+	} else if wait <= 0 {
+		return true
+	}
+
+	rl.Delayed.Add(1)
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-rl.Pipe.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg/msgtest"
+	"github.com/bgpfix/bgpfix/pipe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := pipe.NewPipe(ctx)
+
+	// 41 UPDATEs at once, paced to 200/s after a burst of 1: 40 x 5ms
+	rl := NewRateLimit(p, dir.DIR_L, 200, 1)
+	m := msgtest.Announce("192.0.2.1", nil, "10.0.0.0/8")
+	start := time.Now()
+	for range 41 {
+		assert.True(rl.onUpdate(m))
+	}
+	took := time.Since(start)
+	assert.GreaterOrEqual(took, 180*time.Millisecond)
+	assert.Less(took, time.Second)
+	// NB: a timer firing late may leave the next token ready, without a delay
+	assert.LessOrEqual(rl.Delayed.Load(), uint64(40))
+	assert.Greater(rl.Delayed.Load(), uint64(20))
+	assert.Zero(rl.Dropped.Load())
+
+	// drop over the limit: only the burst passes
+	rl2 := NewRateLimit(p, dir.DIR_L, 1, 5)
+	rl2.Drop = true
+	passed := 0
+	for range 20 {
+		if rl2.onUpdate(m) {
+			passed++
+		}
+	}
+	assert.Equal(5, passed)
+	assert.EqualValues(15, rl2.Dropped.Load())
+	assert.Zero(rl2.Delayed.Load())
+
+	// a cancelled pipe context releases the delayed UPDATEs
+	rl3 := NewRateLimit(p, dir.DIR_L, 0.1, 1)
+	assert.True(rl3.onUpdate(m))
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start = time.Now()
+	assert.False(rl3.onUpdate(m))
+	assert.Less(time.Since(start), time.Second)
+}
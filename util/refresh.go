@@ -0,0 +1,79 @@
+package util
+
+import (
+	"sync"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/pipe"
+)
+
+// RefreshFunc re-announces the Adj-RIB-Out for address family as,
+// using write to send UPDATE messages (eg. taken from pipe.GetMsg) to the peer.
+type RefreshFunc func(as afi.AS, write func(m *msg.Msg) error) error
+
+// Refresh honors ROUTE-REFRESH requests (rfc2918) received from a BGP peer,
+// by calling Func to re-announce the Adj-RIB-Out for the requested address family.
+// If Enhanced Route Refresh is negotiated in Pipe.Caps, the re-announcement is
+// bracketed by BoRR and EoRR messages (rfc7313).
+type Refresh struct {
+	Pipe *pipe.Pipe
+	Dir  dir.Dir     // direction of ROUTE-REFRESH requests from the peer
+	Func RefreshFunc // re-announces the Adj-RIB-Out
+	Keep bool        // if true, let the requests through (otherwise drop them)
+
+	in *pipe.Input // input for the re-announcements (in Dir.Flip())
+	mu sync.Mutex  // serializes the re-announcements
+}
+
+// NewRefresh adds a Refresh stage to p, for ROUTE-REFRESH requests flowing in direction d,
+// calling fn on each request. Must be called before p.Start().
+func NewRefresh(p *pipe.Pipe, d dir.Dir, fn RefreshFunc) *Refresh {
+	r := &Refresh{Pipe: p, Dir: d, Func: fn}
+	r.in = p.Options.AddInput(d.Flip())
+	p.Options.OnMsg(r.onRefresh, d, msg.REFRESH)
+	return r
+}
+
+// onRefresh handles ROUTE-REFRESH message m
+func (r *Refresh) onRefresh(m *msg.Msg) bool {
+	if r.Pipe.ParseMsg(m) != nil || m.Refresh.Subtype != msg.REFRESH_REQUEST {
+		return true // not a request, take it as-is
+	}
+
+	as := m.Refresh.AS
+	go func() {
+		if err := r.announce(as); err != nil {
+			r.Pipe.Warn().Err(err).Uint32("as", uint32(as)).Msg("Refresh: re-announce failed")
+		}
+	}()
+
+	return r.Keep
+}
+
+// announce re-announces as, bracketed by BoRR/EoRR if needed.
+// EoRR is not sent if the re-announcement fails.
+func (r *Refresh) announce(as afi.AS) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enhanced := r.Pipe.Caps.Has(caps.CAP_ENHANCED_ROUTE_REFRESH)
+	if enhanced {
+		if err := r.in.WriteMsg(msg.NewRefresh(as.Afi(), as.Safi(), msg.REFRESH_BORR)); err != nil {
+			return err
+		}
+	}
+
+	if r.Func != nil {
+		if err := r.Func(as, r.in.WriteMsg); err != nil {
+			return err
+		}
+	}
+
+	if enhanced {
+		return r.in.WriteMsg(msg.NewRefresh(as.Afi(), as.Safi(), msg.REFRESH_EORR))
+	}
+	return nil
+}
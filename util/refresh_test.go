@@ -0,0 +1,110 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/msg/msgtest"
+	"github.com/bgpfix/bgpfix/pipe"
+	"github.com/stretchr/testify/assert"
+)
+
+// refreshPipe returns a started pipe with a Refresh stage for requests in DIR_L,
+// re-announcing one UPDATE (or returning ferr, if non-nil).
+func refreshPipe(t *testing.T, enhanced bool, ferr error) (*pipe.Pipe, *Refresh) {
+	p := pipe.NewPipe(context.Background())
+	if enhanced {
+		p.Caps.Use(caps.CAP_ENHANCED_ROUTE_REFRESH)
+	}
+
+	r := NewRefresh(p, dir.DIR_L, func(as afi.AS, write func(m *msg.Msg) error) error {
+		if ferr != nil {
+			return ferr
+		}
+		return write(msgtest.Announce("192.0.2.1", []uint32{65001}, "10.0.0.0/8"))
+	})
+	p.Start()
+	t.Cleanup(p.Stop)
+	return p, r
+}
+
+// refreshNext returns the next message in out, or nil on timeout
+func refreshNext(t *testing.T, out chan *msg.Msg, timeout time.Duration) *msg.Msg {
+	select {
+	case m := <-out:
+		assert.NoError(t, m.Parse(caps.Caps{}))
+		return m
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	assert := assert.New(t)
+	p, _ := refreshPipe(t, false, nil)
+
+	assert.NoError(p.L.WriteMsg(msgtest.Refresh(afi.AS_IPV4_UNICAST, msg.REFRESH_REQUEST)))
+
+	// only the re-announcement, no BoRR/EoRR
+	if m := refreshNext(t, p.R.Out, time.Second); assert.NotNil(m) {
+		assert.Equal(msg.UPDATE, m.Type)
+		assert.Equal(dir.DIR_R, m.Dir)
+	}
+	assert.Nil(refreshNext(t, p.R.Out, 50*time.Millisecond))
+
+	// the request was dropped
+	assert.Nil(refreshNext(t, p.L.Out, 50*time.Millisecond))
+}
+
+func TestRefresh_Enhanced(t *testing.T) {
+	assert := assert.New(t)
+	p, r := refreshPipe(t, true, nil)
+	r.Keep = true
+
+	assert.NoError(p.L.WriteMsg(msgtest.Refresh(afi.AS_IPV6_UNICAST, msg.REFRESH_REQUEST)))
+
+	// the request passes through
+	if m := refreshNext(t, p.L.Out, time.Second); assert.NotNil(m) {
+		assert.Equal(msg.REFRESH, m.Type)
+	}
+
+	// BoRR, the re-announcement, EoRR
+	if m := refreshNext(t, p.R.Out, time.Second); assert.NotNil(m) && assert.Equal(msg.REFRESH, m.Type) {
+		assert.Equal(msg.REFRESH_BORR, m.Refresh.Subtype)
+		assert.Equal(afi.AS_IPV6_UNICAST, m.Refresh.AS)
+	}
+	if m := refreshNext(t, p.R.Out, time.Second); assert.NotNil(m) {
+		assert.Equal(msg.UPDATE, m.Type)
+	}
+	if m := refreshNext(t, p.R.Out, time.Second); assert.NotNil(m) && assert.Equal(msg.REFRESH, m.Type) {
+		assert.Equal(msg.REFRESH_EORR, m.Refresh.Subtype)
+		assert.Equal(afi.AS_IPV6_UNICAST, m.Refresh.AS)
+	}
+
+	// BoRR/EoRR from the peer are not requests
+	assert.NoError(p.L.WriteMsg(msgtest.Refresh(afi.AS_IPV6_UNICAST, msg.REFRESH_BORR)))
+	assert.NotNil(refreshNext(t, p.L.Out, time.Second))
+	assert.Nil(refreshNext(t, p.R.Out, 50*time.Millisecond))
+}
+
+func TestRefresh_Error(t *testing.T) {
+	assert := assert.New(t)
+	p, r := refreshPipe(t, true, errors.New("test"))
+
+	// re-announcement fails: BoRR, but no EoRR
+	assert.EqualError(r.announce(afi.AS_IPV4_UNICAST), "test")
+	if m := refreshNext(t, p.R.Out, time.Second); assert.NotNil(m) && assert.Equal(msg.REFRESH, m.Type) {
+		assert.Equal(msg.REFRESH_BORR, m.Refresh.Subtype)
+	}
+	assert.Nil(refreshNext(t, p.R.Out, 50*time.Millisecond))
+
+	// can't write: BoRR fails
+	p.R.Close()
+	assert.ErrorIs(r.announce(afi.AS_IPV4_UNICAST), pipe.ErrInClosed)
+}
@@ -0,0 +1,156 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/bgpfix/bgpfix/mrt"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/pipe"
+)
+
+// Replay reads messages from a recorded source (MRT or JSON) and writes them
+// to a pipe.Input, paced according to their original timestamps, which
+// are preserved. Use ReadMRT or ReadJSON for whole sources, or WriteMsg
+// for messages from elsewhere.
+//
+// Must not be used concurrently.
+type Replay struct {
+	Ctx   context.Context // if non-nil, abort waiting when done
+	Speed float64         // speed multiplier, eg. 2.0 = twice as fast; <= 0 means as fast as possible
+
+	first time.Time // timestamp of the first message
+	start time.Time // wall clock at the first message
+}
+
+// NewReplay returns a new Replay with given speed multiplier,
+// where 1.0 means real-time and <= 0 means as fast as possible.
+func NewReplay(ctx context.Context, speed float64) *Replay {
+	return &Replay{Ctx: ctx, Speed: speed}
+}
+
+// Reset makes the next message start a new timeline.
+func (r *Replay) Reset() {
+	r.first = time.Time{}
+	r.start = time.Time{}
+}
+
+// Pace blocks until it is time to send m, relative to the first message seen.
+// Messages with zero timestamp are never delayed.
+// Returns the cause of r.Ctx iff it was cancelled while waiting.
+func (r *Replay) Pace(m *msg.Msg) error {
+	if r.Speed <= 0 || m.Time.IsZero() {
+		return nil
+	}
+
+	// first message?
+	if r.first.IsZero() {
+		r.first = m.Time
+		r.start = time.Now()
+		return nil
+	}
+
+	// how long to wait?
+	offset := time.Duration(float64(m.Time.Sub(r.first)) / r.Speed)
+	wait := time.Until(r.start.Add(offset))
+	if wait <= 0 {
+		return nil
+	}
+
+	if r.Ctx == nil {
+		time.Sleep(wait)
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-r.Ctx.Done():
+		return context.Cause(r.Ctx)
+	}
+}
+
+// WriteMsg paces m and writes it to in, preserving its original timestamp
+// and sequence number (if set). On error, m is returned to the pool.
+func (r *Replay) WriteMsg(in *pipe.Input, m *msg.Msg) error {
+	if err := r.Pace(m); err != nil {
+		in.Pipe.PutMsg(m)
+		return err
+	}
+	return in.WriteMsg(m)
+}
+
+// ReadMRT reads MRT-BGP4MP messages from src and replays them into in,
+// skipping other MRT records. Returns nil at the end of src,
+// or the first error, eg. if r.Ctx was cancelled.
+func (r *Replay) ReadMRT(src io.Reader, in *pipe.Input) error {
+	var (
+		p    = in.Pipe
+		br   = mrt.NewReader(p, in)
+		mm   = mrt.NewMrt()
+		rbuf = make([]byte, 64*1024)
+		ibuf []byte // unprocessed input
+	)
+
+	for {
+		n, rerr := src.Read(rbuf)
+		ibuf = append(ibuf, rbuf[:n]...)
+
+		// replay all complete MRT records in ibuf
+		raw := ibuf
+		for len(raw) > 0 {
+			m := p.GetMsg()
+			off, err := br.FromBytes(raw, m, mm)
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				p.PutMsg(m)
+				break // need more data
+			} else if errors.Is(err, mrt.ErrType) || errors.Is(err, mrt.ErrSub) {
+				p.PutMsg(m)
+				raw = raw[off:] // not BGP4MP, skip
+				continue
+			} else if err != nil {
+				p.PutMsg(m)
+				return err
+			}
+
+			m.CopyData()
+			raw = raw[off:]
+			if err := r.WriteMsg(in, m); err != nil {
+				return err
+			}
+		}
+		ibuf = append(ibuf[:0], raw...)
+
+		switch {
+		case rerr == io.EOF && len(ibuf) > 0:
+			return io.ErrUnexpectedEOF
+		case rerr == io.EOF:
+			return nil
+		case rerr != nil:
+			return rerr
+		}
+	}
+}
+
+// ReadJSON reads NDJSON messages from src (see msg.JSONReader) and replays
+// them into in. Returns nil at the end of src, or the first error,
+// eg. if r.Ctx was cancelled.
+func (r *Replay) ReadJSON(src io.Reader, in *pipe.Input) error {
+	jr := msg.NewJSONReader(src)
+	for {
+		m, err := jr.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := r.WriteMsg(in, m.Clone()); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/binary"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/pipe"
+	"github.com/stretchr/testify/assert"
+)
+
+// replayJSON returns NDJSON of KEEPALIVEs at given offsets from t0
+func replayJSON(t0 time.Time, offsets ...time.Duration) []byte {
+	var buf bytes.Buffer
+	for _, off := range offsets {
+		m := msg.NewMsg().Use(msg.KEEPALIVE)
+		m.Dir = dir.DIR_L
+		m.Time = t0.Add(off)
+		buf.Write(m.GetJSON())
+	}
+	return buf.Bytes()
+}
+
+// replayMRT returns an MRT record of given type and subtype at ts, with BGP4MP_MESSAGE_AS4 data
+func replayMRT(ts uint32, typ, sub uint16) []byte {
+	bgp := append(bytes.Repeat([]byte{0xff}, 16), 0x00, 0x13, 0x04) // KEEPALIVE
+	data := []byte{
+		0x00, 0x00, 0xfd, 0xe9, // peer AS 65001
+		0x00, 0x00, 0xfd, 0xea, // local AS 65002
+		0x00, 0x00, // interface
+		0x00, 0x01, // AFI IPv4
+		192, 0, 2, 1, // peer IP
+		192, 0, 2, 2, // local IP
+	}
+	data = append(data, bgp...)
+
+	var rec []byte
+	rec = binary.Msb.AppendUint32(rec, ts)
+	rec = binary.Msb.AppendUint16(rec, typ)
+	rec = binary.Msb.AppendUint16(rec, sub)
+	rec = binary.Msb.AppendUint32(rec, uint32(len(data)))
+	return append(rec, data...)
+}
+
+// replayOut closes p.L and returns the timestamps of all messages in p.L.Out
+func replayOut(p *pipe.Pipe) (times []time.Time) {
+	p.L.Close()
+	for m := range p.L.Out {
+		times = append(times, m.Time)
+	}
+	return times
+}
+
+func TestReplay_Speed(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := replayJSON(t0, 0, 100*time.Millisecond, 200*time.Millisecond)
+
+	tests := []struct {
+		name     string
+		speed    float64
+		min, max time.Duration
+	}{
+		{"real-time", 1, 190 * time.Millisecond, 400 * time.Millisecond},
+		{"twice as fast", 2, 90 * time.Millisecond, 190 * time.Millisecond},
+		{"as fast as possible", 0, 0, 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			p := pipe.NewPipe(context.Background())
+			p.Start()
+			defer p.Stop()
+
+			start := time.Now()
+			assert.NoError(NewReplay(nil, tt.speed).ReadJSON(bytes.NewReader(src), &p.L.Input))
+			took := time.Since(start)
+			assert.GreaterOrEqual(took, tt.min)
+			assert.Less(took, tt.max)
+
+			// original timestamps preserved
+			assert.Equal([]time.Time{t0, t0.Add(100 * time.Millisecond), t0.Add(200 * time.Millisecond)}, replayOut(p))
+		})
+	}
+}
+
+func TestReplay_MRT(t *testing.T) {
+	assert := assert.New(t)
+	p := pipe.NewPipe(context.Background())
+	p.Start()
+	defer p.Stop()
+
+	// 3 BGP4MP records 1s apart, with a TABLE_DUMP_V2 record in between
+	var src []byte
+	src = append(src, replayMRT(1000, 16, 4)...)
+	src = append(src, replayMRT(1000, 13, 1)...)
+	src = append(src, replayMRT(1001, 16, 4)...)
+	src = append(src, replayMRT(1002, 16, 4)...)
+
+	start := time.Now()
+	assert.NoError(NewReplay(nil, 10).ReadMRT(bytes.NewReader(src), &p.L.Input))
+	took := time.Since(start)
+	assert.GreaterOrEqual(took, 190*time.Millisecond)
+	assert.Less(took, time.Second)
+
+	t0 := time.Unix(1000, 0).UTC()
+	assert.Equal([]time.Time{t0, t0.Add(time.Second), t0.Add(2 * time.Second)}, replayOut(p))
+
+	// truncated record
+	p2 := pipe.NewPipe(context.Background())
+	p2.Start()
+	defer p2.Stop()
+	assert.Error(NewReplay(nil, 0).ReadMRT(bytes.NewReader(src[:len(src)-5]), &p2.L.Input))
+}
+
+func TestReplay_Cancel(t *testing.T) {
+	assert := assert.New(t)
+	p := pipe.NewPipe(context.Background())
+	p.Start()
+	defer p.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	// the reader must stop on cancel, not skip the remaining messages
+	src := replayJSON(time.Now(), 0, time.Hour, 2*time.Hour)
+	start := time.Now()
+	err := NewReplay(ctx, 1).ReadJSON(bytes.NewReader(src), &p.L.Input)
+	assert.ErrorIs(err, context.Canceled)
+	assert.Less(time.Since(start), time.Second)
+	assert.Len(replayOut(p), 1)
+}
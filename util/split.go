@@ -0,0 +1,42 @@
+package util
+
+import (
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/pipe"
+)
+
+// SplitFamilies adds a pre-callback to p that splits mixed-family UPDATEs in direction d,
+// ie. messages with IPv4 unicast NLRI in the base message and MP-BGP attributes for
+// another address family, into separate single-family UPDATEs with the same attributes.
+//
+// The original message keeps the IPv4 unicast part, while the MP-BGP part is written
+// to a new pipe.Input in direction d, so that it runs through all other callbacks.
+// The relative order of both parts on output is not guaranteed.
+//
+// Must be called before p.Start(). Returns the added callback.
+func SplitFamilies(p *pipe.Pipe, d dir.Dir) *pipe.Callback {
+	po := &p.Options
+	in := po.AddInput(d)
+
+	return po.OnMsgPre(func(m *msg.Msg) bool {
+		u := &m.Update
+		if pipe.MsgContext(m).Input == in {
+			return true // already split
+		} else if len(u.Families()) < 2 || len(u.Reach)+len(u.Unreach) == 0 {
+			return true // single family, or MP-BGP only
+		}
+
+		// split off the MP-BGP part
+		m2 := p.GetMsg()
+		if err := u.SplitMP(&m2.Update, p.Caps); err != nil {
+			p.PutMsg(m2)
+			p.Event(pipe.EVENT_PARSE, m.Dir, m, err)
+			return false
+		}
+
+		// NB: on error, in.WriteMsg() already put m2 back in the pool
+		in.WriteMsg(m2)
+		return true
+	}, d, msg.UPDATE)
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/attrs"
+	"github.com/bgpfix/bgpfix/caps"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/msg/msgtest"
+	"github.com/bgpfix/bgpfix/pipe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitFamilies(t *testing.T) {
+	assert := assert.New(t)
+
+	p := pipe.NewPipe(context.Background())
+	SplitFamilies(p, dir.DIR_L)
+
+	// all parts must run through the other callbacks
+	var seen atomic.Int32
+	p.Options.OnMsg(func(m *msg.Msg) bool {
+		seen.Add(1)
+		return true
+	}, dir.DIR_L, msg.UPDATE)
+
+	p.Start()
+	defer p.Stop()
+
+	// IPv4 unicast in the base message, IPv6 unicast in MP_REACH
+	assert.NoError(p.L.WriteMsg(msgtest.Update(`{"reach":["10.0.0.0/8"],"attrs":{` +
+		`"ORIGIN":"IGP","ASPATH":[65001],"NEXTHOP":"192.0.2.1",` +
+		`"MP_REACH":{"flags":"O","value":{"af":"IPV6/UNICAST","nexthop":"2001:db8::1","prefixes":["2001:db8:1::/48"]}}}}`)))
+
+	// read both parts, in any order
+	got := make(map[afi.AS]*msg.Msg)
+	for range 2 {
+		select {
+		case m := <-p.L.Out:
+			assert.NoError(m.Marshal(caps.Caps{}))
+			fams := m.Update.Families()
+			if assert.Len(fams, 1) {
+				got[fams[0]] = m
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for the split UPDATEs")
+		}
+	}
+	assert.EqualValues(2, seen.Load())
+
+	// the original keeps the IPv4 part
+	if m := got[afi.AS_IPV4_UNICAST]; assert.NotNil(m) {
+		assert.Len(m.Update.Reach, 1)
+		assert.Nil(m.Update.MP(attrs.ATTR_MP_REACH))
+		assert.True(m.Update.Attrs.Has(attrs.ATTR_NEXTHOP))
+		assert.EqualValues(65001, m.Update.AsPath().Origin())
+	}
+
+	// the new message has the IPv6 part, with the same attributes
+	if m := got[afi.AS_IPV6_UNICAST]; assert.NotNil(m) {
+		assert.Empty(m.Update.Reach)
+		if mp := m.Update.MP(attrs.ATTR_MP_REACH).Prefixes(); assert.NotNil(mp) {
+			assert.Len(mp.Prefixes, 1)
+		}
+		assert.False(m.Update.Attrs.Has(attrs.ATTR_NEXTHOP))
+		assert.EqualValues(65001, m.Update.AsPath().Origin())
+	}
+
+	// single-family UPDATEs are left alone
+	assert.NoError(p.L.WriteMsg(msgtest.Announce("2001:db8::1", []uint32{65001}, "2001:db8:2::/48")))
+	select {
+	case m := <-p.L.Out:
+		assert.Equal([]afi.AS{afi.AS_IPV6_UNICAST}, m.Update.Families())
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the UPDATE")
+	}
+	select {
+	case m := <-p.L.Out:
+		t.Fatalf("unexpected message: %s", m)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.EqualValues(3, seen.Load())
+}
@@ -0,0 +1,120 @@
+package util
+
+import (
+	"maps"
+	"sync"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/attrs"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg"
+	"github.com/bgpfix/bgpfix/pipe"
+)
+
+// Stats accumulates running statistics over UPDATE messages, eg. during a full-table ingest.
+// It is safe for concurrent use.
+type Stats struct {
+	mu      sync.Mutex
+	updates uint64                // number of UPDATEs seen
+	reach   map[afi.AS]uint64     // announced prefixes per AF
+	unreach map[afi.AS]uint64     // withdrawn prefixes per AF
+	origins map[uint32]struct{}   // unique origin ASNs
+	paths   uint64                // number of AS_PATHs seen in announcements
+	hops    uint64                // sum of AS_PATH lengths
+	attrs   map[attrs.Code]uint64 // number of UPDATEs with given attribute
+}
+
+// StatsSnapshot is a point-in-time copy of Stats
+type StatsSnapshot struct {
+	Updates    uint64                // number of UPDATEs seen
+	Reach      map[afi.AS]uint64     // announced prefixes per AF
+	Unreach    map[afi.AS]uint64     // withdrawn prefixes per AF
+	Origins    int                   // number of unique origin ASNs
+	AvgPathLen float64               // average AS_PATH length (see attrs.Aspath.Len) in announcements
+	Attrs      map[attrs.Code]uint64 // number of UPDATEs with given attribute present
+}
+
+// NewStats returns new Stats, adding a callback to p for UPDATEs in direction d, if p is non-nil.
+// Must be called before p.Start().
+func NewStats(p *pipe.Pipe, d dir.Dir) *Stats {
+	s := &Stats{}
+	s.Reset()
+	if p != nil {
+		p.Options.OnMsg(s.Add, d, msg.UPDATE)
+	}
+	return s
+}
+
+// Reset clears all statistics
+func (s *Stats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updates = 0
+	s.reach = make(map[afi.AS]uint64)
+	s.unreach = make(map[afi.AS]uint64)
+	s.origins = make(map[uint32]struct{})
+	s.paths = 0
+	s.hops = 0
+	s.attrs = make(map[attrs.Code]uint64)
+}
+
+// Add updates s with UPDATE m, which must already be parsed. Always returns true.
+// Implements pipe.CallbackFunc.
+func (s *Stats) Add(m *msg.Msg) bool {
+	if m.Type != msg.UPDATE || m.Upper != msg.UPDATE {
+		return true
+	}
+	u := &m.Update
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updates++
+
+	// prefixes per AF
+	s.reach[afi.AS_IPV4_UNICAST] += uint64(len(u.Reach))
+	s.unreach[afi.AS_IPV4_UNICAST] += uint64(len(u.Unreach))
+	if mp := u.MP(attrs.ATTR_MP_REACH); mp != nil {
+		if pfx := mp.Prefixes(); pfx != nil {
+			s.reach[mp.AS] += uint64(len(pfx.Prefixes))
+		}
+	}
+	if mp := u.MP(attrs.ATTR_MP_UNREACH); mp != nil {
+		if pfx := mp.Prefixes(); pfx != nil {
+			s.unreach[mp.AS] += uint64(len(pfx.Prefixes))
+		}
+	}
+
+	// AS_PATH
+	if ap := u.AsPath(); ap != nil && u.HasReach() {
+		s.paths++
+		s.hops += uint64(ap.Len())
+		if origin := ap.Origin(); origin != 0 {
+			s.origins[origin] = struct{}{}
+		}
+	}
+
+	// attributes
+	u.Attrs.Each(func(i int, ac attrs.Code, at attrs.Attr) {
+		s.attrs[ac]++
+	})
+
+	return true
+}
+
+// Snapshot returns a copy of current statistics
+func (s *Stats) Snapshot() (ss StatsSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ss.Updates = s.updates
+	ss.Reach = maps.Clone(s.reach)
+	ss.Unreach = maps.Clone(s.unreach)
+	ss.Origins = len(s.origins)
+	if s.paths > 0 {
+		ss.AvgPathLen = float64(s.hops) / float64(s.paths)
+	}
+	ss.Attrs = maps.Clone(s.attrs)
+	return ss
+}
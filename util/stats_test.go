@@ -0,0 +1,74 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bgpfix/bgpfix/afi"
+	"github.com/bgpfix/bgpfix/attrs"
+	"github.com/bgpfix/bgpfix/dir"
+	"github.com/bgpfix/bgpfix/msg/msgtest"
+	"github.com/bgpfix/bgpfix/pipe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	assert := assert.New(t)
+
+	p := pipe.NewPipe(context.Background())
+	sl := NewStats(p, dir.DIR_L)
+	sr := NewStats(p, dir.DIR_R)
+	p.Start()
+	defer p.Stop()
+
+	// DIR_L: 3 UPDATEs and a KEEPALIVE
+	assert.NoError(p.L.WriteMsg(msgtest.Keepalive()))
+	assert.NoError(p.L.WriteMsg(msgtest.Announce("192.0.2.1", []uint32{65001, 65002}, "10.0.0.0/8", "10.1.0.0/16")))
+	assert.NoError(p.L.WriteMsg(msgtest.Announce("2001:db8::1", []uint32{65001, 65003, 65004, 65005}, "2001:db8:1::/48")))
+	assert.NoError(p.L.WriteMsg(msgtest.Withdraw("10.2.0.0/16")))
+
+	// DIR_R: 1 UPDATE and 2 KEEPALIVEs
+	assert.NoError(p.R.WriteMsg(msgtest.Keepalive()))
+	assert.NoError(p.R.WriteMsg(msgtest.Withdraw("2001:db8:2::/48", "2001:db8:3::/48")))
+	assert.NoError(p.R.WriteMsg(msgtest.Keepalive()))
+
+	for i := 0; i < 4; i++ {
+		select {
+		case <-p.L.Out:
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for L")
+		}
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-p.R.Out:
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for R")
+		}
+	}
+
+	ss := sl.Snapshot()
+	assert.EqualValues(3, ss.Updates)
+	assert.Equal(map[afi.AS]uint64{afi.AS_IPV4_UNICAST: 2, afi.AS_IPV6_UNICAST: 1}, ss.Reach)
+	assert.Equal(map[afi.AS]uint64{afi.AS_IPV4_UNICAST: 1}, ss.Unreach)
+	assert.Equal(2, ss.Origins)
+	assert.Equal(3.0, ss.AvgPathLen)
+	assert.EqualValues(2, ss.Attrs[attrs.ATTR_ASPATH])
+	assert.EqualValues(1, ss.Attrs[attrs.ATTR_MP_REACH])
+
+	ss = sr.Snapshot()
+	assert.EqualValues(1, ss.Updates)
+	assert.Equal(map[afi.AS]uint64{afi.AS_IPV4_UNICAST: 0}, ss.Reach)
+	assert.Equal(map[afi.AS]uint64{afi.AS_IPV4_UNICAST: 0, afi.AS_IPV6_UNICAST: 2}, ss.Unreach)
+	assert.Equal(0, ss.Origins)
+	assert.Equal(0.0, ss.AvgPathLen)
+	assert.EqualValues(1, ss.Attrs[attrs.ATTR_MP_UNREACH])
+
+	// reset
+	sl.Reset()
+	ss = sl.Snapshot()
+	assert.EqualValues(0, ss.Updates)
+	assert.Empty(ss.Reach)
+	assert.Empty(ss.Attrs)
+}